@@ -7,11 +7,15 @@ import (
 
 	"account-manager/internal/config"
 	"account-manager/internal/database"
+	apperrors "account-manager/internal/errors"
 	"account-manager/internal/logger"
 	"account-manager/internal/migration"
 	"account-manager/internal/models"
 	"account-manager/internal/scheduler"
 	"account-manager/internal/service"
+	"account-manager/internal/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
@@ -22,6 +26,16 @@ type App struct {
 	serverService    *service.ServerService
 	auditService     *service.AuditLogService
 	hostKeyService   *service.HostKeyService
+	vaultService     *service.VaultService
+	generatorService *service.PasswordGeneratorService
+	healthService    *service.PasswordHealthService
+	historyService   *service.PasswordHistoryService
+	userService      *service.UserService
+	typeService      *service.AccountTypeService
+	statusService    *service.AccountStatusService
+	tagService       *service.TagService
+	fieldService     *service.CustomFieldService
+	customerService  *service.CustomerService
 	migrationService *migration.MigrationService
 	scheduler        *scheduler.Scheduler
 }
@@ -52,6 +66,15 @@ func (a *App) startup(ctx context.Context) {
 		}
 	}
 
+	// Select where the data key comes from
+	securityCfg := config.Get().Security
+	provider, err := utils.NewKeyProvider(securityCfg.KeyProvider, securityCfg.KeyFile, securityCfg.KeyEnv, securityCfg.AgentSocket)
+	if err != nil {
+		logger.WithField("error", err.Error()).Warn("Invalid key provider, falling back to master password")
+		provider = &utils.PasswordKeyProvider{}
+	}
+	utils.SetKeyProvider(provider)
+
 	// Initialize database
 	database.Initialize()
 
@@ -64,25 +87,209 @@ func (a *App) startup(ctx context.Context) {
 		logger.WithField("error", err.Error()).Warn("Failed to create migration table")
 	}
 
+	// Move account types into their own table
+	if err := a.migrationService.MigrateAccountTypes(); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to migrate account types")
+	}
+
+	// Derive account statuses from the sold flag
+	if err := a.migrationService.MigrateAccountStatuses(); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to migrate account statuses")
+	}
+
 	// Initialize services
 	a.accountService = service.NewAccountService()
 	a.emailService = service.NewEmailService()
 	a.serverService = service.NewServerService()
 	a.auditService = service.NewAuditLogService()
 	a.hostKeyService = service.NewHostKeyService()
+	a.vaultService = service.NewVaultService()
+	a.generatorService = service.NewPasswordGeneratorService()
+	a.healthService = service.NewPasswordHealthService()
+	a.historyService = service.NewPasswordHistoryService()
+	a.userService = service.NewUserService()
+	a.typeService = service.NewAccountTypeService()
+	a.statusService = service.NewAccountStatusService()
+	a.tagService = service.NewTagService()
+	a.fieldService = service.NewCustomFieldService()
+	a.customerService = service.NewCustomerService()
+
+	// Require a login once local users exist
+	if err := a.userService.Initialize(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to load local users")
+	}
 
 	// Initialize and start scheduler
 	a.scheduler = scheduler.NewScheduler()
 	a.scheduler.Start()
+
+	// Tell the frontend to show the unlock screen when the vault locks itself,
+	// and send reminders that were deferred while it was locked
+	a.vaultService.OnLock(func() {
+		a.accountService.ClearSearchIndex()
+		a.userService.Logout()
+		runtime.EventsEmit(a.ctx, "vault:locked")
+	})
+	a.vaultService.OnUnlock(func() {
+		go a.scheduler.CheckExpiringAccounts()
+	})
+	// Password fingerprints are keyed by the data key
+	a.vaultService.OnKeyRotated(func() {
+		go a.recheckPasswords()
+	})
+	a.vaultService.StartAutoLock()
+
+	// Key files, environment variables and key agents need no user input
+	if !a.vaultService.IsInteractive() {
+		a.unlockWithProvider()
+	}
+}
+
+// unlockWithProvider sets up or unlocks the vault using a non-interactive key provider
+func (a *App) unlockWithProvider() {
+	var err error
+	if a.vaultService.IsInitialized() {
+		err = a.Unlock("")
+	} else {
+		err = a.SetupMasterPassword("")
+	}
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to unlock vault with key provider")
+	}
 }
 
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
+	if a.vaultService != nil {
+		a.vaultService.StopAutoLock()
+	}
 	if a.scheduler != nil {
 		a.scheduler.Stop()
 	}
 }
 
+// ============ Vault Methods ============
+
+// IsVaultInitialized reports whether a master password has been set up.
+// The frontend asks for a new master password on first run and for the
+// existing one on every startup before any secret can be read.
+func (a *App) IsVaultInitialized() bool {
+	return a.vaultService.IsInitialized()
+}
+
+// IsVaultInteractive reports whether the frontend has to ask for a master password
+func (a *App) IsVaultInteractive() bool {
+	return a.vaultService.IsInteractive()
+}
+
+func (a *App) IsVaultUnlocked() bool {
+	return a.vaultService.IsUnlocked()
+}
+
+func (a *App) SetupMasterPassword(masterPassword string) error {
+	if err := a.vaultService.Setup(masterPassword); err != nil {
+		return err
+	}
+	if err := a.migrateEncryptedData(); err != nil {
+		return err
+	}
+	go a.rebuildSearchIndex()
+	go a.checkPendingPasswords()
+	go a.rewrapLegacyValues()
+	return nil
+}
+
+func (a *App) Unlock(masterPassword string) error {
+	if err := a.vaultService.Unlock(masterPassword); err != nil {
+		return err
+	}
+	if err := a.migrateEncryptedData(); err != nil {
+		return err
+	}
+	go a.rebuildSearchIndex()
+	go a.checkPendingPasswords()
+	if a.vaultService.HasPendingRotation() {
+		go a.resumeKeyRotation()
+	} else {
+		go a.rewrapLegacyValues()
+	}
+	return nil
+}
+
+// migrateEncryptedData re-encrypts data written with the legacy built-in key
+// and encrypts formerly plaintext columns. Both steps run once and resume if a
+// previous attempt was interrupted.
+func (a *App) migrateEncryptedData() error {
+	if err := a.migrationService.MigrateEncryption(); err != nil {
+		return err
+	}
+	return a.migrationService.EncryptSensitiveColumns()
+}
+
+// rebuildSearchIndex loads decrypted notes into memory so search can match them
+func (a *App) rebuildSearchIndex() {
+	if err := a.accountService.RebuildSearchIndex(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to build search index")
+	}
+}
+
+// checkPendingPasswords scores passwords of accounts that were never checked
+func (a *App) checkPendingPasswords() {
+	if _, err := a.healthService.RecheckPending(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to check password health")
+	}
+}
+
+func (a *App) recheckPasswords() {
+	if _, err := a.healthService.RecheckAll(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to recheck password health")
+	}
+}
+
+// Lock wipes the data key from memory until Unlock is called again
+func (a *App) Lock() {
+	a.vaultService.Lock()
+}
+
+func (a *App) GetAutoLockMinutes() int {
+	return a.vaultService.GetAutoLockMinutes()
+}
+
+func (a *App) SetAutoLockMinutes(minutes int) error {
+	return a.vaultService.SetAutoLockMinutes(minutes)
+}
+
+// RotateEncryptionKey re-encrypts all secrets with a new data key derived from
+// newMasterPassword. Pass the current password twice to keep it unchanged.
+// Progress is reported through the "vault:rotation-progress" event.
+func (a *App) RotateEncryptionKey(currentMasterPassword, newMasterPassword string) error {
+	return a.vaultService.RotateKey(currentMasterPassword, newMasterPassword, a.emitRotationProgress)
+}
+
+func (a *App) HasPendingKeyRotation() bool {
+	return a.vaultService.HasPendingRotation()
+}
+
+func (a *App) emitRotationProgress(progress models.KeyRotationProgress) {
+	runtime.EventsEmit(a.ctx, "vault:rotation-progress", progress)
+}
+
+// resumeKeyRotation finishes a rotation interrupted by closing the app
+func (a *App) resumeKeyRotation() {
+	if err := a.vaultService.ResumeRotation(a.emitRotationProgress); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to resume key rotation")
+		return
+	}
+	a.rewrapLegacyValues()
+}
+
+// rewrapLegacyValues upgrades AES-CFB values to the authenticated format in the background
+func (a *App) rewrapLegacyValues() {
+	if _, err := a.migrationService.RewrapLegacyValues(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to re-wrap legacy ciphertexts")
+	}
+}
+
 // ============ Account Methods ============
 
 func (a *App) CreateAccount(account, password, accountType string, expireAt string, isSold bool) error {
@@ -107,10 +314,66 @@ func (a *App) UpdateAccount(id uint, account, password, accountType string, expi
 	return a.accountService.UpdateAccount(id, account, password, accountType, expireTime, "", isSold)
 }
 
+// CreateAccountWithFields creates an account with custom field values keyed by field name
+func (a *App) CreateAccountWithFields(account, password, accountType string, expireAt string, isSold bool, fields map[string]string) error {
+	var expireTime *time.Time
+	if expireAt != "" {
+		t, err := time.Parse("2006-01-02", expireAt)
+		if err == nil {
+			expireTime = &t
+		}
+	}
+	return a.accountService.CreateAccountWithFields(account, password, accountType, expireTime, "", isSold, fields)
+}
+
+// UpdateAccountWithFields updates an account and the given custom field values;
+// fields left out keep their value and an empty value clears the field
+func (a *App) UpdateAccountWithFields(id uint, account, password, accountType string, expireAt string, isSold bool, fields map[string]string) error {
+	var expireTime *time.Time
+	if expireAt != "" {
+		t, err := time.Parse("2006-01-02", expireAt)
+		if err == nil {
+			expireTime = &t
+		}
+	}
+	return a.accountService.UpdateAccountWithFields(id, account, password, accountType, expireTime, "", isSold, fields)
+}
+
+// DeleteAccount moves an account to the trash
 func (a *App) DeleteAccount(id uint) error {
 	return a.accountService.DeleteAccount(id)
 }
 
+// GetTrash returns the accounts in the trash, most recently deleted first
+func (a *App) GetTrash(page, pageSize int) (*models.PaginatedAccounts, error) {
+	return a.accountService.GetTrash(page, pageSize)
+}
+
+// RestoreAccount takes an account out of the trash
+func (a *App) RestoreAccount(id uint) error {
+	return a.accountService.RestoreAccount(id)
+}
+
+// PurgeAccount removes an account in the trash for good
+func (a *App) PurgeAccount(id uint) error {
+	return a.accountService.PurgeAccount(id)
+}
+
+// EmptyTrash removes every account in the trash for good
+func (a *App) EmptyTrash() (int, error) {
+	return a.accountService.EmptyTrash()
+}
+
+// GetTrashRetentionDays returns the days accounts stay in the trash, 0 means forever
+func (a *App) GetTrashRetentionDays() int {
+	return a.accountService.GetTrashRetentionDays()
+}
+
+// SetTrashRetentionDays sets the days accounts stay in the trash, 0 keeps them
+func (a *App) SetTrashRetentionDays(days int) error {
+	return a.accountService.SetTrashRetentionDays(days)
+}
+
 func (a *App) GetAccount(id uint) (*models.Account, error) {
 	return a.accountService.GetAccount(id)
 }
@@ -126,6 +389,12 @@ func (a *App) GetAccounts(accountType string, isSold *bool, search string, page,
 	return a.accountService.GetAccounts(filter)
 }
 
+// GetAccountsFiltered returns accounts matching every field of filter,
+// including the password health filter ("weak", "reused", "breached")
+func (a *App) GetAccountsFiltered(filter models.AccountFilter) (*models.PaginatedAccounts, error) {
+	return a.accountService.GetAccounts(filter)
+}
+
 func (a *App) GetStats() (*models.AccountStats, error) {
 	return a.accountService.GetStats()
 }
@@ -138,6 +407,26 @@ func (a *App) MarkAsUnsold(id uint) error {
 	return a.accountService.MarkAsUnsold(id)
 }
 
+// ChangeAccountStatus moves an account to another status allowed by the workflow
+func (a *App) ChangeAccountStatus(id uint, status, note string) error {
+	return a.accountService.ChangeStatus(id, status, note)
+}
+
+// GetAccountChangeHistory returns the field level change sets of an account, newest first
+func (a *App) GetAccountChangeHistory(accountID uint) ([]models.AccountChangeSet, error) {
+	return a.accountService.GetChangeHistory(accountID)
+}
+
+// RevertAccountChangeSet puts the fields changed by a change set back to their previous values
+func (a *App) RevertAccountChangeSet(changeSetID uint) error {
+	return a.accountService.RevertChangeSet(changeSetID)
+}
+
+// GetAccountStatusHistory returns the status transitions of an account, newest first
+func (a *App) GetAccountStatusHistory(accountID uint) ([]models.AccountStatusChange, error) {
+	return a.statusService.GetStatusHistory(accountID)
+}
+
 func (a *App) BatchImport(accounts []map[string]interface{}) *models.BatchImportResult {
 	success, errors := a.accountService.BatchImport(accounts)
 	return &models.BatchImportResult{
@@ -146,6 +435,393 @@ func (a *App) BatchImport(accounts []map[string]interface{}) *models.BatchImport
 	}
 }
 
+// SetAccountTOTP stores a TOTP secret given as an otpauth:// URI or base32 secret
+func (a *App) SetAccountTOTP(id uint, secret string) error {
+	return a.accountService.SetTOTP(id, secret)
+}
+
+func (a *App) RemoveAccountTOTP(id uint) error {
+	return a.accountService.RemoveTOTP(id)
+}
+
+// GetTOTPCode returns the current one-time code and the seconds until it changes
+func (a *App) GetTOTPCode(id uint) (*models.TOTPCode, error) {
+	return a.accountService.GetTOTPCode(id)
+}
+
+// RenderCopyText fills the copy format for an account, including {totp}
+func (a *App) RenderCopyText(id uint) (string, error) {
+	return a.accountService.RenderCopyText(id)
+}
+
+// GetPasswordHistory lists the previous passwords of an account without revealing them
+func (a *App) GetPasswordHistory(accountID uint) ([]models.PasswordHistory, error) {
+	return a.historyService.GetHistory(accountID)
+}
+
+// RevealPasswordHistory decrypts one previous password; the access is audited
+func (a *App) RevealPasswordHistory(historyID uint) (string, error) {
+	return a.historyService.Reveal(historyID)
+}
+
+// RestorePasswordHistory makes a previous password current again
+func (a *App) RestorePasswordHistory(historyID uint) error {
+	return a.historyService.Restore(historyID)
+}
+
+// ============ Renewal Methods ============
+
+// ExtendAccountExpiry renews an account for days, or one validity period of
+// its type when days is 0. cost may be nil to use the type's renewal price.
+func (a *App) ExtendAccountExpiry(id uint, days int, cost *models.Cents, note string) (*models.Renewal, error) {
+	return a.accountService.ExtendExpiry(id, days, cost, note)
+}
+
+// GetAccountRenewals returns the renewals of an account, newest first
+func (a *App) GetAccountRenewals(accountID uint) ([]models.Renewal, error) {
+	return a.accountService.GetRenewals(accountID)
+}
+
+// GetRenewalReport sums the renewals between two dates (YYYY-MM-DD, both
+// included) by day, week or month
+func (a *App) GetRenewalReport(startDate, endDate, granularity string) (*models.RenewalReport, error) {
+	from, to, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return a.accountService.GetRenewalReport(from, to, granularity)
+}
+
+// parseDateRange turns two local dates into the range [start, end + 1 day)
+func parseDateRange(startDate, endDate string) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperrors.New(apperrors.ErrCodeInvalidInput, "开始日期格式应为 YYYY-MM-DD")
+	}
+	to, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperrors.New(apperrors.ErrCodeInvalidInput, "结束日期格式应为 YYYY-MM-DD")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// ============ Sales Methods ============
+
+// SellAccount marks an account sold and records the sale in the ledger
+func (a *App) SellAccount(id uint, details models.SaleDetails) (*models.Sale, error) {
+	return a.accountService.SellAccount(id, details)
+}
+
+// UpdateSale corrects the details of a sale that was not reversed
+func (a *App) UpdateSale(id uint, details models.SaleDetails) (*models.Sale, error) {
+	return a.accountService.UpdateSale(id, details)
+}
+
+func (a *App) GetSales(filter models.SaleFilter) (*models.PaginatedSales, error) {
+	return a.accountService.GetSales(filter)
+}
+
+// GetRevenueReport sums the ledger between two dates (YYYY-MM-DD, both
+// included) by day, week or month and by account type
+func (a *App) GetRevenueReport(startDate, endDate, granularity string) (*models.RevenueReport, error) {
+	from, to, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return a.accountService.GetRevenueReport(from, to, granularity)
+}
+
+func (a *App) GetDefaultCurrency() string {
+	return a.accountService.GetDefaultCurrency()
+}
+
+func (a *App) SetDefaultCurrency(currency string) error {
+	return a.accountService.SetDefaultCurrency(currency)
+}
+
+// ============ Bulk Account Methods ============
+// Each takes account ids or a filter, writes every account that passes its
+// checks in one transaction and reports the result per account.
+
+func (a *App) BulkChangeStatus(selection models.BulkSelection, status, note string) (*models.BulkResult, error) {
+	return a.accountService.BulkChangeStatus(selection, status, note)
+}
+
+func (a *App) BulkMarkAsSold(selection models.BulkSelection) (*models.BulkResult, error) {
+	return a.accountService.BulkMarkAsSold(selection)
+}
+
+func (a *App) BulkMarkAsUnsold(selection models.BulkSelection) (*models.BulkResult, error) {
+	return a.accountService.BulkMarkAsUnsold(selection)
+}
+
+// BulkExtendExpiry moves the expiry of the selected accounts days later
+func (a *App) BulkExtendExpiry(selection models.BulkSelection, days int) (*models.BulkResult, error) {
+	return a.accountService.BulkExtendExpiry(selection, days)
+}
+
+func (a *App) BulkChangeType(selection models.BulkSelection, accountType string) (*models.BulkResult, error) {
+	return a.accountService.BulkChangeType(selection, accountType)
+}
+
+// BulkDeleteAccounts moves the selected accounts to the trash
+func (a *App) BulkDeleteAccounts(selection models.BulkSelection) (*models.BulkResult, error) {
+	return a.accountService.BulkDelete(selection)
+}
+
+// ============ Account Type Methods ============
+
+// GetAccountTypes returns the configured account types in display order
+func (a *App) GetAccountTypes() ([]models.AccountTypeConfig, error) {
+	return a.typeService.GetTypes()
+}
+
+// SaveAccountType creates an account type, or updates it when ID is set
+func (a *App) SaveAccountType(accountType models.AccountTypeConfig) (*models.AccountTypeConfig, error) {
+	if err := a.typeService.SaveType(&accountType); err != nil {
+		return nil, err
+	}
+	return &accountType, nil
+}
+
+// DeleteAccountType removes an account type that no account uses
+func (a *App) DeleteAccountType(id uint) error {
+	return a.typeService.DeleteType(id)
+}
+
+// ============ Custom Field Methods ============
+
+// GetCustomFields returns the custom fields of an account type, or of every type when empty
+func (a *App) GetCustomFields(accountType string) ([]models.CustomField, error) {
+	return a.fieldService.GetFields(accountType)
+}
+
+// SaveCustomField creates a custom field, or updates it when ID is set
+func (a *App) SaveCustomField(field models.CustomField) (*models.CustomField, error) {
+	if err := a.fieldService.SaveField(&field); err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+// DeleteCustomField removes a custom field and its value on every account
+func (a *App) DeleteCustomField(id uint) error {
+	return a.fieldService.DeleteField(id)
+}
+
+// ============ Tag Methods ============
+
+// GetTags returns all tags by name
+func (a *App) GetTags() ([]models.Tag, error) {
+	return a.tagService.GetTags()
+}
+
+// SaveTag creates a tag, or updates it when ID is set
+func (a *App) SaveTag(tag models.Tag) (*models.Tag, error) {
+	if err := a.tagService.SaveTag(&tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag removes a tag from every account and deletes it
+func (a *App) DeleteTag(id uint) error {
+	return a.tagService.DeleteTag(id)
+}
+
+// TagAccounts adds the tags to the accounts and returns how many were added
+func (a *App) TagAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	return a.tagService.TagAccounts(accountIDs, tagIDs)
+}
+
+// UntagAccounts removes the tags from the accounts and returns how many were removed
+func (a *App) UntagAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	return a.tagService.UntagAccounts(accountIDs, tagIDs)
+}
+
+// SetAccountTags replaces the tags of one account
+func (a *App) SetAccountTags(accountID uint, tagIDs []uint) error {
+	return a.tagService.SetAccountTags(accountID, tagIDs)
+}
+
+// ============ Reservation Methods ============
+
+// ReserveAccounts holds unsold accounts of a type for an order
+func (a *App) ReserveAccounts(request models.ReservationRequest) (*models.Reservation, error) {
+	return a.accountService.ReserveAccounts(request)
+}
+
+// ConfirmReservation sells the accounts of a held reservation
+func (a *App) ConfirmReservation(id uint, details models.SaleDetails) (*models.BulkResult, error) {
+	return a.accountService.ConfirmReservation(id, details)
+}
+
+// ReleaseReservation frees the accounts of a held reservation
+func (a *App) ReleaseReservation(id uint) error {
+	return a.accountService.ReleaseReservation(id)
+}
+
+// GetReservations returns the reservations in a status, or all when status is empty
+func (a *App) GetReservations(status string, page, pageSize int) (*models.PaginatedReservations, error) {
+	return a.accountService.GetReservations(status, page, pageSize)
+}
+
+// GetReservation returns a reservation with its accounts
+func (a *App) GetReservation(id uint) (*models.Reservation, error) {
+	return a.accountService.GetReservation(id)
+}
+
+// ============ Customer Methods ============
+
+// GetCustomers returns the customers whose name, email or handle contains search
+func (a *App) GetCustomers(search string, page, pageSize int) (*models.PaginatedCustomers, error) {
+	return a.customerService.GetCustomers(search, page, pageSize)
+}
+
+// GetCustomer returns a customer with its notes
+func (a *App) GetCustomer(id uint) (*models.Customer, error) {
+	return a.customerService.GetCustomer(id)
+}
+
+// SaveCustomer creates a customer, or updates it when ID is set
+func (a *App) SaveCustomer(customer models.Customer) (*models.Customer, error) {
+	if err := a.customerService.SaveCustomer(&customer); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// DeleteCustomer deletes a customer and unlinks its accounts
+func (a *App) DeleteCustomer(id uint) error {
+	return a.customerService.DeleteCustomer(id)
+}
+
+// AssignAccountsToCustomer links sold accounts to a customer and returns how many were linked
+func (a *App) AssignAccountsToCustomer(customerID uint, accountIDs []uint) (int64, error) {
+	return a.customerService.AssignAccounts(customerID, accountIDs)
+}
+
+// UnassignCustomerAccounts unlinks accounts from their customer and returns how many were unlinked
+func (a *App) UnassignCustomerAccounts(accountIDs []uint) (int64, error) {
+	return a.customerService.UnassignAccounts(accountIDs)
+}
+
+// GetCustomerAccounts returns the accounts held by a customer
+func (a *App) GetCustomerAccounts(customerID uint, page, pageSize int) (*models.PaginatedAccounts, error) {
+	if _, err := a.customerService.GetCustomer(customerID); err != nil {
+		return nil, err
+	}
+	filter := models.AccountFilter{
+		CustomerID: customerID,
+		Page:       page,
+		PageSize:   pageSize,
+	}
+	return a.accountService.GetAccounts(filter)
+}
+
+// GetCustomerExpiryOverview lists per customer the accounts expiring between
+// two dates (YYYY-MM-DD, both included). customerID 0 covers every customer.
+func (a *App) GetCustomerExpiryOverview(customerID uint, startDate, endDate string) ([]models.CustomerExpiry, error) {
+	from, to, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return a.customerService.GetExpiryOverview(customerID, from, to)
+}
+
+// ============ Account Status Methods ============
+
+// GetAccountStatuses returns the configured account statuses in workflow order
+func (a *App) GetAccountStatuses() ([]models.AccountStatusConfig, error) {
+	return a.statusService.GetStatuses()
+}
+
+// SaveAccountStatus creates an account status, or updates it when ID is set
+func (a *App) SaveAccountStatus(status models.AccountStatusConfig) (*models.AccountStatusConfig, error) {
+	if err := a.statusService.SaveStatus(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// DeleteAccountStatus removes an account status that no account is in
+func (a *App) DeleteAccountStatus(id uint) error {
+	return a.statusService.DeleteStatus(id)
+}
+
+// GetStatusTransitions returns every allowed status transition
+func (a *App) GetStatusTransitions() ([]models.AccountStatusTransition, error) {
+	return a.statusService.GetTransitions()
+}
+
+// SetStatusTransitions replaces the statuses accounts may move to from one status
+func (a *App) SetStatusTransitions(from string, to []string) error {
+	return a.statusService.SetTransitions(from, to)
+}
+
+// ============ Password Generator Methods ============
+
+// GeneratePassword generates a password with the named policy,
+// or the default policy when policyName is empty
+func (a *App) GeneratePassword(policyName string) (*models.GeneratedPassword, error) {
+	return a.generatorService.Generate(policyName)
+}
+
+// GeneratePasswordForType generates a password with the account type's default policy
+func (a *App) GeneratePasswordForType(accountType string) (*models.GeneratedPassword, error) {
+	return a.generatorService.GenerateForType(accountType)
+}
+
+func (a *App) GetPasswordPolicies() ([]models.PasswordPolicy, error) {
+	return a.generatorService.GetPolicies()
+}
+
+// SavePasswordPolicy creates a policy, or updates it when its id is set
+func (a *App) SavePasswordPolicy(policy models.PasswordPolicy) (*models.PasswordPolicy, error) {
+	if err := a.generatorService.SavePolicy(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (a *App) DeletePasswordPolicy(id uint) error {
+	return a.generatorService.DeletePolicy(id)
+}
+
+// GetTypePasswordPolicies returns the default policy name of each account type
+func (a *App) GetTypePasswordPolicies() (map[string]string, error) {
+	return a.generatorService.GetTypePolicies()
+}
+
+func (a *App) SetTypePasswordPolicy(accountType, policyName string) error {
+	return a.generatorService.SetTypePolicy(accountType, policyName)
+}
+
+// ============ Password Health Methods ============
+
+// CheckPasswordStrength scores a password and looks it up in the breach list without storing it
+func (a *App) CheckPasswordStrength(password string) *models.PasswordCheck {
+	return a.healthService.CheckPassword(password)
+}
+
+func (a *App) GetPasswordHealthStats() (*models.PasswordHealthStats, error) {
+	return a.healthService.GetStats()
+}
+
+// RecheckPasswordHealth recomputes strength, breach and reuse flags for every account
+func (a *App) RecheckPasswordHealth() (int, error) {
+	return a.healthService.RecheckAll()
+}
+
+// ImportBreachedPasswords imports a SHA-1 breached password file or directory of range files
+func (a *App) ImportBreachedPasswords(path string) (int, error) {
+	return a.healthService.ImportBreachFile(path)
+}
+
+func (a *App) ClearBreachedPasswords() error {
+	return a.healthService.ClearBreachData()
+}
+
 // ============ Email Methods ============
 
 func (a *App) GetEmailConfig() (*models.EmailConfig, error) {
@@ -280,6 +956,53 @@ func (a *App) CleanupOldAuditLogs(retentionDays int) error {
 	return a.auditService.CleanupOldLogs(retentionDays)
 }
 
+// VerifyAuditChain reports the first audit log entry that was tampered with
+func (a *App) VerifyAuditChain() (*models.AuditChainReport, error) {
+	return a.auditService.VerifyAuditChain()
+}
+
+// ============ User Methods ============
+
+// Login signs a local user in; only needed once a user has been created
+func (a *App) Login(username, password string) (*models.Session, error) {
+	return a.userService.Login(username, password)
+}
+
+func (a *App) Logout() {
+	a.userService.Logout()
+}
+
+// GetSession returns the signed-in user and their permissions
+func (a *App) GetSession() *models.Session {
+	return a.userService.GetSession()
+}
+
+func (a *App) GetUsers() ([]models.User, error) {
+	return a.userService.GetUsers()
+}
+
+// CreateUser adds a local user; the first one must be an admin and turns login on
+func (a *App) CreateUser(username, displayName, password string, role string) (*models.User, error) {
+	return a.userService.CreateUser(username, displayName, password, models.Role(role))
+}
+
+func (a *App) UpdateUser(id uint, displayName string, role string, disabled bool) error {
+	return a.userService.UpdateUser(id, displayName, models.Role(role), disabled)
+}
+
+func (a *App) ResetUserPassword(id uint, newPassword string) error {
+	return a.userService.ResetPassword(id, newPassword)
+}
+
+// ChangeMyPassword changes the password of the signed-in user
+func (a *App) ChangeMyPassword(oldPassword, newPassword string) error {
+	return a.userService.ChangePassword(oldPassword, newPassword)
+}
+
+func (a *App) DeleteUser(id uint) error {
+	return a.userService.DeleteUser(id)
+}
+
 // ============ Host Key Methods ============
 
 func (a *App) GetAllHostKeys() ([]models.HostKey, error) {
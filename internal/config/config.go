@@ -14,6 +14,7 @@ type Config struct {
 	Cache    CacheConfig    `yaml:"cache"`
 	Worker   WorkerConfig   `yaml:"worker"`
 	Server   ServerConfig   `yaml:"server"`
+	Security SecurityConfig `yaml:"security"`
 }
 
 // AppConfig holds application-level configuration
@@ -54,6 +55,14 @@ type ServerConfig struct {
 	BuildTarget     string `yaml:"build_target"`
 }
 
+// SecurityConfig holds data key provider configuration
+type SecurityConfig struct {
+	KeyProvider string `yaml:"key_provider"` // password, keyfile, env, agent
+	KeyFile     string `yaml:"key_file"`     // Path to the key file for the keyfile provider
+	KeyEnv      string `yaml:"key_env"`      // Environment variable for the env provider
+	AgentSocket string `yaml:"agent_socket"` // Unix socket for the agent provider
+}
+
 // Global configuration instance
 var globalConfig *Config
 
@@ -107,4 +116,10 @@ func mergeDefaults(cfg, defaults *Config) {
 	if cfg.Server.DefaultPort == 0 {
 		cfg.Server = defaults.Server
 	}
+	if cfg.Security.KeyProvider == "" {
+		cfg.Security.KeyProvider = defaults.Security.KeyProvider
+	}
+	if cfg.Security.KeyEnv == "" {
+		cfg.Security.KeyEnv = defaults.Security.KeyEnv
+	}
 }
@@ -33,5 +33,9 @@ func GetDefaults() *Config {
 			DeployTimeout: 300,
 			BuildTarget:   "linux/amd64",
 		},
+		Security: SecurityConfig{
+			KeyProvider: "password",
+			KeyEnv:      "ACCOUNT_MANAGER_KEY",
+		},
 	}
 }
@@ -72,5 +72,18 @@ func Validate(cfg *Config) error {
 	if cfg.Worker.PoolSize < 1 {
 		return fmt.Errorf("worker.pool_size must be at least 1")
 	}
+	switch cfg.Security.KeyProvider {
+	case "password", "env":
+	case "keyfile":
+		if cfg.Security.KeyFile == "" {
+			return fmt.Errorf("security.key_file is required for the keyfile provider")
+		}
+	case "agent":
+		if cfg.Security.AgentSocket == "" {
+			return fmt.Errorf("security.agent_socket is required for the agent provider")
+		}
+	default:
+		return fmt.Errorf("security.key_provider must be one of password, keyfile, env, agent")
+	}
 	return nil
 }
@@ -20,6 +20,20 @@ type Container struct {
 	ServerRepo    repoInterface.IServerRepository
 	AuditLogRepo  repoInterface.IAuditLogRepository
 	HostKeyRepo   repoInterface.IHostKeyRepository
+	VaultRepo     repoInterface.IVaultRepository
+	PasswordPolicyRepo repoInterface.IPasswordPolicyRepository
+	BreachRepo         repoInterface.IBreachRepository
+	PasswordHistoryRepo repoInterface.IPasswordHistoryRepository
+	UserRepo            repoInterface.IUserRepository
+	AccountTypeRepo     repoInterface.IAccountTypeRepository
+	AccountStatusRepo   repoInterface.IAccountStatusRepository
+	TagRepo             repoInterface.ITagRepository
+	CustomFieldRepo     repoInterface.ICustomFieldRepository
+	AccountChangeRepo   repoInterface.IAccountChangeRepository
+	RenewalRepo         repoInterface.IRenewalRepository
+	SaleRepo            repoInterface.ISaleRepository
+	CustomerRepo        repoInterface.ICustomerRepository
+	ReservationRepo     repoInterface.IReservationRepository
 
 	// Services
 	AccountService  serviceInterface.IAccountService
@@ -27,6 +41,16 @@ type Container struct {
 	ServerService   serviceInterface.IServerService
 	AuditLogService serviceInterface.IAuditLogService
 	HostKeyService  serviceInterface.IHostKeyService
+	VaultService    serviceInterface.IVaultService
+	PasswordGeneratorService serviceInterface.IPasswordGeneratorService
+	PasswordHealthService    serviceInterface.IPasswordHealthService
+	PasswordHistoryService   serviceInterface.IPasswordHistoryService
+	UserService              serviceInterface.IUserService
+	AccountTypeService       serviceInterface.IAccountTypeService
+	AccountStatusService     serviceInterface.IAccountStatusService
+	TagService               serviceInterface.ITagService
+	CustomFieldService       serviceInterface.ICustomFieldService
+	CustomerService          serviceInterface.ICustomerService
 
 	// Infrastructure
 	MigrationService *migration.MigrationService
@@ -43,6 +67,20 @@ func NewContainer(db *gorm.DB) *Container {
 	c.ServerRepo = repository.NewServerRepository()
 	c.AuditLogRepo = repository.NewAuditLogRepository()
 	c.HostKeyRepo = repository.NewHostKeyRepository()
+	c.VaultRepo = repository.NewVaultRepository()
+	c.PasswordPolicyRepo = repository.NewPasswordPolicyRepository()
+	c.BreachRepo = repository.NewBreachRepository()
+	c.PasswordHistoryRepo = repository.NewPasswordHistoryRepository()
+	c.UserRepo = repository.NewUserRepository()
+	c.AccountTypeRepo = repository.NewAccountTypeRepository()
+	c.AccountStatusRepo = repository.NewAccountStatusRepository()
+	c.TagRepo = repository.NewTagRepository()
+	c.CustomFieldRepo = repository.NewCustomFieldRepository()
+	c.AccountChangeRepo = repository.NewAccountChangeRepository()
+	c.RenewalRepo = repository.NewRenewalRepository()
+	c.SaleRepo = repository.NewSaleRepository()
+	c.CustomerRepo = repository.NewCustomerRepository()
+	c.ReservationRepo = repository.NewReservationRepository()
 
 	// Initialize services
 	c.AccountService = service.NewAccountService()
@@ -50,6 +88,16 @@ func NewContainer(db *gorm.DB) *Container {
 	c.ServerService = service.NewServerService()
 	c.AuditLogService = service.NewAuditLogService()
 	c.HostKeyService = service.NewHostKeyService()
+	c.VaultService = service.NewVaultService()
+	c.PasswordGeneratorService = service.NewPasswordGeneratorService()
+	c.PasswordHealthService = service.NewPasswordHealthService()
+	c.PasswordHistoryService = service.NewPasswordHistoryService()
+	c.UserService = service.NewUserService()
+	c.AccountTypeService = service.NewAccountTypeService()
+	c.AccountStatusService = service.NewAccountStatusService()
+	c.TagService = service.NewTagService()
+	c.CustomFieldService = service.NewCustomFieldService()
+	c.CustomerService = service.NewCustomerService()
 
 	// Initialize infrastructure
 	c.MigrationService = migration.NewMigrationService(db)
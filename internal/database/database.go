@@ -39,6 +39,15 @@ func Initialize() error {
 		return err
 	}
 
+	// Account tags keep when they were added
+	if err := db.SetupJoinTable(&models.Account{}, "Tags", &models.AccountTag{}); err != nil {
+		return err
+	}
+
+	if err := checkSearchIndex(db); err != nil {
+		return err
+	}
+
 	// Auto migrate
 	err = db.AutoMigrate(
 		&models.Account{},
@@ -48,11 +57,36 @@ func Initialize() error {
 		&models.ServerConfig{},
 		&models.HostKey{},
 		&models.AuditLog{},
+		&models.VaultConfig{},
+		&models.PasswordPolicy{},
+		&models.BreachedPassword{},
+		&models.PasswordHistory{},
+		&models.User{},
+		&models.AuditCheckpoint{},
+		&models.AuditSigningKey{},
+		&models.AccountTypeConfig{},
+		&models.AccountStatusConfig{},
+		&models.AccountStatusTransition{},
+		&models.AccountStatusChange{},
+		&models.Tag{},
+		&models.AccountTag{},
+		&models.CustomField{},
+		&models.AccountFieldValue{},
+		&models.AccountChangeSet{},
+		&models.AccountFieldChange{},
+		&models.Renewal{},
+		&models.Sale{},
+		&models.Customer{},
+		&models.Reservation{},
 	)
 	if err != nil {
 		return err
 	}
 
+	if err := setupSearchIndex(db); err != nil {
+		return err
+	}
+
 	// Initialize default system config if not exists
 	var sysConfig models.SystemConfig
 	if db.First(&sysConfig).Error != nil {
@@ -62,6 +96,12 @@ func Initialize() error {
 		})
 	}
 
+	// Seed built-in password policies that do not exist yet
+	for _, policy := range models.DefaultPasswordPolicies {
+		p := policy
+		db.Where(models.PasswordPolicy{Name: p.Name}).FirstOrCreate(&p)
+	}
+
 	// Initialize default email config if not exists
 	var emailConfig models.EmailConfig
 	if db.First(&emailConfig).Error != nil {
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"account-manager/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// accounts_fts is an FTS5 index over the name and tag names of every
+// account, including those in the trash, with the account id as rowid. The
+// trigram tokenizer keeps substring semantics, so a MATCH finds the same
+// accounts as LIKE '%...%'.
+//
+// Notes are deliberately not indexed. They are encrypted at rest, while FTS5
+// keeps the indexed text and its trigrams in plain text in the database file,
+// so indexing notes would write them to disk unencrypted. Plain searches and
+// notes: terms match notes through the in-memory secret index of the service
+// package instead (secretIndex.Match and secretIndex.MatchNotes), which only
+// holds decrypted text while the vault is unlocked.
+//
+// Triggers keep the index in sync with every write, including batch import
+// and bulk operations, in the same transaction.
+const createSearchIndex = `CREATE VIRTUAL TABLE IF NOT EXISTS accounts_fts USING fts5(account, tags, tokenize = 'trigram')`
+
+// accountTagNames is the tags column of the index for the account id given by expr
+func accountTagNames(expr string) string {
+	return `COALESCE((SELECT group_concat(tags.name, ' ') FROM account_tags
+		JOIN tags ON tags.id = account_tags.tag_id WHERE account_tags.account_id = ` + expr + `), '')`
+}
+
+var searchIndexTriggers = map[string]string{
+	"accounts_fts_insert": `AFTER INSERT ON accounts BEGIN
+		INSERT INTO accounts_fts(rowid, account, tags) VALUES (NEW.id, NEW.account, ` + accountTagNames("NEW.id") + `);
+	END`,
+	"accounts_fts_update": `AFTER UPDATE OF account ON accounts BEGIN
+		UPDATE accounts_fts SET account = NEW.account WHERE rowid = NEW.id;
+	END`,
+	"accounts_fts_delete": `AFTER DELETE ON accounts BEGIN
+		DELETE FROM accounts_fts WHERE rowid = OLD.id;
+	END`,
+	"accounts_fts_tag_insert": `AFTER INSERT ON account_tags BEGIN
+		UPDATE accounts_fts SET tags = ` + accountTagNames("NEW.account_id") + ` WHERE rowid = NEW.account_id;
+	END`,
+	"accounts_fts_tag_delete": `AFTER DELETE ON account_tags BEGIN
+		UPDATE accounts_fts SET tags = ` + accountTagNames("OLD.account_id") + ` WHERE rowid = OLD.account_id;
+	END`,
+	"accounts_fts_tag_rename": `AFTER UPDATE OF name ON tags BEGIN
+		UPDATE accounts_fts SET tags = ` + accountTagNames("accounts_fts.rowid") + `
+		WHERE rowid IN (SELECT account_id FROM account_tags WHERE tag_id = NEW.id);
+	END`,
+}
+
+// Shortest text the trigram tokenizer can match
+const minSearchIndexQuery = 3
+
+// Markers around the matches in snippets taken from the index
+const (
+	SnippetOpen  = "\x02"
+	SnippetClose = "\x03"
+)
+
+var hasSearchIndex bool
+
+// HasSearchIndex reports whether the full-text index is available. It needs
+// SQLite with FTS5, which go-sqlite3 only builds with the sqlite_fts5 tag.
+func HasSearchIndex() bool {
+	return hasSearchIndex
+}
+
+// SearchIndexQuery returns the FTS5 query matching text as a substring, or
+// false when the index cannot be used for it and search should use LIKE
+func SearchIndexQuery(text string) (string, bool) {
+	if !hasSearchIndex || utf8.RuneCountInString(text) < minSearchIndexQuery {
+		return "", false
+	}
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`, true
+}
+
+// checkSearchIndex creates the full-text index when SQLite has FTS5. It runs
+// before the tables are migrated: without FTS5 the triggers left by a build
+// that had it are dropped, since every write they fire would fail, and
+// search falls back to LIKE.
+func checkSearchIndex(db *gorm.DB) error {
+	// The table may exist from a build with FTS5, so it is also queried
+	err := db.Exec(createSearchIndex).Error
+	if err == nil {
+		err = db.Exec("SELECT rowid FROM accounts_fts LIMIT 0").Error
+	}
+	if err == nil {
+		hasSearchIndex = true
+		return nil
+	}
+
+	logger.WithField("error", err.Error()).Warn("Full-text search unavailable, using LIKE search")
+	for name := range searchIndexTriggers {
+		if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupSearchIndex creates the triggers of the full-text index once the
+// tables exist. When a trigger is missing, because the index is new, the
+// accounts table was rebuilt by a migration or an earlier run lacked FTS5,
+// the index may be stale and is rebuilt.
+func setupSearchIndex(db *gorm.DB) error {
+	if !hasSearchIndex {
+		return nil
+	}
+
+	var existing []string
+	err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'accounts_fts_%'").
+		Scan(&existing).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, body := range searchIndexTriggers {
+			if err := tx.Exec("CREATE TRIGGER IF NOT EXISTS " + name + " " + body).Error; err != nil {
+				return err
+			}
+		}
+		if len(existing) == len(searchIndexTriggers) {
+			return nil
+		}
+		if err := tx.Exec("DELETE FROM accounts_fts").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO accounts_fts(rowid, account, tags)
+			SELECT id, account, ` + accountTagNames("accounts.id") + ` FROM accounts`).Error
+	})
+}
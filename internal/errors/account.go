@@ -1,5 +1,7 @@
 package errors
 
+import "fmt"
+
 // Account-specific error constructors
 
 func NewAccountEmpty() *AppError {
@@ -18,6 +20,111 @@ func NewAccountNameInUse() *AppError {
 	return New(ErrCodeAccountNameInUse, "账号名已被使用")
 }
 
+func NewAccountInTrash() *AppError {
+	return New(ErrCodeAccountInTrash, "回收站中有同名账号，请先恢复或彻底删除")
+}
+
+func NewChangeSetNotFound(id uint) *AppError {
+	return New(ErrCodeChangeSetNotFound, fmt.Sprintf("变更记录 #%d 不存在", id))
+}
+
+func NewRevertConflict(fields string) *AppError {
+	return New(ErrCodeRevertConflict, fmt.Sprintf("字段 %s 在此之后已被修改，无法撤销", fields))
+}
+
 func NewDecryptionFailed(err error) *AppError {
 	return Wrap(err, ErrCodeDecryptionFailed, "解密失败")
 }
+
+func NewInvalidTOTP(err error) *AppError {
+	return Wrap(err, ErrCodeInvalidTOTP, "无效的 TOTP 密钥")
+}
+
+func NewTOTPNotConfigured() *AppError {
+	return New(ErrCodeTOTPNotConfigured, "该账号未设置 TOTP")
+}
+
+func NewAccountTypeNotFound(name string) *AppError {
+	return New(ErrCodeAccountTypeNotFound, fmt.Sprintf("账号类型 %s 不存在", name))
+}
+
+func NewAccountTypeExists(name string) *AppError {
+	return New(ErrCodeAccountTypeExists, fmt.Sprintf("账号类型 %s 已存在", name))
+}
+
+func NewAccountTypeInUse(name string, count int64) *AppError {
+	return New(ErrCodeAccountTypeInUse, fmt.Sprintf("账号类型 %s 仍有 %d 个账号在使用", name, count))
+}
+
+func NewStatusNotFound(name string) *AppError {
+	return New(ErrCodeStatusNotFound, fmt.Sprintf("账号状态 %s 不存在", name))
+}
+
+func NewStatusExists(name string) *AppError {
+	return New(ErrCodeStatusExists, fmt.Sprintf("账号状态 %s 已存在", name))
+}
+
+func NewStatusInUse(name string, count int64) *AppError {
+	return New(ErrCodeStatusInUse, fmt.Sprintf("账号状态 %s 仍有 %d 个账号在使用", name, count))
+}
+
+func NewInvalidTransition(from, to string) *AppError {
+	return New(ErrCodeInvalidTransition, fmt.Sprintf("账号不能从 %s 状态变为 %s", from, to))
+}
+
+func NewTagNotFound(name string) *AppError {
+	return New(ErrCodeTagNotFound, fmt.Sprintf("标签 %s 不存在", name))
+}
+
+func NewTagExists(name string) *AppError {
+	return New(ErrCodeTagExists, fmt.Sprintf("标签 %s 已存在", name))
+}
+
+func NewFieldNotFound(name string) *AppError {
+	return New(ErrCodeFieldNotFound, fmt.Sprintf("自定义字段 %s 不存在", name))
+}
+
+func NewFieldExists(name string) *AppError {
+	return New(ErrCodeFieldExists, fmt.Sprintf("自定义字段 %s 已存在", name))
+}
+
+func NewSaleNotFound(id uint) *AppError {
+	return New(ErrCodeSaleNotFound, fmt.Sprintf("销售记录 #%d 不存在", id))
+}
+
+func NewAccountSold() *AppError {
+	return New(ErrCodeAccountSold, "账号已售出")
+}
+
+func NewSaleReversed(id uint) *AppError {
+	return New(ErrCodeSaleReversed, fmt.Sprintf("销售记录 #%d 已撤销，不能修改", id))
+}
+
+func NewCustomerNotFound(id uint) *AppError {
+	return New(ErrCodeCustomerNotFound, fmt.Sprintf("客户 #%d 不存在", id))
+}
+
+func NewReservationNotFound(id uint) *AppError {
+	return New(ErrCodeReservationNotFound, fmt.Sprintf("预留 #%d 不存在", id))
+}
+
+func NewReservationClosed(id uint, status string) *AppError {
+	return New(ErrCodeReservationClosed, fmt.Sprintf("预留 #%d 已结束（%s）", id, status))
+}
+
+func NewAccountReserved(reservationID uint) *AppError {
+	return New(ErrCodeAccountReserved, fmt.Sprintf("账号已被预留 #%d 占用，请先确认或释放该预留", reservationID))
+}
+
+func NewInsufficientStock(accountType string, requested, available int) *AppError {
+	return New(ErrCodeInsufficientStock, fmt.Sprintf("%s 可用账号不足：需要 %d 个，仅有 %d 个", accountType, requested, available))
+}
+
+// NewInvalidQuery reports a search query error at a position in characters, from 1
+func NewInvalidQuery(pos int, reason string) *AppError {
+	return New(ErrCodeInvalidQuery, fmt.Sprintf("搜索语法错误（第 %d 个字符）：%s", pos, reason))
+}
+
+func NewInvalidFieldValue(field, reason string) *AppError {
+	return New(ErrCodeInvalidFieldValue, fmt.Sprintf("字段 %s %s", field, reason))
+}
@@ -1,5 +1,7 @@
 package errors
 
+import "fmt"
+
 // Authentication-specific error constructors
 
 func NewPasswordTooShort(minLength int) *AppError {
@@ -9,3 +11,43 @@ func NewPasswordTooShort(minLength int) *AppError {
 func NewInvalidPassword() *AppError {
 	return New(ErrCodeInvalidPassword, "密码错误")
 }
+
+func NewVaultNotInitialized() *AppError {
+	return New(ErrCodeVaultNotInitialized, "尚未设置主密码")
+}
+
+func NewVaultInitialized() *AppError {
+	return New(ErrCodeVaultInitialized, "主密码已设置")
+}
+
+func NewVaultLocked() *AppError {
+	return New(ErrCodeVaultLocked, "密码库已锁定，请先解锁")
+}
+
+func NewKeyProviderFailed(err error) *AppError {
+	return Wrap(err, ErrCodeKeyProviderFailed, "无法获取加密密钥")
+}
+
+func NewNotLoggedIn() *AppError {
+	return New(ErrCodeNotLoggedIn, "请先登录")
+}
+
+func NewPermissionDenied(permission string) *AppError {
+	return New(ErrCodePermissionDenied, fmt.Sprintf("没有权限执行此操作（%s）", permission))
+}
+
+func NewUserNotFound() *AppError {
+	return New(ErrCodeUserNotFound, "用户不存在")
+}
+
+func NewUserExists() *AppError {
+	return New(ErrCodeUserExists, "用户名已存在")
+}
+
+func NewUserDisabled() *AppError {
+	return New(ErrCodeUserDisabled, "用户已被禁用")
+}
+
+func NewLastAdmin() *AppError {
+	return New(ErrCodeLastAdmin, "至少需要保留一个启用的管理员")
+}
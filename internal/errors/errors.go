@@ -11,11 +11,46 @@ const (
 	ErrCodeAccountExists       ErrorCode = "ACCOUNT_EXISTS"
 	ErrCodeAccountNotFound     ErrorCode = "ACCOUNT_NOT_FOUND"
 	ErrCodeAccountNameInUse    ErrorCode = "ACCOUNT_NAME_IN_USE"
+	ErrCodeAccountInTrash      ErrorCode = "ACCOUNT_IN_TRASH"
+	ErrCodeChangeSetNotFound   ErrorCode = "CHANGE_SET_NOT_FOUND"
+	ErrCodeRevertConflict      ErrorCode = "REVERT_CONFLICT"
+	ErrCodeInvalidTOTP         ErrorCode = "INVALID_TOTP"
+	ErrCodeTOTPNotConfigured   ErrorCode = "TOTP_NOT_CONFIGURED"
+	ErrCodeAccountTypeNotFound ErrorCode = "ACCOUNT_TYPE_NOT_FOUND"
+	ErrCodeAccountTypeExists   ErrorCode = "ACCOUNT_TYPE_EXISTS"
+	ErrCodeAccountTypeInUse    ErrorCode = "ACCOUNT_TYPE_IN_USE"
+	ErrCodeStatusNotFound      ErrorCode = "ACCOUNT_STATUS_NOT_FOUND"
+	ErrCodeStatusExists        ErrorCode = "ACCOUNT_STATUS_EXISTS"
+	ErrCodeStatusInUse         ErrorCode = "ACCOUNT_STATUS_IN_USE"
+	ErrCodeInvalidTransition   ErrorCode = "INVALID_STATUS_TRANSITION"
+	ErrCodeTagNotFound         ErrorCode = "TAG_NOT_FOUND"
+	ErrCodeTagExists           ErrorCode = "TAG_EXISTS"
+	ErrCodeFieldNotFound       ErrorCode = "CUSTOM_FIELD_NOT_FOUND"
+	ErrCodeFieldExists         ErrorCode = "CUSTOM_FIELD_EXISTS"
+	ErrCodeInvalidFieldValue   ErrorCode = "INVALID_FIELD_VALUE"
+	ErrCodeSaleNotFound        ErrorCode = "SALE_NOT_FOUND"
+	ErrCodeAccountSold         ErrorCode = "ACCOUNT_ALREADY_SOLD"
+	ErrCodeSaleReversed        ErrorCode = "SALE_REVERSED"
+	ErrCodeCustomerNotFound    ErrorCode = "CUSTOMER_NOT_FOUND"
+	ErrCodeReservationNotFound ErrorCode = "RESERVATION_NOT_FOUND"
+	ErrCodeReservationClosed   ErrorCode = "RESERVATION_CLOSED"
+	ErrCodeAccountReserved     ErrorCode = "ACCOUNT_RESERVED"
+	ErrCodeInsufficientStock   ErrorCode = "INSUFFICIENT_STOCK"
 
 	// Authentication errors
 	ErrCodePasswordTooShort    ErrorCode = "PASSWORD_TOO_SHORT"
 	ErrCodeInvalidPassword     ErrorCode = "INVALID_PASSWORD"
 	ErrCodeDecryptionFailed    ErrorCode = "DECRYPTION_FAILED"
+	ErrCodeVaultNotInitialized ErrorCode = "VAULT_NOT_INITIALIZED"
+	ErrCodeVaultInitialized    ErrorCode = "VAULT_ALREADY_INITIALIZED"
+	ErrCodeVaultLocked         ErrorCode = "VAULT_LOCKED"
+	ErrCodeKeyProviderFailed   ErrorCode = "KEY_PROVIDER_FAILED"
+	ErrCodeNotLoggedIn         ErrorCode = "NOT_LOGGED_IN"
+	ErrCodePermissionDenied    ErrorCode = "PERMISSION_DENIED"
+	ErrCodeUserNotFound        ErrorCode = "USER_NOT_FOUND"
+	ErrCodeUserExists          ErrorCode = "USER_EXISTS"
+	ErrCodeUserDisabled        ErrorCode = "USER_DISABLED"
+	ErrCodeLastAdmin           ErrorCode = "LAST_ADMIN"
 
 	// Email errors
 	ErrCodeEmailConfigFailed   ErrorCode = "EMAIL_CONFIG_FAILED"
@@ -29,9 +64,18 @@ const (
 	ErrCodeSSHConnectionFailed ErrorCode = "SSH_CONNECTION_FAILED"
 	ErrCodeEncryptionFailed    ErrorCode = "ENCRYPTION_FAILED"
 
+	// Password policy errors
+	ErrCodePolicyNotFound      ErrorCode = "PASSWORD_POLICY_NOT_FOUND"
+	ErrCodePolicyExists        ErrorCode = "PASSWORD_POLICY_EXISTS"
+	ErrCodePolicyBuiltIn       ErrorCode = "PASSWORD_POLICY_BUILT_IN"
+
+	// Audit log errors
+	ErrCodeAuditChainBroken    ErrorCode = "AUDIT_CHAIN_BROKEN"
+
 	// Validation errors
 	ErrCodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
 	ErrCodeInvalidInput        ErrorCode = "INVALID_INPUT"
+	ErrCodeInvalidQuery        ErrorCode = "INVALID_QUERY"
 )
 
 // AppError represents an application error with code and message
@@ -37,3 +37,23 @@ func NewSSHConnectionFailed(err error) *AppError {
 func NewEncryptionFailed(field string, err error) *AppError {
 	return Wrap(err, ErrCodeEncryptionFailed, fmt.Sprintf("加密%s失败", field))
 }
+
+// Password policy error constructors
+
+func NewPolicyNotFound(name string) *AppError {
+	return New(ErrCodePolicyNotFound, fmt.Sprintf("密码策略 %s 不存在", name))
+}
+
+func NewPolicyExists(name string) *AppError {
+	return New(ErrCodePolicyExists, fmt.Sprintf("密码策略 %s 已存在", name))
+}
+
+func NewPolicyBuiltIn() *AppError {
+	return New(ErrCodePolicyBuiltIn, "内置密码策略不能删除或重命名")
+}
+
+// Audit log error constructors
+
+func NewAuditChainBroken(logID uint, reason string) *AppError {
+	return New(ErrCodeAuditChainBroken, fmt.Sprintf("审计日志在记录 %d 处被篡改：%s", logID, reason))
+}
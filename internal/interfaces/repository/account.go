@@ -1,16 +1,37 @@
 package repository
 
-import "account-manager/internal/models"
+import (
+	"time"
+
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+)
 
 // IAccountRepository defines the interface for account data access
 type IAccountRepository interface {
 	Create(account *models.Account) error
 	Update(account *models.Account) error
-	Delete(id uint) error
+	Delete(id uint, deletedBy string) error
+	Restore(id uint) error
+	Purge(id uint) error
+	FindDeletedByID(id uint) (*models.Account, error)
+	FindDeletedByAccount(accountName string) (*models.Account, error)
+	FindTrash(page, pageSize int) (*models.PaginatedAccounts, error)
+	FindTrashedBefore(date time.Time) ([]uint, error)
 	FindByID(id uint) (*models.Account, error)
 	FindByAccount(accountName string) (*models.Account, error)
 	FindAll(filter models.AccountFilter) (*models.PaginatedAccounts, error)
+	FindIDs(filter models.AccountFilter) ([]uint, error)
+	FindByIDs(ids []uint) ([]models.Account, error)
+	ApplyBulk(write *repository.BulkWrite) error
+	FindAllSecrets() ([]models.Account, error)
+	FindUncheckedSecrets() ([]models.Account, error)
+	UpdatePasswordHealth(account *models.Account) error
+	RefreshReusedFlags() error
+	GetPasswordHealthStats() (*models.PasswordHealthStats, error)
 	GetStats() (*models.AccountStats, error)
+	CountByType(accountType models.AccountType) (int64, error)
+	CountByStatus(status string) (int64, error)
 	FindExpiringAccounts(daysBefore int) ([]models.Account, error)
 	MarkReminderSent(ids []uint) error
 	BatchCreate(accounts []models.Account) error
@@ -0,0 +1,10 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IAccountChangeRepository defines the interface for account change history data access
+type IAccountChangeRepository interface {
+	Create(set *models.AccountChangeSet) error
+	FindByID(id uint) (*models.AccountChangeSet, error)
+	FindByAccount(accountID uint) ([]models.AccountChangeSet, error)
+}
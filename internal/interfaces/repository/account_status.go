@@ -0,0 +1,20 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IAccountStatusRepository defines the interface for account status data access
+type IAccountStatusRepository interface {
+	FindAll() ([]models.AccountStatusConfig, error)
+	FindByID(id uint) (*models.AccountStatusConfig, error)
+	FindByName(name string) (*models.AccountStatusConfig, error)
+	FindInitial() (*models.AccountStatusConfig, error)
+	FindFirstSold() (*models.AccountStatusConfig, error)
+	Create(status *models.AccountStatusConfig) error
+	Update(status *models.AccountStatusConfig, oldName string) error
+	Delete(status *models.AccountStatusConfig) error
+	FindTransitions() ([]models.AccountStatusTransition, error)
+	TransitionAllowed(from, to string) (bool, error)
+	SetTransitions(from string, to []string) error
+	CreateChange(change *models.AccountStatusChange) error
+	FindChanges(accountID uint) ([]models.AccountStatusChange, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IAccountTypeRepository defines the interface for account type data access
+type IAccountTypeRepository interface {
+	FindAll() ([]models.AccountTypeConfig, error)
+	FindByID(id uint) (*models.AccountTypeConfig, error)
+	FindByName(name models.AccountType) (*models.AccountTypeConfig, error)
+	Create(accountType *models.AccountTypeConfig) error
+	Update(accountType *models.AccountTypeConfig, oldName models.AccountType) error
+	Delete(id uint) error
+}
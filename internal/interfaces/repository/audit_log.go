@@ -12,4 +12,16 @@ type IAuditLogRepository interface {
 	FindAll(filter models.AuditLogFilter) (*models.PaginatedAuditLogs, error)
 	DeleteOlderThan(date time.Time) error
 	GetStats() (map[string]int64, error)
+	Last() (*models.AuditLog, error)
+	FindUnchained() ([]models.AuditLog, error)
+	UpdateChain(id uint, prevHash, hash string) error
+	FindAfter(afterID uint, limit int) ([]models.AuditLog, error)
+	FindByID(id uint) (*models.AuditLog, error)
+	PruneRange(date time.Time) (firstID, lastID uint, count int64, err error)
+	Prune(lastID uint, anchor *models.AuditCheckpoint) error
+	CreateCheckpoint(checkpoint *models.AuditCheckpoint) error
+	LastCheckpoint(kind string) (*models.AuditCheckpoint, error)
+	FindCheckpoints(kind string) ([]models.AuditCheckpoint, error)
+	GetSigningKey() (*models.AuditSigningKey, error)
+	CreateSigningKey(key *models.AuditSigningKey) error
 }
@@ -0,0 +1,11 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IBreachRepository defines the interface for breached password data access
+type IBreachRepository interface {
+	Upsert(entries []models.BreachedPassword) error
+	FindByHash(hash string) (*models.BreachedPassword, error)
+	Count() (int64, error)
+	DeleteAll() error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "account-manager/internal/models"
+
+// ICustomFieldRepository defines the interface for custom field data access
+type ICustomFieldRepository interface {
+	FindByType(accountType models.AccountType) ([]models.CustomField, error)
+	FindAll() ([]models.CustomField, error)
+	FindByID(id uint) (*models.CustomField, error)
+	FindByName(accountType models.AccountType, name string) (*models.CustomField, error)
+	Create(field *models.CustomField) error
+	Update(field *models.CustomField) error
+	Delete(id uint) error
+	CountValues(fieldID uint) (int64, error)
+	FindValues(accountIDs []uint) ([]models.AccountFieldValue, error)
+	FindSecretValues() ([]models.AccountFieldValue, error)
+	SetValues(accountID uint, values []models.AccountFieldValue, clear []uint) error
+	DeleteValues(accountID uint) error
+}
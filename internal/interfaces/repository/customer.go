@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/models"
+)
+
+// ICustomerRepository defines the interface for customer data access
+type ICustomerRepository interface {
+	FindAll(search string, page, pageSize int) (*models.PaginatedCustomers, error)
+	FindByID(id uint) (*models.Customer, error)
+	FindByIDs(ids []uint) ([]models.Customer, error)
+	Create(customer *models.Customer) error
+	Update(customer *models.Customer) error
+	Delete(id uint) error
+	AssignAccounts(customerID *uint, accountIDs []uint) (int64, error)
+	FindExpiring(customerID uint, from, to time.Time) ([]models.Account, error)
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IPasswordHistoryRepository defines the interface for password history data access
+type IPasswordHistoryRepository interface {
+	Create(entry *models.PasswordHistory) error
+	FindByID(id uint) (*models.PasswordHistory, error)
+	FindByAccount(accountID uint) ([]models.PasswordHistory, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IPasswordPolicyRepository defines the interface for password policy data access
+type IPasswordPolicyRepository interface {
+	FindAll() ([]models.PasswordPolicy, error)
+	FindByID(id uint) (*models.PasswordPolicy, error)
+	FindByName(name string) (*models.PasswordPolicy, error)
+	Create(policy *models.PasswordPolicy) error
+	Update(policy *models.PasswordPolicy) error
+	Delete(id uint) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/models"
+)
+
+// IRenewalRepository defines the interface for renewal history data access
+type IRenewalRepository interface {
+	Create(renewal *models.Renewal) error
+	FindByAccount(accountID uint) ([]models.Renewal, error)
+	FindBetween(from, to time.Time) ([]models.Renewal, error)
+	Renew(account *models.Account, renewal *models.Renewal) error
+}
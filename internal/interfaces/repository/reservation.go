@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/models"
+)
+
+// IReservationRepository defines the interface for account reservation data access
+type IReservationRepository interface {
+	Allocate(reservation *models.Reservation, status string, now time.Time) ([]uint, error)
+	Close(reservation *models.Reservation) error
+	FindByID(id uint) (*models.Reservation, error)
+	FindAll(status string, page, pageSize int) (*models.PaginatedReservations, error)
+	FindExpired(now time.Time) ([]models.Reservation, error)
+	FindAccounts(reservationIDs []uint) ([]models.Account, error)
+}
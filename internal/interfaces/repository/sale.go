@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/models"
+)
+
+// ISaleRepository defines the interface for sales ledger data access
+type ISaleRepository interface {
+	Create(sale *models.Sale) error
+	Update(sale *models.Sale) error
+	Record(account *models.Account, sales []models.Sale) error
+	FindByID(id uint) (*models.Sale, error)
+	FindOpenSale(accountID uint) (*models.Sale, error)
+	FindReversal(saleID uint) (*models.Sale, error)
+	FindByAccount(accountID uint) ([]models.Sale, error)
+	FindAll(filter models.SaleFilter) (*models.PaginatedSales, error)
+	FindBetween(from, to time.Time) ([]models.Sale, error)
+}
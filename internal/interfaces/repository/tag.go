@@ -0,0 +1,17 @@
+package repository
+
+import "account-manager/internal/models"
+
+// ITagRepository defines the interface for tag data access
+type ITagRepository interface {
+	FindAll() ([]models.Tag, error)
+	FindByID(id uint) (*models.Tag, error)
+	FindByName(name string) (*models.Tag, error)
+	FindByIDs(ids []uint) ([]models.Tag, error)
+	Create(tag *models.Tag) error
+	Update(tag *models.Tag) error
+	Delete(id uint) error
+	AddToAccounts(accountIDs, tagIDs []uint) (int64, error)
+	RemoveFromAccounts(accountIDs, tagIDs []uint) (int64, error)
+	SetAccountTags(accountID uint, tagIDs []uint) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IUserRepository defines the interface for local user data access
+type IUserRepository interface {
+	Create(user *models.User) error
+	Update(user *models.User) error
+	Delete(id uint) error
+	FindByID(id uint) (*models.User, error)
+	FindByUsername(username string) (*models.User, error)
+	FindAll() ([]models.User, error)
+	Count() (int64, error)
+	CountActiveAdmins() (int64, error)
+	UpdateLastLogin(id uint) error
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "account-manager/internal/models"
+
+// IVaultRepository defines the interface for vault settings data access
+type IVaultRepository interface {
+	GetConfig() (*models.VaultConfig, error)
+	SaveConfig(config *models.VaultConfig) error
+	Exists() (bool, error)
+}
@@ -11,11 +11,50 @@ type IAccountService interface {
 	CreateAccount(account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool) error
 	UpdateAccount(id uint, account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool) error
 	DeleteAccount(id uint) error
+	GetTrash(page, pageSize int) (*models.PaginatedAccounts, error)
+	RestoreAccount(id uint) error
+	PurgeAccount(id uint) error
+	EmptyTrash() (int, error)
+	PurgeExpiredTrash() (int, error)
+	GetTrashRetentionDays() int
+	SetTrashRetentionDays(days int) error
 	GetAccount(id uint) (*models.Account, error)
 	GetAccounts(filter models.AccountFilter) (*models.PaginatedAccounts, error)
 	GetStats() (*models.AccountStats, error)
 	MarkAsSold(id uint) error
 	MarkAsUnsold(id uint) error
+	ChangeStatus(id uint, status string, note string) error
+	GetChangeHistory(accountID uint) ([]models.AccountChangeSet, error)
+	RevertChangeSet(changeSetID uint) error
+	CreateAccountWithFields(account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string) error
+	UpdateAccountWithFields(id uint, account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string) error
 	BatchImport(accounts []map[string]interface{}) (int, []string)
+	ExtendExpiry(id uint, days int, cost *models.Cents, note string) (*models.Renewal, error)
+	GetRenewals(accountID uint) ([]models.Renewal, error)
+	GetRenewalReport(from, to time.Time, granularity string) (*models.RenewalReport, error)
+	SellAccount(id uint, details models.SaleDetails) (*models.Sale, error)
+	UpdateSale(id uint, details models.SaleDetails) (*models.Sale, error)
+	GetSales(filter models.SaleFilter) (*models.PaginatedSales, error)
+	GetRevenueReport(from, to time.Time, granularity string) (*models.RevenueReport, error)
+	GetDefaultCurrency() string
+	SetDefaultCurrency(currency string) error
+	BulkChangeStatus(selection models.BulkSelection, status string, note string) (*models.BulkResult, error)
+	BulkMarkAsSold(selection models.BulkSelection) (*models.BulkResult, error)
+	BulkMarkAsUnsold(selection models.BulkSelection) (*models.BulkResult, error)
+	BulkExtendExpiry(selection models.BulkSelection, days int) (*models.BulkResult, error)
+	BulkChangeType(selection models.BulkSelection, accountType string) (*models.BulkResult, error)
+	BulkDelete(selection models.BulkSelection) (*models.BulkResult, error)
+	ReserveAccounts(request models.ReservationRequest) (*models.Reservation, error)
+	ConfirmReservation(id uint, details models.SaleDetails) (*models.BulkResult, error)
+	ReleaseReservation(id uint) error
+	ExpireReservations() (int, error)
+	GetReservations(status string, page, pageSize int) (*models.PaginatedReservations, error)
+	GetReservation(id uint) (*models.Reservation, error)
 	DecryptPassword(id uint) (string, error)
+	SetTOTP(id uint, secret string) error
+	RemoveTOTP(id uint) error
+	GetTOTPCode(id uint) (*models.TOTPCode, error)
+	RenderCopyText(id uint) (string, error)
+	RebuildSearchIndex() error
+	ClearSearchIndex()
 }
@@ -0,0 +1,13 @@
+package service
+
+import "account-manager/internal/models"
+
+// IAccountStatusService defines the interface for the account status workflow
+type IAccountStatusService interface {
+	GetStatuses() ([]models.AccountStatusConfig, error)
+	SaveStatus(status *models.AccountStatusConfig) error
+	DeleteStatus(id uint) error
+	GetTransitions() ([]models.AccountStatusTransition, error)
+	SetTransitions(from string, to []string) error
+	GetStatusHistory(accountID uint) ([]models.AccountStatusChange, error)
+}
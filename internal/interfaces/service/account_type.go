@@ -0,0 +1,10 @@
+package service
+
+import "account-manager/internal/models"
+
+// IAccountTypeService defines the interface for account type business logic
+type IAccountTypeService interface {
+	GetTypes() ([]models.AccountTypeConfig, error)
+	SaveType(accountType *models.AccountTypeConfig) error
+	DeleteType(id uint) error
+}
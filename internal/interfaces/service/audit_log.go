@@ -9,6 +9,8 @@ type IAuditLogService interface {
 	LogAccountCreate(accountID uint, user string, account string) error
 	LogAccountUpdate(accountID uint, user string, changes map[string]interface{}) error
 	LogAccountDelete(accountID uint, user string, account string) error
+	LogAccountRestore(accountID uint, user string, account string) error
+	LogAccountPurge(accountID uint, user string, account string) error
 	LogPasswordView(accountID uint, user string, action string) error
 	LogLogin(user string, success bool, errorMsg string) error
 	LogLogout(user string) error
@@ -17,4 +19,6 @@ type IAuditLogService interface {
 	CleanupOldLogs(retentionDays int) error
 	GetStats() (map[string]int64, error)
 	ExportToCSV(filter models.AuditLogFilter) (string, error)
+	Checkpoint() error
+	VerifyAuditChain() (*models.AuditChainReport, error)
 }
@@ -0,0 +1,10 @@
+package service
+
+import "account-manager/internal/models"
+
+// ICustomFieldService defines the interface for custom field business logic
+type ICustomFieldService interface {
+	GetFields(accountType string) ([]models.CustomField, error)
+	SaveField(field *models.CustomField) error
+	DeleteField(id uint) error
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"time"
+
+	"account-manager/internal/models"
+)
+
+// ICustomerService defines the interface for customer business logic
+type ICustomerService interface {
+	GetCustomers(search string, page, pageSize int) (*models.PaginatedCustomers, error)
+	GetCustomer(id uint) (*models.Customer, error)
+	SaveCustomer(customer *models.Customer) error
+	DeleteCustomer(id uint) error
+	AssignAccounts(customerID uint, accountIDs []uint) (int64, error)
+	UnassignAccounts(accountIDs []uint) (int64, error)
+	GetExpiryOverview(customerID uint, from, to time.Time) ([]models.CustomerExpiry, error)
+}
@@ -0,0 +1,14 @@
+package service
+
+import "account-manager/internal/models"
+
+// IPasswordGeneratorService defines the interface for password generation and policies
+type IPasswordGeneratorService interface {
+	Generate(policyName string) (*models.GeneratedPassword, error)
+	GenerateForType(accountType string) (*models.GeneratedPassword, error)
+	GetPolicies() ([]models.PasswordPolicy, error)
+	SavePolicy(policy *models.PasswordPolicy) error
+	DeletePolicy(id uint) error
+	GetTypePolicies() (map[string]string, error)
+	SetTypePolicy(accountType, policyName string) error
+}
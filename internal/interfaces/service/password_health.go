@@ -0,0 +1,13 @@
+package service
+
+import "account-manager/internal/models"
+
+// IPasswordHealthService defines the interface for password strength and breach checking
+type IPasswordHealthService interface {
+	CheckPassword(password string) *models.PasswordCheck
+	RecheckAll() (int, error)
+	RecheckPending() (int, error)
+	GetStats() (*models.PasswordHealthStats, error)
+	ImportBreachFile(path string) (int, error)
+	ClearBreachData() error
+}
@@ -0,0 +1,10 @@
+package service
+
+import "account-manager/internal/models"
+
+// IPasswordHistoryService defines the interface for previous account passwords
+type IPasswordHistoryService interface {
+	GetHistory(accountID uint) ([]models.PasswordHistory, error)
+	Reveal(historyID uint) (string, error)
+	Restore(historyID uint) error
+}
@@ -0,0 +1,13 @@
+package service
+
+import "account-manager/internal/models"
+
+// ITagService defines the interface for account tag business logic
+type ITagService interface {
+	GetTags() ([]models.Tag, error)
+	SaveTag(tag *models.Tag) error
+	DeleteTag(id uint) error
+	TagAccounts(accountIDs, tagIDs []uint) (int64, error)
+	UntagAccounts(accountIDs, tagIDs []uint) (int64, error)
+	SetAccountTags(accountID uint, tagIDs []uint) error
+}
@@ -0,0 +1,17 @@
+package service
+
+import "account-manager/internal/models"
+
+// IUserService defines the interface for local users and the login session
+type IUserService interface {
+	Initialize() error
+	GetSession() *models.Session
+	Login(username, password string) (*models.Session, error)
+	Logout()
+	GetUsers() ([]models.User, error)
+	CreateUser(username, displayName, password string, role models.Role) (*models.User, error)
+	UpdateUser(id uint, displayName string, role models.Role, disabled bool) error
+	ResetPassword(id uint, newPassword string) error
+	ChangePassword(oldPassword, newPassword string) error
+	DeleteUser(id uint) error
+}
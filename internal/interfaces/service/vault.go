@@ -0,0 +1,23 @@
+package service
+
+import "account-manager/internal/models"
+
+// IVaultService defines the interface for master password and data key management
+type IVaultService interface {
+	IsInitialized() bool
+	IsUnlocked() bool
+	IsInteractive() bool
+	Setup(masterPassword string) error
+	Unlock(masterPassword string) error
+	Lock()
+	OnLock(hook func())
+	OnUnlock(hook func())
+	OnKeyRotated(hook func())
+	GetAutoLockMinutes() int
+	SetAutoLockMinutes(minutes int) error
+	StartAutoLock()
+	StopAutoLock()
+	HasPendingRotation() bool
+	RotateKey(currentMasterPassword, newMasterPassword string, progress func(models.KeyRotationProgress)) error
+	ResumeRotation(progress func(models.KeyRotationProgress)) error
+}
@@ -0,0 +1,117 @@
+package migration
+
+import (
+	"encoding/json"
+	"strings"
+
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const accountStatusesFlag = "account_statuses_migrated"
+
+// Values the settings page used in SystemConfig.AccountStatuses for IsSold
+var legacyStatusValues = map[string]string{
+	"unsold": models.AccountStatusInStock,
+	"sold":   models.AccountStatusSold,
+}
+
+// MigrateAccountStatuses creates the default status workflow on first start,
+// keeps the labels and colours saved by the settings page in
+// SystemConfig.AccountStatuses and derives the status of existing accounts
+// from IsSold. It runs once per database.
+func (s *MigrationService) MigrateAccountStatuses() error {
+	done, err := s.isFlagSet(accountStatusesFlag)
+	if err != nil || done {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		sortOrder := 0
+		for _, status := range models.DefaultAccountStatuses {
+			st := status
+			if err := tx.Where(models.AccountStatusConfig{Name: st.Name}).FirstOrCreate(&st).Error; err != nil {
+				return err
+			}
+			sortOrder = st.SortOrder
+		}
+		for _, transition := range models.DefaultAccountStatusTransitions {
+			t := transition
+			if err := tx.Where(t).FirstOrCreate(&t).Error; err != nil {
+				return err
+			}
+		}
+
+		var sysConfig models.SystemConfig
+		if tx.First(&sysConfig).Error == nil && sysConfig.AccountStatuses != "" {
+			var tags []typeTag
+			if err := json.Unmarshal([]byte(sysConfig.AccountStatuses), &tags); err != nil {
+				logger.WithField("error", err.Error()).Warn("Ignoring unreadable account status settings")
+			}
+			for _, tag := range tags {
+				name := strings.TrimSpace(tag.Value)
+				if name == "" {
+					continue
+				}
+				if mapped, ok := legacyStatusValues[name]; ok {
+					updates := map[string]interface{}{}
+					if tag.Label != "" {
+						updates["label"] = tag.Label
+					}
+					if tag.Color != "" {
+						updates["color"] = tag.Color
+					}
+					if len(updates) > 0 {
+						if err := tx.Model(&models.AccountStatusConfig{}).Where("name = ?", mapped).Updates(updates).Error; err != nil {
+							return err
+						}
+					}
+					continue
+				}
+
+				// Custom statuses can be entered from and left to stock
+				sortOrder++
+				status := models.AccountStatusConfig{Name: name, Label: tag.Label, Color: tag.Color, SortOrder: sortOrder}
+				if err := tx.Where(models.AccountStatusConfig{Name: name}).FirstOrCreate(&status).Error; err != nil {
+					return err
+				}
+				for _, t := range []models.AccountStatusTransition{
+					{FromStatus: models.AccountStatusInStock, ToStatus: name},
+					{FromStatus: name, ToStatus: models.AccountStatusInStock},
+				} {
+					if err := tx.Where(t).FirstOrCreate(&t).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		// Existing accounts start in stock or sold
+		if err := tx.Model(&models.Account{}).
+			Where("(status IS NULL OR status = '') AND is_sold = ?", true).
+			UpdateColumns(map[string]interface{}{
+				"status":    models.AccountStatusSold,
+				"status_at": gorm.Expr("sold_at"),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Account{}).
+			Where("status IS NULL OR status = ''").
+			UpdateColumns(map[string]interface{}{
+				"status":    models.AccountStatusInStock,
+				"status_at": gorm.Expr("created_at"),
+			}).Error; err != nil {
+			return err
+		}
+
+		return setFlag(tx, accountStatusesFlag)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Account statuses migrated")
+	return nil
+}
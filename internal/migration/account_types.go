@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"encoding/json"
+	"strings"
+
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const accountTypesFlag = "account_types_migrated"
+
+// typeTag is the format the settings page stored in SystemConfig.AccountTypes
+type typeTag struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+	Color string `json:"color"`
+}
+
+// MigrateAccountTypes fills the account type table on first start with the
+// formerly hardcoded types, the types saved by the settings page in
+// SystemConfig.AccountTypes and any other type used by existing accounts.
+// It runs once per database, so deleted types are not recreated.
+func (s *MigrationService) MigrateAccountTypes() error {
+	done, err := s.isFlagSet(accountTypesFlag)
+	if err != nil || done {
+		return err
+	}
+
+	created := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		sortOrder := 0
+		add := func(accountType models.AccountTypeConfig) error {
+			var existing models.AccountTypeConfig
+			err := tx.Where("name = ?", accountType.Name).First(&existing).Error
+			if err == nil {
+				if accountType.Color != "" && accountType.Color != existing.Color {
+					return tx.Model(&existing).UpdateColumn("color", accountType.Color).Error
+				}
+				return nil
+			}
+			sortOrder++
+			accountType.SortOrder = sortOrder
+			created++
+			return tx.Create(&accountType).Error
+		}
+
+		for _, accountType := range models.DefaultAccountTypes {
+			if err := add(accountType); err != nil {
+				return err
+			}
+		}
+
+		var sysConfig models.SystemConfig
+		if tx.First(&sysConfig).Error == nil && sysConfig.AccountTypes != "" {
+			var tags []typeTag
+			if err := json.Unmarshal([]byte(sysConfig.AccountTypes), &tags); err != nil {
+				logger.WithField("error", err.Error()).Warn("Ignoring unreadable account type settings")
+			}
+			for _, tag := range tags {
+				name := strings.TrimSpace(tag.Value)
+				if name == "" {
+					continue
+				}
+				if err := add(models.AccountTypeConfig{Name: models.AccountType(name), Color: tag.Color, Expires: true}); err != nil {
+					return err
+				}
+			}
+		}
+
+		var used []string
+		if err := tx.Model(&models.Account{}).Distinct().Where("account_type <> ''").Pluck("account_type", &used).Error; err != nil {
+			return err
+		}
+		for _, name := range used {
+			if err := add(models.AccountTypeConfig{Name: models.AccountType(name), Expires: true}); err != nil {
+				return err
+			}
+		}
+
+		return setFlag(tx, accountTypesFlag)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.WithField("types", created).Info("Account types migrated")
+	return nil
+}
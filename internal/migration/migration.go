@@ -2,6 +2,10 @@ package migration
 
 import (
 	"errors"
+	"fmt"
+
+	"account-manager/internal/logger"
+	"account-manager/internal/utils"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +18,55 @@ type SystemConfig struct {
 	EncryptionMigrated bool  `gorm:"default:false"`
 }
 
+// TableName keeps migration flags out of the models.SystemConfig table
+func (SystemConfig) TableName() string {
+	return "migration_flags"
+}
+
+// EncryptedColumn identifies a database column holding encrypted values
+type EncryptedColumn struct {
+	Table  string
+	Column string
+}
+
+// legacyEncryptedColumns lists the columns encrypted with the built-in key
+// before the master password was introduced
+var legacyEncryptedColumns = []EncryptedColumn{
+	{Table: "accounts", Column: "password"},
+	{Table: "email_configs", Column: "sender_password"},
+	{Table: "server_configs", Column: "password"},
+	{Table: "server_configs", Column: "private_key"},
+}
+
+// SensitiveColumn is a column that used to be stored in plaintext and is
+// encrypted once by EncryptSensitiveColumns, tracked by its migration flag
+type SensitiveColumn struct {
+	EncryptedColumn
+	Flag string
+}
+
+// sensitiveColumns lists formerly plaintext columns. Add future sensitive
+// fields here and to EncryptedColumns.
+var sensitiveColumns = []SensitiveColumn{
+	{EncryptedColumn: EncryptedColumn{Table: "accounts", Column: "notes"}, Flag: "notes_encrypted"},
+}
+
+// EncryptedColumns lists every column written with utils.Encrypt
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "accounts", Column: "password"},
+	{Table: "accounts", Column: "notes"},
+	{Table: "accounts", Column: "totp"},
+	{Table: "email_configs", Column: "sender_password"},
+	{Table: "server_configs", Column: "password"},
+	{Table: "server_configs", Column: "private_key"},
+	{Table: "password_histories", Column: "password"},
+	{Table: "audit_signing_keys", Column: "private_key"},
+	{Table: "account_field_values", Column: "secret_value"},
+	{Table: "account_field_changes", Column: "old_secret"},
+	{Table: "account_field_changes", Column: "new_secret"},
+	{Table: "customers", Column: "notes"},
+}
+
 // MigrationService handles data migration operations
 type MigrationService struct {
 	db *gorm.DB
@@ -38,23 +91,269 @@ func (s *MigrationService) IsEncryptionMigrated() (bool, error) {
 	return config.EncryptionMigrated, nil
 }
 
-// MigrateEncryption marks encryption migration as complete
-// No longer needed with fixed encryption key
+// MigrateEncryption re-encrypts all values written with the legacy built-in
+// key using the master password derived key. It runs once per database.
 func (s *MigrationService) MigrateEncryption() error {
-	return s.MarkEncryptionMigrated()
+	if !utils.HasEncryptionKey() {
+		return utils.ErrNoEncryptionKey
+	}
+
+	migrated, err := s.IsEncryptionMigrated()
+	if err != nil {
+		return err
+	}
+	if migrated {
+		return nil
+	}
+
+	count := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, col := range legacyEncryptedColumns {
+			n, err := reencryptColumn(tx, col, utils.DecryptLegacy, utils.Encrypt)
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		return markEncryptionMigrated(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.WithField("values", count).Info("Legacy encrypted values migrated to master password key")
+	return nil
+}
+
+// EncryptSensitiveColumns encrypts columns that used to be stored in
+// plaintext, such as account notes. Each column is converted once in its own
+// transaction and marked with a migration flag. It must run before
+// RewrapLegacyValues or a key rotation, which expect every value encrypted.
+func (s *MigrationService) EncryptSensitiveColumns() error {
+	if !utils.HasEncryptionKey() {
+		return utils.ErrNoEncryptionKey
+	}
+	release := utils.HoldKey()
+	defer release()
+
+	for _, col := range sensitiveColumns {
+		done, err := s.isFlagSet(col.Flag)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+
+		count := 0
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			var rows []encryptedValue
+			err := tx.Table(col.Table).
+				Select("id, "+col.Column+" AS value").
+				Where(col.Column+" IS NOT NULL AND "+col.Column+" <> '' AND "+col.Column+" NOT LIKE ?", "v2:%").
+				Scan(&rows).Error
+			if err != nil {
+				return err
+			}
+
+			for _, r := range rows {
+				encrypted, err := utils.Encrypt(r.Value)
+				if err != nil {
+					return err
+				}
+				if err := tx.Table(col.Table).Where("id = ?", r.ID).UpdateColumn(col.Column, encrypted).Error; err != nil {
+					return err
+				}
+			}
+			count = len(rows)
+			return setFlag(tx, col.Flag)
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.WithFields(map[string]interface{}{
+			"table":  col.Table,
+			"column": col.Column,
+			"values": count,
+		}).Info("Plaintext column encrypted")
+	}
+
+	return nil
+}
+
+// RewrapLegacyValues upgrades values still stored in the unauthenticated
+// AES-CFB format to the current authenticated format. Rows are rewritten one
+// at a time so it can run in the background; values that fail to decrypt are
+// logged and left untouched.
+func (s *MigrationService) RewrapLegacyValues() (int, error) {
+	if !utils.HasEncryptionKey() {
+		return 0, utils.ErrNoEncryptionKey
+	}
+	release := utils.HoldKey()
+	defer release()
+
+	count := 0
+	for _, col := range EncryptedColumns {
+		rows, err := findLegacyValues(s.db, col)
+		if err != nil {
+			return count, err
+		}
+
+		for _, r := range rows {
+			plaintext, err := utils.Decrypt(r.Value)
+			if err != nil {
+				logger.WithFields(map[string]interface{}{
+					"table": col.Table,
+					"id":    r.ID,
+					"error": err.Error(),
+				}).Warn("Skipping legacy value that failed to decrypt")
+				continue
+			}
+			encrypted, err := utils.Encrypt(plaintext)
+			if err != nil {
+				return count, err
+			}
+
+			// Only overwrite the value we read, in case it changed meanwhile
+			result := s.db.Table(col.Table).
+				Where("id = ? AND "+col.Column+" = ?", r.ID, r.Value).
+				UpdateColumn(col.Column, encrypted)
+			if result.Error != nil {
+				return count, result.Error
+			}
+			count += int(result.RowsAffected)
+		}
+	}
+
+	if count > 0 {
+		logger.WithField("values", count).Info("Legacy ciphertexts re-wrapped")
+	}
+	return count, nil
 }
 
 // MarkEncryptionMigrated marks the encryption migration as complete
 func (s *MigrationService) MarkEncryptionMigrated() error {
+	return markEncryptionMigrated(s.db)
+}
+
+// EnsureMigrationTableExists creates the migration_flags table if it doesn't exist
+func (s *MigrationService) EnsureMigrationTableExists() error {
+	return s.db.AutoMigrate(&SystemConfig{})
+}
+
+func (s *MigrationService) isFlagSet(key string) (bool, error) {
+	var config SystemConfig
+	err := s.db.Where("key = ?", key).First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return config.Value == "true", nil
+}
+
+func setFlag(db *gorm.DB, key string) error {
+	config := SystemConfig{Key: key, Value: "true"}
+	return db.Where(SystemConfig{Key: key}).
+		Assign(SystemConfig{Value: "true"}).
+		FirstOrCreate(&config).Error
+}
+
+func markEncryptionMigrated(db *gorm.DB) error {
 	config := SystemConfig{
 		Key:                "encryption_migrated",
 		Value:              "true",
 		EncryptionMigrated: true,
 	}
-	return s.db.Save(&config).Error
+	return db.Where(SystemConfig{Key: config.Key}).
+		Assign(SystemConfig{Value: config.Value, EncryptionMigrated: true}).
+		FirstOrCreate(&config).Error
 }
 
-// EnsureMigrationTableExists creates the system_config table if it doesn't exist
-func (s *MigrationService) EnsureMigrationTableExists() error {
-	return s.db.AutoMigrate(&SystemConfig{})
+// ReencryptAll rewrites every encrypted value inside tx. transform returns the
+// new value and whether the row needs to be written; progress is called after
+// each value with the running and total counts. Values transform fails on,
+// such as ones no loaded key decrypts, are left as they are and returned in
+// skipped as table.column id=N.
+func ReencryptAll(tx *gorm.DB, transform func(string) (string, bool, error), progress func(done, total int)) (written int, skipped []string, err error) {
+	values := make(map[EncryptedColumn][]encryptedValue, len(EncryptedColumns))
+	total := 0
+	for _, col := range EncryptedColumns {
+		var rows []encryptedValue
+		err := tx.Table(col.Table).
+			Select("id, " + col.Column + " AS value").
+			Where(col.Column + " IS NOT NULL AND " + col.Column + " <> ''").
+			Scan(&rows).Error
+		if err != nil {
+			return 0, nil, err
+		}
+		values[col] = rows
+		total += len(rows)
+	}
+
+	done := 0
+	for _, col := range EncryptedColumns {
+		for _, r := range values[col] {
+			encrypted, changed, err := transform(r.Value)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s.%s id=%d", col.Table, col.Column, r.ID))
+				changed = false
+			}
+			if changed {
+				if err := tx.Table(col.Table).Where("id = ?", r.ID).UpdateColumn(col.Column, encrypted).Error; err != nil {
+					return written, skipped, err
+				}
+				written++
+			}
+
+			done++
+			if progress != nil {
+				progress(done, total)
+			}
+		}
+	}
+
+	return written, skipped, nil
+}
+
+type encryptedValue struct {
+	ID    uint
+	Value string
+}
+
+// findLegacyValues returns the rows of a column not yet in the versioned format
+func findLegacyValues(db *gorm.DB, col EncryptedColumn) ([]encryptedValue, error) {
+	var rows []encryptedValue
+	err := db.Table(col.Table).
+		Select("id, "+col.Column+" AS value").
+		Where(col.Column+" IS NOT NULL AND "+col.Column+" <> '' AND "+col.Column+" NOT LIKE ?", "v2:%").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// reencryptColumn decrypts every legacy value in a column and writes it back
+// encrypted, returning the number of rows rewritten
+func reencryptColumn(tx *gorm.DB, col EncryptedColumn, decrypt, encrypt func(string) (string, error)) (int, error) {
+	rows, err := findLegacyValues(tx, col)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, r := range rows {
+		plaintext, err := decrypt(r.Value)
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Table(col.Table).Where("id = ?", r.ID).UpdateColumn(col.Column, encrypted).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), nil
 }
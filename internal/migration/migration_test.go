@@ -0,0 +1,165 @@
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"account-manager/internal/utils"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB returns an in-memory database with a table for every
+// encrypted column
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a new database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	columns := make(map[string][]string)
+	var tables []string
+	for _, col := range EncryptedColumns {
+		if _, ok := columns[col.Table]; !ok {
+			tables = append(tables, col.Table)
+		}
+		columns[col.Table] = append(columns[col.Table], col.Column+" TEXT")
+	}
+	for _, table := range tables {
+		sql := "CREATE TABLE " + table + " (id INTEGER PRIMARY KEY"
+		for _, column := range columns[table] {
+			sql += ", " + column
+		}
+		if err := db.Exec(sql + ")").Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// rotateTo is the transform of a key rotation to newKey
+func rotateTo(newKeyID string, newKey []byte) func(string) (string, bool, error) {
+	return func(value string) (string, bool, error) {
+		if utils.CiphertextKeyID(value) == newKeyID {
+			return value, false, nil
+		}
+		plaintext, err := utils.Decrypt(value)
+		if err != nil {
+			return "", false, err
+		}
+		encrypted, err := utils.EncryptWithKey(newKeyID, newKey, plaintext)
+		return encrypted, true, err
+	}
+}
+
+func TestReencryptAll(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, utils.KeyLength), bytes.Repeat([]byte{2}, utils.KeyLength)
+	if err := utils.SetEncryptionKey("0a0a0a0a", oldKey); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(utils.ClearEncryptionKey)
+
+	db := openTestDB(t)
+	want := make(map[string]string)
+	for i, col := range EncryptedColumns {
+		plaintext := fmt.Sprintf("%s.%s secret", col.Table, col.Column)
+		encrypted, err := utils.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec("INSERT INTO "+col.Table+" (id, "+col.Column+") VALUES (?, ?)", 100+i, encrypted).Error; err != nil {
+			t.Fatal(err)
+		}
+		want[fmt.Sprintf("%s.%s id=%d", col.Table, col.Column, 100+i)] = plaintext
+	}
+	// A value written with a key that is no longer around, and an empty one
+	lost, _ := utils.EncryptWithKey("0c0c0c0c", bytes.Repeat([]byte{3}, utils.KeyLength), "lost")
+	db.Exec("INSERT INTO accounts (id, password, notes) VALUES (1, ?, '')", lost)
+
+	var progress []int
+	written, skipped, err := ReencryptAll(db, rotateTo("0b0b0b0b", newKey), func(done, total int) {
+		if total != len(EncryptedColumns)+1 {
+			t.Errorf("progress total = %d, want %d", total, len(EncryptedColumns)+1)
+		}
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(EncryptedColumns) {
+		t.Errorf("written = %d, want %d", written, len(EncryptedColumns))
+	}
+	if len(skipped) != 1 || skipped[0] != "accounts.password id=1" {
+		t.Errorf("skipped = %v, want [accounts.password id=1]", skipped)
+	}
+	if len(progress) != len(EncryptedColumns)+1 || progress[len(progress)-1] != len(EncryptedColumns)+1 {
+		t.Errorf("progress = %v", progress)
+	}
+
+	for i, col := range EncryptedColumns {
+		var value string
+		db.Table(col.Table).Select(col.Column).Where("id = ?", 100+i).Scan(&value)
+		if utils.CiphertextKeyID(value) != "0b0b0b0b" {
+			t.Errorf("%s.%s still uses key %q", col.Table, col.Column, utils.CiphertextKeyID(value))
+		}
+		plaintext, err := utils.DecryptWithKey(newKey, value)
+		key := fmt.Sprintf("%s.%s id=%d", col.Table, col.Column, 100+i)
+		if err != nil || plaintext != want[key] {
+			t.Errorf("%s = %q, %v, want %q", key, plaintext, err, want[key])
+		}
+	}
+	var kept string
+	db.Table("accounts").Select("password").Where("id = 1").Scan(&kept)
+	if kept != lost {
+		t.Error("a value that could not be decrypted was rewritten")
+	}
+
+	// Running it again finds nothing left to do
+	if err := utils.SetEncryptionKey("0b0b0b0b", newKey); err != nil {
+		t.Fatal(err)
+	}
+	written, skipped, err = ReencryptAll(db, rotateTo("0b0b0b0b", newKey), nil)
+	if err != nil || written != 0 || len(skipped) != 1 {
+		t.Errorf("second run: written = %d, skipped = %v, err = %v", written, skipped, err)
+	}
+}
+
+func TestReencryptAllRollsBackWithTransaction(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, utils.KeyLength)
+	if err := utils.SetEncryptionKey("0a0a0a0a", oldKey); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(utils.ClearEncryptionKey)
+
+	db := openTestDB(t)
+	encrypted, _ := utils.Encrypt("hunter2")
+	db.Exec("INSERT INTO accounts (id, password) VALUES (1, ?)", encrypted)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, _, err := ReencryptAll(tx, rotateTo("0b0b0b0b", bytes.Repeat([]byte{2}, utils.KeyLength)), nil); err != nil {
+			return err
+		}
+		return fmt.Errorf("interrupted")
+	})
+	if err == nil {
+		t.Fatal("the transaction was not rolled back")
+	}
+	var value string
+	db.Table("accounts").Select("password").Where("id = 1").Scan(&value)
+	if value != encrypted {
+		t.Error("values re-encrypted by an interrupted rotation were kept")
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type AccountType string
@@ -13,35 +15,130 @@ const (
 )
 
 type Account struct {
-	ID           uint        `json:"id" gorm:"primaryKey"`
-	Account      string      `json:"account" gorm:"uniqueIndex;not null"`
-	Password     string      `json:"password"`
-	AccountType  AccountType `json:"accountType" gorm:"type:varchar(20);not null;index:idx_type_sold"`
-	IsSold       bool        `json:"isSold" gorm:"default:false;index:idx_type_sold"`
-	SoldAt       *time.Time  `json:"soldAt"`
-	ExpireAt     *time.Time  `json:"expireAt" gorm:"index:idx_expire"`
-	ReminderSent bool        `json:"reminderSent" gorm:"default:false"`
-	Notes        string      `json:"notes"`
-	CreatedAt    time.Time   `json:"createdAt" gorm:"index:idx_created_desc"`
-	UpdatedAt    time.Time   `json:"updatedAt"`
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	Account       string      `json:"account" gorm:"uniqueIndex;not null"`
+	Password      string      `json:"password"`
+	AccountType   AccountType `json:"accountType" gorm:"type:varchar(20);not null;index:idx_type_sold"`
+	IsSold        bool        `json:"isSold" gorm:"default:false;index:idx_type_sold"` // Follows Status, see AccountStatusConfig.Sold
+	SoldAt        *time.Time  `json:"soldAt"`
+	Status        string      `json:"status" gorm:"type:varchar(20);index"`
+	StatusAt      *time.Time  `json:"statusAt"` // When Status last changed
+	ExpireAt      *time.Time  `json:"expireAt" gorm:"index:idx_expire"`
+	ReminderSent  bool        `json:"reminderSent" gorm:"default:false"`
+	Notes         string      `json:"notes"` // Encrypted like Password
+	TOTP          string      `json:"-"`     // Encrypted otpauth:// URI, see GetTOTPCode
+	HasTOTP       bool        `json:"hasTotp" gorm:"default:false"`
+	Tags          []Tag       `json:"tags" gorm:"many2many:account_tags"`
+	CustomerID    *uint       `json:"customerId" gorm:"index"`    // Customer holding the account, cleared when it is no longer sold
+	ReservationID *uint       `json:"reservationId" gorm:"index"` // Last reservation that allocated the account, see Reservation
+
+	// SearchSnippet is the part of the account matching a search, as HTML
+	// with the matches in <mark>. Only set by searches.
+	SearchSnippet string `json:"searchSnippet,omitempty" gorm:"->;-:migration"`
+
+	// DecryptError is set when the account's secrets could not be decrypted.
+	// They are left blank and the rest of the account is still listed.
+	DecryptError bool `json:"decryptError,omitempty" gorm:"-"`
+
+	// CustomFields maps CustomField.Name to its value, see AccountFieldValue
+	CustomFields map[string]string `json:"customFields" gorm:"-"`
+
+	// Password health, computed while the vault is unlocked
+	PasswordScore       int        `json:"passwordScore"` // 0-4, see utils.EstimateStrength
+	PasswordBreached    bool       `json:"passwordBreached" gorm:"default:false"`
+	PasswordReused      bool       `json:"passwordReused" gorm:"default:false"`
+	PasswordFingerprint string     `json:"-" gorm:"type:varchar(64);index"` // Keyed hash, see utils.PasswordFingerprint
+	PasswordCheckedAt   *time.Time `json:"passwordCheckedAt"`
+
+	CreatedAt time.Time `json:"createdAt" gorm:"index:idx_created_desc"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Accounts in the trash have DeletedAt set and are hidden from every
+	// query except the trash ones, see AccountRepository.FindTrash
+	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	DeletedBy string         `json:"deletedBy" gorm:"type:varchar(255)"`
 }
 
+// Password health filters for AccountFilter.Security
+const (
+	SecurityFilterWeak     = "weak"
+	SecurityFilterReused   = "reused"
+	SecurityFilterBreached = "breached"
+)
+
+// Accounts scoring at or below this are reported as weak
+const WeakPasswordScore = 1
+
 type AccountFilter struct {
 	AccountType string `json:"accountType"`
 	IsSold      *bool  `json:"isSold"`
-	Search      string `json:"search"`
+	Status      string `json:"status"`
+	Search      string `json:"search"`   // Plain text, or a query such as type:PLUS sold:false
+	Security    string `json:"security"` // weak, reused or breached
 	Page        int    `json:"page"`
 	PageSize    int    `json:"pageSize"`
+
+	// Tag ids; an account matches when it has any of TagsAny, all of TagsAll
+	// and none of TagsNone
+	TagsAny  []uint `json:"tagsAny"`
+	TagsAll  []uint `json:"tagsAll"`
+	TagsNone []uint `json:"tagsNone"`
+
+	// Every filter must match
+	Fields []FieldFilter `json:"fields"`
+
+	CustomerID uint `json:"customerId"` // Accounts held by a customer
+
+	// SecretMatchIDs holds the accounts whose encrypted fields match Search,
+	// resolved from the in-memory index because SQL cannot search ciphertext
+	SecretMatchIDs []uint `json:"-"`
+
+	// Terms holds the conditions of Search when it uses the query syntax, in
+	// which case Search itself is cleared
+	Terms []QueryTerm `json:"-"`
 }
 
 type AccountStats struct {
-	Total           int64 `json:"total"`
-	PlusCount       int64 `json:"plusCount"`
-	BusinessCount   int64 `json:"businessCount"`
-	FreeCount       int64 `json:"freeCount"`
-	SoldCount       int64 `json:"soldCount"`
-	ExpiredCount    int64 `json:"expiredCount"`
-	ExpiringIn7Days int64 `json:"expiringIn7Days"`
+	Total           int64                `json:"total"`
+	SoldCount       int64                `json:"soldCount"`
+	ExpiredCount    int64                `json:"expiredCount"`
+	ExpiringIn7Days int64                `json:"expiringIn7Days"`
+	ByType          []AccountTypeStats   `json:"byType"`   // Every configured type, in display order
+	ByStatus        []AccountStatusStats `json:"byStatus"` // Every configured status, in workflow order
+	ByTag           []TagStats           `json:"byTag"`    // Every tag, by name
+	Revenue         []RevenueStats       `json:"revenue"`  // Net sales of all time by account type and currency
+
+	// Deprecated: use ByType. Kept for the built-in types until the
+	// dashboard reads ByType.
+	PlusCount     int64 `json:"plusCount"`
+	BusinessCount int64 `json:"businessCount"`
+	FreeCount     int64 `json:"freeCount"`
+}
+
+// PasswordHealthStats summarizes password health across all accounts
+type PasswordHealthStats struct {
+	Checked       int64 `json:"checked"`
+	Weak          int64 `json:"weak"`
+	Reused        int64 `json:"reused"`
+	Breached      int64 `json:"breached"`
+	BreachEntries int64 `json:"breachEntries"` // Hashes in the imported breach database
+}
+
+// TOTPCode is the current one-time password of an account
+type TOTPCode struct {
+	Code      string `json:"code"`
+	Remaining int    `json:"remaining"` // Seconds until the code changes
+	Period    int    `json:"period"`
+	Issuer    string `json:"issuer"`
+}
+
+// PasswordCheck is the strength and breach status of a candidate password
+type PasswordCheck struct {
+	Score       int      `json:"score"`
+	EntropyBits float64  `json:"entropyBits"`
+	Feedback    []string `json:"feedback"`
+	Breached    bool     `json:"breached"`
+	BreachCount int      `json:"breachCount"` // Times seen in the imported breach list
 }
 
 type PaginatedAccounts struct {
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Sources of an AccountChangeSet
+const (
+	ChangeSourceUpdate  = "update"  // UpdateAccount
+	ChangeSourceStatus  = "status"  // ChangeStatus, MarkAsSold, MarkAsUnsold
+	ChangeSourceRevert  = "revert"  // RevertChangeSet
+	ChangeSourceBulk    = "bulk"    // Bulk operations
+	ChangeSourceRenewal = "renewal" // ExtendExpiry
+	ChangeSourceRestore = "restore" // PasswordHistoryService.Restore
+	ChangeSourceTOTP    = "totp"    // SetTOTP, RemoveTOTP
+)
+
+// Names of the account fields tracked by AccountFieldChange. Custom fields
+// are named ChangeFieldCustomPrefix + CustomField.Name.
+const (
+	ChangeFieldAccount      = "account"
+	ChangeFieldAccountType  = "accountType"
+	ChangeFieldStatus       = "status"
+	ChangeFieldExpireAt     = "expireAt" // RFC 3339, empty when the account never expires
+	ChangeFieldPassword     = "password"
+	ChangeFieldNotes        = "notes"
+	ChangeFieldTOTP         = "totp"
+	ChangeFieldCustomPrefix = "field:"
+)
+
+// AccountChangeSet groups the field changes made to an account by one update
+type AccountChangeSet struct {
+	ID        uint                 `json:"id" gorm:"primaryKey"`
+	AccountID uint                 `json:"accountId" gorm:"not null;index:idx_change_account"`
+	ChangedBy string               `json:"changedBy" gorm:"type:varchar(255)"`
+	ChangedAt time.Time            `json:"changedAt" gorm:"index:idx_change_account"`
+	Source    string               `json:"source" gorm:"type:varchar(20)"`
+	RevertOf  *uint                `json:"revertOf"` // Change set undone by this one
+	Changes   []AccountFieldChange `json:"changes" gorm:"foreignKey:ChangeSetID"`
+}
+
+// AccountFieldChange is the value of one field before and after an update.
+// Secret fields only show that they changed: their values are kept encrypted
+// in OldSecret and NewSecret, which are never returned, so the change can be
+// reverted.
+type AccountFieldChange struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	ChangeSetID uint   `json:"changeSetId" gorm:"not null;index"`
+	Field       string `json:"field" gorm:"type:varchar(100);not null"`
+	OldValue    string `json:"oldValue" gorm:"type:text"`
+	NewValue    string `json:"newValue" gorm:"type:text"`
+	Secret      bool   `json:"secret"`
+	OldSecret   string `json:"-" gorm:"type:text"`
+	NewSecret   string `json:"-" gorm:"type:text"`
+}
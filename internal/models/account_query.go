@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Fields of a QueryTerm, used as keys in the search syntax
+const (
+	QueryFieldText    = "text" // A bare word, matched like a plain search
+	QueryFieldAccount = "account"
+	QueryFieldNotes   = "notes"
+	QueryFieldType    = "type"
+	QueryFieldStatus  = "status"
+	QueryFieldSold    = "sold"
+	QueryFieldExpired = "expired"
+	QueryFieldTag     = "tag"
+	QueryFieldExpires = "expires"
+	QueryFieldCreated = "created"
+)
+
+// QueryTerm is one condition of a structured search, see AccountFilter.Search.
+// Parsing resolves every value, so the repository only maps terms to fixed
+// columns and bound parameters.
+type QueryTerm struct {
+	Field  string
+	Negate bool   // Written with a leading "-"
+	Text   string // text, account, notes, type, status and tag
+	Bool   bool   // sold and expired
+
+	// Time range of expires and created; From is inclusive, To exclusive and
+	// either may be nil. Never selects accounts that do not expire.
+	From  *time.Time
+	To    *time.Time
+	Never bool
+
+	// Accounts whose encrypted fields match Text, for text and notes
+	MatchIDs []uint
+}
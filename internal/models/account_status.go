@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Built-in account statuses
+const (
+	AccountStatusInStock  = "in_stock"
+	AccountStatusReserved = "reserved"
+	AccountStatusSold     = "sold"
+	AccountStatusReturned = "returned"
+	AccountStatusBanned   = "banned"
+	AccountStatusRetired  = "retired"
+)
+
+// AccountStatusConfig is a step in the account workflow. Accounts refer to it by Name.
+type AccountStatusConfig struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(20);uniqueIndex;not null"`
+	Label     string    `json:"label" gorm:"type:varchar(50)"`
+	Color     string    `json:"color" gorm:"type:varchar(20)"`
+	Initial   bool      `json:"initial"` // Status of new accounts, exactly one status has it
+	Sold      bool      `json:"sold"`    // Accounts in this status count as sold, see Account.IsSold
+	SortOrder int       `json:"sortOrder"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AccountStatusTransition allows accounts to move from one status to another
+type AccountStatusTransition struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	FromStatus string `json:"fromStatus" gorm:"type:varchar(20);not null;uniqueIndex:idx_transition"`
+	ToStatus   string `json:"toStatus" gorm:"type:varchar(20);not null;uniqueIndex:idx_transition"`
+}
+
+// AccountStatusChange records one transition of an account
+type AccountStatusChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AccountID  uint      `json:"accountId" gorm:"index;not null"`
+	FromStatus string    `json:"fromStatus" gorm:"type:varchar(20)"`
+	ToStatus   string    `json:"toStatus" gorm:"type:varchar(20);not null"`
+	ChangedBy  string    `json:"changedBy" gorm:"type:varchar(255)"`
+	ChangedAt  time.Time `json:"changedAt" gorm:"index"`
+	Note       string    `json:"note" gorm:"type:text"`
+}
+
+// DefaultAccountStatuses are created on first start
+var DefaultAccountStatuses = []AccountStatusConfig{
+	{Name: AccountStatusInStock, Label: "库存", Color: "#f0a020", Initial: true, SortOrder: 1},
+	{Name: AccountStatusReserved, Label: "已预留", Color: "#2080f0", SortOrder: 2},
+	{Name: AccountStatusSold, Label: "已售出", Color: "#18a058", Sold: true, SortOrder: 3},
+	{Name: AccountStatusReturned, Label: "已退回", Color: "#8a2be2", SortOrder: 4},
+	{Name: AccountStatusBanned, Label: "已封禁", Color: "#d03050", SortOrder: 5},
+	{Name: AccountStatusRetired, Label: "已停用", Color: "#909399", SortOrder: 6},
+}
+
+// DefaultAccountStatusTransitions is the workflow created on first start
+var DefaultAccountStatusTransitions = []AccountStatusTransition{
+	{FromStatus: AccountStatusInStock, ToStatus: AccountStatusReserved},
+	{FromStatus: AccountStatusInStock, ToStatus: AccountStatusSold},
+	{FromStatus: AccountStatusInStock, ToStatus: AccountStatusBanned},
+	{FromStatus: AccountStatusInStock, ToStatus: AccountStatusRetired},
+	{FromStatus: AccountStatusReserved, ToStatus: AccountStatusInStock},
+	{FromStatus: AccountStatusReserved, ToStatus: AccountStatusSold},
+	{FromStatus: AccountStatusReserved, ToStatus: AccountStatusBanned},
+	{FromStatus: AccountStatusSold, ToStatus: AccountStatusInStock}, // Undo a sale recorded by mistake
+	{FromStatus: AccountStatusSold, ToStatus: AccountStatusReturned},
+	{FromStatus: AccountStatusSold, ToStatus: AccountStatusBanned},
+	{FromStatus: AccountStatusReturned, ToStatus: AccountStatusInStock},
+	{FromStatus: AccountStatusReturned, ToStatus: AccountStatusBanned},
+	{FromStatus: AccountStatusReturned, ToStatus: AccountStatusRetired},
+	{FromStatus: AccountStatusBanned, ToStatus: AccountStatusInStock},
+	{FromStatus: AccountStatusBanned, ToStatus: AccountStatusRetired},
+}
+
+// AccountStatusStats counts the accounts in one status
+type AccountStatusStats struct {
+	Status string `json:"status"`
+	Label  string `json:"label"`
+	Color  string `json:"color"`
+	Total  int64  `json:"total"`
+}
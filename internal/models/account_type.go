@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// AccountTypeConfig describes an account type. Accounts refer to it by Name.
+type AccountTypeConfig struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	Name         AccountType `json:"name" gorm:"type:varchar(20);uniqueIndex;not null"`
+	Color        string      `json:"color" gorm:"type:varchar(20)"`
+	ValidityDays int         `json:"validityDays"`                                   // 0 uses SystemConfig.DefaultValidityDays
+	Expires      bool        `json:"expires"`                                        // Accounts of types that never expire get no expiry date
+	RenewalPrice *Cents      `json:"renewalPrice" gorm:"column:renewal_price_cents"` // Optional price of one renewal period
+	SortOrder    int         `json:"sortOrder"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	UpdatedAt    time.Time   `json:"updatedAt"`
+}
+
+// DefaultAccountTypes are created on first start, matching the types that
+// used to be hardcoded
+var DefaultAccountTypes = []AccountTypeConfig{
+	{Name: AccountTypePLUS, Color: "#2080f0", Expires: true, SortOrder: 1},
+	{Name: AccountTypeBUSINESS, Color: "#18a058", Expires: true, SortOrder: 2},
+	{Name: AccountTypeFREE, Color: "#909399", Expires: false, SortOrder: 3},
+}
+
+// AccountTypeStats counts the accounts of one type
+type AccountTypeStats struct {
+	Type    AccountType `json:"type"`
+	Color   string      `json:"color"`
+	Total   int64       `json:"total"`
+	Sold    int64       `json:"sold"`
+	Expired int64       `json:"expired"`
+}
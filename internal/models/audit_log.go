@@ -12,8 +12,10 @@ type AuditLog struct {
 	ResourceID   uint      `json:"resourceId" gorm:"index:idx_resource"`
 	IPAddress    string    `json:"ipAddress" gorm:"type:varchar(50)"`
 	Details      string    `json:"details" gorm:"type:text"` // JSON string with additional details
-	Success      bool      `json:"success" gorm:"default:true"`
+	Success      bool      `json:"success"`
 	ErrorMessage string    `json:"errorMessage" gorm:"type:text"`
+	PrevHash     string    `json:"prevHash" gorm:"type:char(64)"` // Hash of the previous entry, empty for the first
+	Hash         string    `json:"hash" gorm:"type:char(64);index"` // SHA-256 over PrevHash and the fields above
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
@@ -35,3 +37,47 @@ type PaginatedAuditLogs struct {
 	PageSize   int        `json:"pageSize"`
 	TotalPages int        `json:"totalPages"`
 }
+
+// Kinds of AuditCheckpoint
+const (
+	CheckpointPeriodic = "checkpoint" // Signs the chain head every few entries
+	CheckpointAnchor   = "anchor"     // Stands in for entries removed by CleanupOldLogs
+)
+
+// AuditCheckpoint is a signed statement about the audit chain. A periodic
+// checkpoint signs the hash of entry LastLogID, so entries cannot be rewritten
+// or dropped from the end without the signing key. An anchor records a pruned
+// range: PrevHash is the hash before FirstLogID and Hash the hash of LastLogID,
+// which the first remaining entry must chain to.
+type AuditCheckpoint struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Kind       string    `json:"kind" gorm:"type:varchar(20);not null;index"`
+	FirstLogID uint      `json:"firstLogId"`
+	LastLogID  uint      `json:"lastLogId" gorm:"index"`
+	Entries    int64     `json:"entries"`
+	PrevHash   string    `json:"prevHash" gorm:"type:char(64)"`
+	Hash       string    `json:"hash" gorm:"type:char(64);not null"`
+	Signature  string    `json:"signature" gorm:"type:text;not null"` // Base64 Ed25519 signature
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AuditSigningKey is the Ed25519 key that signs checkpoints. The private key
+// is encrypted with the vault key, so checkpoints can only be forged or
+// verified by someone who can unlock the vault.
+type AuditSigningKey struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	PrivateKey string    `json:"-" gorm:"type:text;not null"`
+	PublicKey  string    `json:"publicKey" gorm:"type:varchar(64);not null"` // Base64
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AuditChainReport is the result of verifying the audit chain
+type AuditChainReport struct {
+	Valid              bool   `json:"valid"`
+	Entries            int    `json:"entries"`            // Entries checked
+	Checkpoints        int    `json:"checkpoints"`        // Periodic checkpoints checked
+	Anchors            int    `json:"anchors"`            // Pruned ranges accounted for
+	SignaturesVerified bool   `json:"signaturesVerified"` // False while the vault is locked
+	BrokenAt           uint   `json:"brokenAt"`           // Id of the first entry that fails, 0 if none
+	Reason             string `json:"reason"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// BreachedPassword is an entry of the locally imported breached-password list,
+// identified by the upper-case hex SHA-1 of the password
+type BreachedPassword struct {
+	Hash  string `json:"hash" gorm:"type:char(40);primaryKey"`
+	Count int    `json:"count"`
+}
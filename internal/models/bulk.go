@@ -0,0 +1,35 @@
+package models
+
+// Actions of a bulk account operation
+const (
+	BulkActionStatus       = "status"
+	BulkActionExtendExpiry = "extend_expiry"
+	BulkActionChangeType   = "change_type"
+	BulkActionDelete       = "delete"
+)
+
+// BulkSelection picks the accounts of a bulk operation: the accounts in IDs,
+// or every account matching Filter when IDs is empty. Pagination of Filter
+// is ignored.
+type BulkSelection struct {
+	IDs    []uint         `json:"ids"`
+	Filter *AccountFilter `json:"filter"`
+}
+
+// BulkItemResult is the outcome of a bulk operation for one account
+type BulkItemResult struct {
+	ID      uint   `json:"id"`
+	Account string `json:"account"`
+	Success bool   `json:"success"`
+	Changed bool   `json:"changed"` // False when the account already matched
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkResult reports a bulk operation. Accounts that failed their checks are
+// skipped; the others are written together in one transaction.
+type BulkResult struct {
+	Action    string           `json:"action"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
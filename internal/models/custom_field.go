@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Types of CustomField
+const (
+	FieldTypeText   = "text"
+	FieldTypeSecret = "secret" // Encrypted like Account.Password
+	FieldTypeDate   = "date"   // YYYY-MM-DD
+	FieldTypeNumber = "number"
+	FieldTypeEnum   = "enum" // One of CustomField.Options
+	FieldTypeURL    = "url"
+)
+
+// CustomField is an extra field carried by the accounts of one type
+type CustomField struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	AccountType AccountType `json:"accountType" gorm:"type:varchar(20);not null;uniqueIndex:idx_type_field"`
+	Name        string      `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_type_field"` // Key in Account.CustomFields
+	Label       string      `json:"label" gorm:"type:varchar(100)"`
+	Type        string      `json:"type" gorm:"type:varchar(20);not null"`
+	Required    bool        `json:"required"`
+	Options     []string    `json:"options" gorm:"serializer:json"` // Choices of an enum field
+	SortOrder   int         `json:"sortOrder"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// AccountFieldValue is the value of a custom field on one account. Secret
+// values are encrypted in SecretValue; all others are kept in Value so they
+// can be filtered and searched in SQL.
+type AccountFieldValue struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	AccountID   uint   `json:"accountId" gorm:"not null;uniqueIndex:idx_account_field"`
+	FieldID     uint   `json:"fieldId" gorm:"not null;uniqueIndex:idx_account_field;index"`
+	Value       string `json:"value" gorm:"type:text"`
+	SecretValue string `json:"-" gorm:"type:text"`
+}
+
+// Comparisons of FieldFilter.Op
+const (
+	FieldOpEquals   = "eq"
+	FieldOpContains = "contains"
+	FieldOpGreater  = "gt" // Numeric for number fields, by text otherwise, which orders dates
+	FieldOpLess     = "lt"
+)
+
+// FieldFilter matches accounts on the value of a custom field. Secret fields
+// cannot be filtered, only searched.
+type FieldFilter struct {
+	Field string `json:"field"` // CustomField.Name
+	Op    string `json:"op"`    // Defaults to eq
+	Value string `json:"value"`
+}
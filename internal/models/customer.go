@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Customer is a buyer holding accounts, see Account.CustomerID. Name, Email
+// and Handle are searchable; Notes is encrypted like Account.Notes.
+type Customer struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"type:varchar(100);not null;index"`
+	Email        string    `json:"email" gorm:"type:varchar(255);index"`
+	Handle       string    `json:"handle" gorm:"type:varchar(100)"` // Messaging handle, e.g. @name on Telegram
+	Notes        string    `json:"notes"`
+	AccountCount int64     `json:"accountCount" gorm:"-"` // Accounts held, filled by list queries
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type PaginatedCustomers struct {
+	Data       []Customer `json:"data"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"pageSize"`
+	TotalPages int        `json:"totalPages"`
+}
+
+// ExpiringAccount is an account listed in an expiry overview. It carries no
+// secret so overviews work while the vault is locked.
+type ExpiringAccount struct {
+	ID          uint        `json:"id"`
+	Account     string      `json:"account"`
+	AccountType AccountType `json:"accountType"`
+	Status      string      `json:"status"`
+	ExpireAt    time.Time   `json:"expireAt"`
+	Expired     bool        `json:"expired"`
+}
+
+// CustomerExpiry lists the accounts of a customer expiring in a range
+type CustomerExpiry struct {
+	CustomerID uint              `json:"customerId"`
+	Name       string            `json:"name"`
+	Email      string            `json:"email"`
+	Handle     string            `json:"handle"`
+	Accounts   []ExpiringAccount `json:"accounts"` // Soonest first
+}
@@ -33,8 +33,12 @@ type SystemConfig struct {
 	ReminderDaysBefore  int    `json:"reminderDaysBefore" gorm:"default:1"`
 	CopyFormat          string `json:"copyFormat" gorm:"default:'账号：{account}\n密码：{password}'"`
 	EmailFormat         string `json:"emailFormat" gorm:"default:'您的账号 {account} 将在 {expireAt} 过期，请及时处理。'"`
-	AccountTypes        string `json:"accountTypes" gorm:"type:text"`
-	AccountStatuses     string `json:"accountStatuses" gorm:"type:text"`
+	AccountTypes        string `json:"accountTypes" gorm:"type:text"` // Deprecated: see AccountTypeConfig, only read by migration.MigrateAccountTypes
+	AccountStatuses     string `json:"accountStatuses" gorm:"type:text"` // Deprecated: see AccountStatusConfig, only read by migration.MigrateAccountStatuses
+	AutoLockMinutes     int    `json:"autoLockMinutes" gorm:"default:15"` // Idle minutes before the vault locks, 0 disables
+	PasswordPolicies    string `json:"passwordPolicies" gorm:"type:text"` // JSON map of account type to default password policy name
+	TrashRetentionDays  int    `json:"trashRetentionDays" gorm:"default:30"` // Days before deleted accounts are purged, 0 keeps them
+	DefaultCurrency     string `json:"defaultCurrency" gorm:"type:varchar(3);default:'CNY'"` // Currency of sales entered without one
 	CreatedAt           time.Time `json:"createdAt"`
 	UpdatedAt           time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Cents is an amount of money in hundredths of the currency unit, so sums of
+// the ledger are exact. In JSON it is a decimal number with at most two
+// fractional digits, such as 12.5 for 1250.
+type Cents int64
+
+var centsPattern = regexp.MustCompile(`^(-?)([0-9]+)(?:\.([0-9]{1,2}))?$`)
+
+// ErrInvalidAmount is returned for amounts with more than two decimals or
+// too large to store
+var ErrInvalidAmount = errors.New("invalid amount")
+
+func (c Cents) MarshalJSON() ([]byte, error) {
+	sign, abs := "", uint64(c)
+	if c < 0 {
+		sign, abs = "-", uint64(-c)
+	}
+	return []byte(fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)), nil
+}
+
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	m := centsPattern.FindStringSubmatch(text)
+	if m == nil {
+		return ErrInvalidAmount
+	}
+	units, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil || units > math.MaxInt64/100-1 {
+		return ErrInvalidAmount
+	}
+	fraction := 0
+	if m[3] != "" {
+		fraction, _ = strconv.Atoi((m[3] + "0")[:2])
+	}
+	value := units*100 + int64(fraction)
+	if m[1] == "-" {
+		value = -value
+	}
+	*c = Cents(value)
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCentsMarshalJSON(t *testing.T) {
+	tests := []struct {
+		cents Cents
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1250, "12.50"},
+		{-1, "-0.01"},
+		{-1250, "-12.50"},
+		{9223372036854775807, "92233720368547758.07"},
+		{-9223372036854775808, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.cents)
+		if err != nil {
+			t.Errorf("Marshal(%d): %v", tt.cents, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%d) = %s, want %s", tt.cents, got, tt.want)
+		}
+	}
+}
+
+func TestCentsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Cents
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "12", want: 1200},
+		{input: "12.5", want: 1250},
+		{input: "12.50", want: 1250},
+		{input: "0.1", want: 10},
+		{input: "0.07", want: 7},
+		{input: "-3.99", want: -399},
+		{input: "19.99", want: 1999},
+		{input: "92233720368547756", want: 9223372036854775600},
+		{input: "1.234", wantErr: true},
+		{input: "1e2", wantErr: true},
+		{input: ".5", wantErr: true},
+		{input: "5.", wantErr: true},
+		{input: `"12.50"`, wantErr: true},
+		{input: "92233720368547758", wantErr: true},
+		{input: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Cents
+		err := got.UnmarshalJSON([]byte(tt.input))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %d, want an error", tt.input, got)
+			} else if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Unmarshal(%s) error = %v, want ErrInvalidAmount", tt.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCentsUnmarshalNullKeepsValue(t *testing.T) {
+	var sale struct {
+		Price *Cents `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(`{"price":null}`), &sale); err != nil || sale.Price != nil {
+		t.Errorf("Unmarshal(null) = %v, %v, want nil", sale.Price, err)
+	}
+	price := Cents(100)
+	if err := price.UnmarshalJSON([]byte("null")); err != nil || price != 100 {
+		t.Errorf("UnmarshalJSON(null) = %d, %v, want the value unchanged", price, err)
+	}
+}
+
+func TestCentsRoundTrip(t *testing.T) {
+	for _, cents := range []Cents{0, 1, 10, 99, 100, 1999, -1, -1050, 123456789} {
+		data, err := json.Marshal(cents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Cents
+		if err := json.Unmarshal(data, &got); err != nil || got != cents {
+			t.Errorf("Unmarshal(Marshal(%d)) = %d, %v", cents, got, err)
+		}
+	}
+
+	// The sale fields of the frontend go through the same encoding
+	in := SaleDetails{Price: 1999}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out SaleDetails
+	if err := json.Unmarshal(data, &out); err != nil || out.Price != in.Price {
+		t.Errorf("SaleDetails round trip = %+v, %v, want price %d", out, err, in.Price)
+	}
+}
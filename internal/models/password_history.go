@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps a previous password of an account. A row is written
+// whenever the password is replaced; ChangedBy and ChangedAt describe that
+// replacement. The password is encrypted like Account.Password and is only
+// returned through the reveal API.
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AccountID uint      `json:"accountId" gorm:"not null;index:idx_history_account"`
+	Password  string    `json:"-" gorm:"not null"`
+	ChangedBy string    `json:"changedBy" gorm:"type:varchar(255)"`
+	ChangedAt time.Time `json:"changedAt" gorm:"index:idx_history_account"`
+	Reason    string    `json:"reason" gorm:"type:varchar(20)"` // update, restore
+}
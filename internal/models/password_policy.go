@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Password generation modes
+const (
+	PasswordModeRandom        = "random"
+	PasswordModePassphrase    = "passphrase"
+	PasswordModePronounceable = "pronounceable"
+)
+
+// Name of the policy used when an account type has no default
+const DefaultPasswordPolicy = "default"
+
+// PasswordPolicy is a named set of password generator settings.
+// In passphrase mode Uppercase capitalizes each word and Digits appends a
+// number; in pronounceable mode they capitalize the first letter and end the
+// password with two digits.
+type PasswordPolicy struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Mode             string    `json:"mode" gorm:"type:varchar(20);not null;default:'random'"`
+	Length           int       `json:"length"`
+	Lowercase        bool      `json:"lowercase"`
+	Uppercase        bool      `json:"uppercase"`
+	Digits           bool      `json:"digits"`
+	Symbols          bool      `json:"symbols"`
+	ExcludeAmbiguous bool      `json:"excludeAmbiguous"`
+	WordCount        int       `json:"wordCount"`
+	Separator        string    `json:"separator" gorm:"type:varchar(5)"`
+	BuiltIn          bool      `json:"builtIn" gorm:"default:false"` // Seeded policies cannot be deleted
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// GeneratedPassword is a generated password with its estimated strength
+type GeneratedPassword struct {
+	Password    string  `json:"password"`
+	Policy      string  `json:"policy"`
+	EntropyBits float64 `json:"entropyBits"`
+}
+
+// DefaultPasswordPolicies are seeded on first run
+var DefaultPasswordPolicies = []PasswordPolicy{
+	{Name: DefaultPasswordPolicy, Mode: PasswordModeRandom, Length: 16, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, ExcludeAmbiguous: true, BuiltIn: true},
+	{Name: "strong", Mode: PasswordModeRandom, Length: 32, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, BuiltIn: true},
+	{Name: "alphanumeric", Mode: PasswordModeRandom, Length: 20, Lowercase: true, Uppercase: true, Digits: true, ExcludeAmbiguous: true, BuiltIn: true},
+	{Name: "pin", Mode: PasswordModeRandom, Length: 6, Digits: true, BuiltIn: true},
+	{Name: "passphrase", Mode: PasswordModePassphrase, WordCount: 5, Separator: "-", Uppercase: true, Digits: true, BuiltIn: true},
+	{Name: "pronounceable", Mode: PasswordModePronounceable, Length: 14, Uppercase: true, Digits: true, BuiltIn: true},
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Renewal records an extension of the expiry date of an account. AccountType
+// is the type at the time of the renewal so reports do not shift when the
+// account changes type later.
+type Renewal struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	AccountID   uint        `json:"accountId" gorm:"not null;index"`
+	AccountType AccountType `json:"accountType" gorm:"type:varchar(20)"`
+	OldExpireAt *time.Time  `json:"oldExpireAt"`
+	NewExpireAt time.Time   `json:"newExpireAt"`
+	PeriodDays  int         `json:"periodDays"`
+	Cost        *Cents      `json:"cost" gorm:"column:cost_cents"` // Defaults to the renewal price of the type
+	Note        string      `json:"note" gorm:"type:varchar(500)"`
+	RenewedBy   string      `json:"renewedBy" gorm:"type:varchar(255)"`
+	RenewedAt   time.Time   `json:"renewedAt" gorm:"index"`
+}
+
+// RenewalPeriodStats sums the renewals of one report period
+type RenewalPeriodStats struct {
+	Period string `json:"period"` // Formatted as described by ReportByDay, ReportByWeek and ReportByMonth
+	Count  int64  `json:"count"`
+	Days   int64  `json:"days"`
+	Cost   Cents  `json:"cost"`
+}
+
+// RenewalTypeStats sums the renewals of one account type
+type RenewalTypeStats struct {
+	Type  AccountType `json:"type"`
+	Count int64       `json:"count"`
+	Cost  Cents       `json:"cost"`
+}
+
+// RenewalReport summarizes the renewals made between From and To
+type RenewalReport struct {
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Granularity string               `json:"granularity"`
+	Count       int64                `json:"count"`
+	Cost        Cents                `json:"cost"`
+	ByPeriod    []RenewalPeriodStats `json:"byPeriod"` // Periods without renewals are left out
+	ByType      []RenewalTypeStats   `json:"byType"`
+}
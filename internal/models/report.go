@@ -0,0 +1,8 @@
+package models
+
+// Granularities of the reports grouped by period
+const (
+	ReportByDay   = "day"   // 2006-01-02
+	ReportByWeek  = "week"  // ISO week, 2006-W01
+	ReportByMonth = "month" // 2006-01
+)
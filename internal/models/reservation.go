@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Statuses of a Reservation
+const (
+	ReservationHeld      = "held"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Strategies picking the accounts of a Reservation
+const (
+	AllocateOldest   = "oldest"   // Created first
+	AllocateExpiring = "expiring" // Expiring soonest, accounts that never expire last
+	AllocateLongest  = "longest"  // Accounts that never expire first, then expiring latest
+)
+
+// Reservation holds unsold accounts of one type for an order until it is
+// confirmed, which sells them, or released. A held reservation that is not
+// confirmed before HeldUntil expires and frees its accounts.
+//
+// Accounts point to their reservation with Account.ReservationID. An account
+// is held while that reservation is held and not past HeldUntil; confirmed
+// accounts keep the link, released and expired ones lose it.
+type Reservation struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	AccountType AccountType       `json:"accountType" gorm:"type:varchar(20);not null"`
+	Quantity    int               `json:"quantity"`
+	Strategy    string            `json:"strategy" gorm:"type:varchar(20)"`
+	Reference   string            `json:"reference" gorm:"type:varchar(100)"` // Order the accounts are held for
+	Status      string            `json:"status" gorm:"type:varchar(20);not null;index"`
+	HeldBy      string            `json:"heldBy" gorm:"type:varchar(255)"`
+	HeldUntil   time.Time         `json:"heldUntil" gorm:"index"`
+	ResolvedBy  string            `json:"resolvedBy" gorm:"type:varchar(255)"`
+	ResolvedAt  *time.Time        `json:"resolvedAt"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	Accounts    []ReservedAccount `json:"accounts" gorm:"-"`
+}
+
+// ReservedAccount is an account of a reservation. It carries no secret so
+// reservations can be listed while the vault is locked.
+type ReservedAccount struct {
+	ID        uint       `json:"id"`
+	Account   string     `json:"account"`
+	Status    string     `json:"status"`
+	ExpireAt  *time.Time `json:"expireAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ReservationRequest asks for Quantity accounts of AccountType
+type ReservationRequest struct {
+	AccountType string `json:"accountType"`
+	Quantity    int    `json:"quantity"`
+	Strategy    string `json:"strategy"`    // Defaults to AllocateOldest
+	HoldMinutes int    `json:"holdMinutes"` // Defaults to 30
+	Reference   string `json:"reference"`
+}
+
+type PaginatedReservations struct {
+	Data       []Reservation `json:"data"`
+	Total      int64         `json:"total"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"pageSize"`
+	TotalPages int           `json:"totalPages"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// Kinds of a Sale
+const (
+	SaleKindSale     = "sale"
+	SaleKindReversal = "reversal"
+)
+
+// Suggested sale channels, any short name is accepted
+const (
+	SaleChannelShop   = "shop"
+	SaleChannelDirect = "direct"
+)
+
+// Sale is an entry of the sales ledger. A sale is written when an account
+// moves to a sold status; moving it back writes a reversal that refers to the
+// sale and carries the negated price, so the ledger is never rewritten and
+// its sum is the net revenue. Account and AccountType are kept as they were
+// at the time of the sale, and entries outlive the account when it is purged.
+type Sale struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	AccountID   uint        `json:"accountId" gorm:"not null;index"`
+	Account     string      `json:"account" gorm:"type:varchar(255)"`
+	AccountType AccountType `json:"accountType" gorm:"type:varchar(20)"`
+	Kind        string      `json:"kind" gorm:"type:varchar(10);not null"`
+	ReversalOf  *uint       `json:"reversalOf" gorm:"index"` // Sale undone by this reversal
+	CustomerID  *uint       `json:"customerId" gorm:"index"`
+	Buyer       string      `json:"buyer" gorm:"type:varchar(255)"`
+	Price       Cents       `json:"price" gorm:"column:price_cents;not null;default:0"`
+	Currency    string      `json:"currency" gorm:"type:varchar(3)"`
+	Channel     string      `json:"channel" gorm:"type:varchar(30)"`
+	OrderRef    string      `json:"orderRef" gorm:"type:varchar(100)"`
+	Note        string      `json:"note" gorm:"type:varchar(500)"`
+	SoldBy      string      `json:"soldBy" gorm:"type:varchar(255)"`
+	SoldAt      time.Time   `json:"soldAt" gorm:"index"`
+}
+
+// SaleDetails describes a sale as entered by the user. An empty Currency
+// uses SystemConfig.DefaultCurrency. When CustomerID is set the account is
+// linked to the customer and Buyer defaults to the customer name.
+type SaleDetails struct {
+	CustomerID *uint  `json:"customerId"`
+	Buyer      string `json:"buyer"`
+	Price      Cents  `json:"price"`
+	Currency   string `json:"currency"`
+	Channel    string `json:"channel"`
+	OrderRef   string `json:"orderRef"`
+	Note       string `json:"note"`
+}
+
+// SaleFilter selects ledger entries. Zero fields match everything.
+type SaleFilter struct {
+	AccountID   uint        `json:"accountId"`
+	AccountType AccountType `json:"accountType"`
+	Channel     string      `json:"channel"`
+	Buyer       string      `json:"buyer"` // Matches part of the buyer
+	From        *time.Time  `json:"from"`
+	To          *time.Time  `json:"to"` // Exclusive
+	Page        int         `json:"page"`
+	PageSize    int         `json:"pageSize"`
+}
+
+type PaginatedSales struct {
+	Data       []Sale `json:"data"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	TotalPages int    `json:"totalPages"`
+}
+
+// RevenueStats sums the ledger entries of one group in one currency.
+// Amounts in different currencies are never added up.
+type RevenueStats struct {
+	Key       string `json:"key"` // Period or account type, depending on the list
+	Currency  string `json:"currency"`
+	Sales     int64  `json:"sales"`
+	Reversals int64  `json:"reversals"`
+	Gross     Cents  `json:"gross"` // Sum of sale prices
+	Net       Cents  `json:"net"`   // Gross minus reversed sales
+}
+
+// RevenueReport summarizes the ledger entries made between From and To.
+// Reversals count in the period they were made.
+type RevenueReport struct {
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Granularity string         `json:"granularity"`
+	Totals      []RevenueStats `json:"totals"`   // Key is empty
+	ByPeriod    []RevenueStats `json:"byPeriod"` // Periods without entries are left out
+	ByType      []RevenueStats `json:"byType"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Tag groups accounts by source, region, batch or anything else
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Color     string    `json:"color" gorm:"type:varchar(20)"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AccountTag is the join table between accounts and tags
+type AccountTag struct {
+	AccountID uint      `gorm:"primaryKey"`
+	TagID     uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time // When the tag was added
+}
+
+// TagStats counts the accounts carrying one tag
+type TagStats struct {
+	TagID uint   `json:"tagId"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Total int64  `json:"total"`
+}
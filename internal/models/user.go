@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleViewer   Role = "viewer"
+)
+
+type Permission string
+
+const (
+	PermViewAccounts    Permission = "accounts.view"
+	PermEditAccounts    Permission = "accounts.edit"
+	PermRevealPasswords Permission = "passwords.reveal"
+	PermManageSettings  Permission = "settings.manage"
+	PermDeployServers   Permission = "servers.deploy"
+	PermViewAudit       Permission = "audit.view"
+	PermManageUsers     Permission = "users.manage"
+)
+
+// RolePermissions lists what each role may do
+var RolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermViewAccounts, PermEditAccounts, PermRevealPasswords, PermManageSettings,
+		PermDeployServers, PermViewAudit, PermManageUsers,
+	},
+	RoleOperator: {
+		PermViewAccounts, PermEditAccounts, PermRevealPasswords,
+	},
+	RoleViewer: {
+		PermViewAccounts,
+	},
+}
+
+// Has reports whether the role grants a permission
+func (r Role) Has(perm Permission) bool {
+	for _, p := range RolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// User is a local profile on a shared workstation
+type User struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Username     string     `json:"username" gorm:"type:varchar(64);uniqueIndex;not null"`
+	DisplayName  string     `json:"displayName" gorm:"type:varchar(100)"`
+	PasswordHash string     `json:"-" gorm:"not null"` // Argon2id, see utils.HashPassword
+	Role         Role       `json:"role" gorm:"type:varchar(20);not null"`
+	Disabled     bool       `json:"disabled" gorm:"default:false"`
+	LastLoginAt  *time.Time `json:"lastLoginAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// Session describes who is using the app
+type Session struct {
+	User         *User        `json:"user"`         // nil in single-user mode or when logged out
+	LoginEnabled bool         `json:"loginEnabled"` // False until the first user is created
+	Permissions  []Permission `json:"permissions"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// VaultConfig stores the per-install key derivation parameters.
+// The master password itself is never stored; Verifier holds a known value
+// encrypted with the derived key so a wrong password can be detected.
+type VaultConfig struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	KDF        string     `json:"kdf" gorm:"type:varchar(20);not null"` // argon2id
+	Salt       string     `json:"-" gorm:"type:varchar(64);not null"`   // Base64 encoded salt
+	KDFTime    uint32     `json:"-" gorm:"not null"`
+	KDFMemory  uint32     `json:"-" gorm:"not null"` // KiB
+	KDFThreads uint8      `json:"-" gorm:"not null"`
+	Verifier   string     `json:"-" gorm:"type:text;not null"`
+	KeyID      string     `json:"keyId" gorm:"type:varchar(16)"`    // Id embedded in every ciphertext
+	Provider   string     `json:"provider" gorm:"type:varchar(20)"` // Key provider the data key came from
+	RotatedAt  *time.Time `json:"rotatedAt"`
+
+	// Key rotation in progress. The new key is stored wrapped with the current
+	// key so an interrupted rotation can be resumed after the next unlock.
+	PendingKeyID    string `json:"pendingKeyId" gorm:"type:varchar(16)"`
+	PendingSalt     string `json:"-" gorm:"type:varchar(64)"`
+	PendingVerifier string `json:"-" gorm:"type:text"`
+	PendingKey      string `json:"-" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// KeyRotationProgress reports how many encrypted values have been rewritten
+type KeyRotationProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+)
+
+type AccountChangeRepository struct{}
+
+func NewAccountChangeRepository() *AccountChangeRepository {
+	return &AccountChangeRepository{}
+}
+
+// Create stores a change set together with its field changes
+func (r *AccountChangeRepository) Create(set *models.AccountChangeSet) error {
+	return database.GetDB().Create(set).Error
+}
+
+func (r *AccountChangeRepository) FindByID(id uint) (*models.AccountChangeSet, error) {
+	var set models.AccountChangeSet
+	err := database.GetDB().Preload("Changes").First(&set, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// FindByAccount returns the change sets of an account, newest first
+func (r *AccountChangeRepository) FindByAccount(accountID uint) ([]models.AccountChangeSet, error) {
+	var sets []models.AccountChangeSet
+	err := database.GetDB().Preload("Changes").
+		Where("account_id = ?", accountID).
+		Order("changed_at DESC, id DESC").
+		Find(&sets).Error
+	return sets, err
+}
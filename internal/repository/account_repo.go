@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"account-manager/internal/database"
@@ -19,47 +21,160 @@ func (r *AccountRepository) Create(account *models.Account) error {
 	return database.GetDB().Create(account).Error
 }
 
+// Update saves the account row. Tags are changed through TagRepository.
 func (r *AccountRepository) Update(account *models.Account) error {
-	return database.GetDB().Save(account).Error
+	return database.GetDB().Omit("Tags").Save(account).Error
 }
 
-func (r *AccountRepository) Delete(id uint) error {
-	return database.GetDB().Delete(&models.Account{}, id).Error
+// UpdateWithChanges saves an account together with the password it replaced
+// and the change set recording the update, in one transaction. history and
+// set may be nil.
+func (r *AccountRepository) UpdateWithChanges(account *models.Account, history *models.PasswordHistory, set *models.AccountChangeSet) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if history != nil {
+			if err := tx.Create(history).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Tags").Save(account).Error; err != nil {
+			return err
+		}
+		if set != nil {
+			return tx.Create(set).Error
+		}
+		return nil
+	})
 }
 
-func (r *AccountRepository) FindByID(id uint) (*models.Account, error) {
+// Delete moves an account to the trash. Its tags and custom field values
+// are kept so it can be restored.
+func (r *AccountRepository) Delete(id uint, deletedBy string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Account{}).Where("id = ?", id).UpdateColumn("deleted_by", deletedBy).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Account{}, id).Error
+	})
+}
+
+// Restore takes an account out of the trash
+func (r *AccountRepository) Restore(id uint) error {
+	return database.GetDB().Unscoped().Model(&models.Account{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumns(map[string]interface{}{"deleted_at": nil, "deleted_by": ""}).Error
+}
+
+// Purge removes an account in the trash for good, with its tags, custom
+// field values, password history and status, change and renewal history
+func (r *AccountRepository) Purge(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("change_set_id IN (?)",
+			tx.Model(&models.AccountChangeSet{}).Select("id").Where("account_id = ?", id),
+		).Delete(&models.AccountFieldChange{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.AccountTag{},
+			&models.AccountFieldValue{},
+			&models.PasswordHistory{},
+			&models.AccountStatusChange{},
+			&models.AccountChangeSet{},
+			&models.Renewal{},
+		} {
+			if err := tx.Where("account_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Account{}, id).Error
+	})
+}
+
+// FindDeletedByID returns an account in the trash
+func (r *AccountRepository) FindDeletedByID(id uint) (*models.Account, error) {
 	var account models.Account
-	err := database.GetDB().First(&account, id).Error
+	err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL").First(&account, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-func (r *AccountRepository) FindByAccount(accountName string) (*models.Account, error) {
+// FindDeletedByAccount returns the account in the trash with the given name
+func (r *AccountRepository) FindDeletedByAccount(accountName string) (*models.Account, error) {
 	var account models.Account
-	err := database.GetDB().Where("account = ?", accountName).First(&account).Error
+	err := database.GetDB().Unscoped().Where("account = ? AND deleted_at IS NOT NULL", accountName).First(&account).Error
 	if err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-func (r *AccountRepository) FindAll(filter models.AccountFilter) (*models.PaginatedAccounts, error) {
-	db := database.GetDB().Model(&models.Account{})
+// FindTrash returns the accounts in the trash, most recently deleted first
+func (r *AccountRepository) FindTrash(page, pageSize int) (*models.PaginatedAccounts, error) {
+	db := database.GetDB().Unscoped().Model(&models.Account{}).Where("deleted_at IS NOT NULL")
 
-	// Apply filters
-	if filter.AccountType != "" {
-		db = db.Where("account_type = ?", filter.AccountType)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
 	}
-	if filter.IsSold != nil {
-		db = db.Where("is_sold = ?", *filter.IsSold)
+
+	if page <= 0 {
+		page = 1
 	}
-	if filter.Search != "" {
-		search := "%" + filter.Search + "%"
-		db = db.Where("account LIKE ? OR notes LIKE ?", search, search)
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	var accounts []models.Account
+	err := db.Preload("Tags").Order("deleted_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize > 0 {
+		totalPages++
 	}
 
+	return &models.PaginatedAccounts{
+		Data:       accounts,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// FindTrashedBefore returns the ids of accounts deleted before a date
+func (r *AccountRepository) FindTrashedBefore(date time.Time) ([]uint, error) {
+	var ids []uint
+	err := database.GetDB().Unscoped().Model(&models.Account{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", date).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *AccountRepository) FindByID(id uint) (*models.Account, error) {
+	var account models.Account
+	err := database.GetDB().Preload("Tags").First(&account, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *AccountRepository) FindByAccount(accountName string) (*models.Account, error) {
+	var account models.Account
+	err := database.GetDB().Where("account = ?", accountName).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *AccountRepository) FindAll(filter models.AccountFilter) (*models.PaginatedAccounts, error) {
+	db := filterQuery(filter)
+
 	// Count total
 	var total int64
 	db.Count(&total)
@@ -72,9 +187,20 @@ func (r *AccountRepository) FindAll(filter models.AccountFilter) (*models.Pagina
 		filter.PageSize = 20
 	}
 
+	// Rank full-text matches first, best first, with a snippet of the match
+	query := db.Preload("Tags")
+	if match, ok := rankQuery(filter); ok {
+		query = query.Select("accounts.*, fts.snippet AS search_snippet").
+			Joins(`LEFT JOIN (
+				SELECT rowid, bm25(accounts_fts) AS score, snippet(accounts_fts, -1, ?, ?, '…', 32) AS snippet
+				FROM accounts_fts WHERE accounts_fts MATCH ?
+			) AS fts ON fts.rowid = accounts.id`, database.SnippetOpen, database.SnippetClose, match).
+			Order("fts.score IS NULL, fts.score")
+	}
+
 	offset := (filter.Page - 1) * filter.PageSize
 	var accounts []models.Account
-	err := db.Order("created_at DESC").Offset(offset).Limit(filter.PageSize).Find(&accounts).Error
+	err := query.Order("created_at DESC").Offset(offset).Limit(filter.PageSize).Find(&accounts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -93,60 +219,440 @@ func (r *AccountRepository) FindAll(filter models.AccountFilter) (*models.Pagina
 	}, nil
 }
 
+// filterQuery selects the accounts matching every condition of filter,
+// ignoring pagination
+func filterQuery(filter models.AccountFilter) *gorm.DB {
+	db := database.GetDB().Model(&models.Account{})
+
+	if filter.AccountType != "" {
+		db = db.Where("account_type = ?", filter.AccountType)
+	}
+	if filter.IsSold != nil {
+		db = db.Where("is_sold = ?", *filter.IsSold)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.Search != "" {
+		sql, args := searchCondition(filter.Search, filter.SecretMatchIDs)
+		db = db.Where(sql, args...)
+	}
+	for _, term := range filter.Terms {
+		sql, args := termCondition(term)
+		if term.Negate {
+			sql = "NOT (" + sql + ")"
+		}
+		db = db.Where(sql, args...)
+	}
+	for _, f := range filter.Fields {
+		db = db.Where("id IN (?)", fieldFilterQuery(f))
+	}
+	if filter.CustomerID != 0 {
+		db = db.Where("customer_id = ?", filter.CustomerID)
+	}
+	switch filter.Security {
+	case models.SecurityFilterWeak:
+		db = db.Where("password_checked_at IS NOT NULL AND password_score <= ?", models.WeakPasswordScore)
+	case models.SecurityFilterReused:
+		db = db.Where("password_reused = ?", true)
+	case models.SecurityFilterBreached:
+		db = db.Where("password_breached = ?", true)
+	}
+	if len(filter.TagsAny) > 0 {
+		db = db.Where("id IN (SELECT account_id FROM account_tags WHERE tag_id IN ?)", filter.TagsAny)
+	}
+	if len(filter.TagsAll) > 0 {
+		tagIDs := uniqueIDs(filter.TagsAll)
+		db = db.Where(`id IN (
+			SELECT account_id FROM account_tags WHERE tag_id IN ?
+			GROUP BY account_id HAVING COUNT(DISTINCT tag_id) = ?
+		)`, tagIDs, len(tagIDs))
+	}
+	if len(filter.TagsNone) > 0 {
+		db = db.Where("id NOT IN (SELECT account_id FROM account_tags WHERE tag_id IN ?)", filter.TagsNone)
+	}
+
+	return db
+}
+
+// searchCondition matches search in the account name, a tag name or a custom
+// field value, through the full-text index when it can be used. Notes and
+// secret custom field values are encrypted and never reach SQL: secretIDs
+// holds the accounts whose decrypted values match, from the in-memory index.
+func searchCondition(search string, secretIDs []uint) (string, []interface{}) {
+	like := "%" + search + "%"
+	sql := `account LIKE ? OR id IN (SELECT account_tags.account_id FROM account_tags
+		JOIN tags ON tags.id = account_tags.tag_id WHERE tags.name LIKE ?)`
+	args := []interface{}{like, like}
+	if match, ok := database.SearchIndexQuery(search); ok {
+		sql = "id IN (SELECT rowid FROM accounts_fts WHERE accounts_fts MATCH ?)"
+		args = []interface{}{match}
+	}
+
+	sql += " OR id IN (SELECT account_id FROM account_field_values WHERE value LIKE ?)"
+	args = append(args, like)
+	if len(secretIDs) > 0 {
+		sql += " OR " + idSetCondition
+		args = append(args, idSet(secretIDs))
+	}
+	return sql, args
+}
+
+// idSetCondition matches ids passed as one JSON array parameter, see idSet.
+// Binding each id separately would exceed SQLite's limit on host parameters
+// once enough accounts match.
+const (
+	idSetCondition        = "id IN (SELECT value FROM json_each(?))"
+	accountIDSetCondition = "account_id IN (SELECT value FROM json_each(?))"
+)
+
+// idSet encodes ids as a JSON array for idSetCondition
+func idSet(ids []uint) string {
+	encoded, _ := json.Marshal(ids)
+	return string(encoded)
+}
+
+// rankQuery returns the full-text query ranking the results of a search,
+// false when the search does not use the index
+func rankQuery(filter models.AccountFilter) (string, bool) {
+	texts := []string{filter.Search}
+	for _, term := range filter.Terms {
+		if term.Field == models.QueryFieldText && !term.Negate {
+			texts = append(texts, term.Text)
+		}
+	}
+
+	var phrases []string
+	for _, text := range texts {
+		if match, ok := database.SearchIndexQuery(text); ok {
+			phrases = append(phrases, match)
+		}
+	}
+	return strings.Join(phrases, " OR "), len(phrases) > 0
+}
+
+// termCondition returns the condition of a parsed search term. Columns are
+// fixed by the field; values are always bound.
+func termCondition(term models.QueryTerm) (string, []interface{}) {
+	switch term.Field {
+	case models.QueryFieldAccount:
+		if match, ok := database.SearchIndexQuery(term.Text); ok {
+			return "id IN (SELECT rowid FROM accounts_fts WHERE accounts_fts MATCH ?)", []interface{}{"account : " + match}
+		}
+		return "account LIKE ?", []interface{}{"%" + term.Text + "%"}
+	case models.QueryFieldNotes:
+		if len(term.MatchIDs) == 0 {
+			return "1 = 0", nil
+		}
+		return idSetCondition, []interface{}{idSet(term.MatchIDs)}
+	case models.QueryFieldType:
+		return "account_type = ? COLLATE NOCASE", []interface{}{term.Text}
+	case models.QueryFieldStatus:
+		return "status = ? COLLATE NOCASE", []interface{}{term.Text}
+	case models.QueryFieldSold:
+		return "is_sold = ?", []interface{}{term.Bool}
+	case models.QueryFieldExpired:
+		if term.Bool {
+			return "expire_at IS NOT NULL AND expire_at < ?", []interface{}{time.Now()}
+		}
+		return "expire_at IS NULL OR expire_at >= ?", []interface{}{time.Now()}
+	case models.QueryFieldTag:
+		return `id IN (SELECT account_tags.account_id FROM account_tags
+			JOIN tags ON tags.id = account_tags.tag_id WHERE tags.name = ? COLLATE NOCASE)`, []interface{}{term.Text}
+	case models.QueryFieldExpires:
+		if term.Never {
+			return "expire_at IS NULL", nil
+		}
+		return rangeCondition("expire_at", "expire_at IS NOT NULL", term)
+	case models.QueryFieldCreated:
+		return rangeCondition("created_at", "1 = 1", term)
+	default:
+		return searchCondition(term.Text, term.MatchIDs)
+	}
+}
+
+// rangeCondition limits a time column to the range of term
+func rangeCondition(column, base string, term models.QueryTerm) (string, []interface{}) {
+	sql := base
+	var args []interface{}
+	if term.From != nil {
+		sql += " AND " + column + " >= ?"
+		args = append(args, *term.From)
+	}
+	if term.To != nil {
+		sql += " AND " + column + " < ?"
+		args = append(args, *term.To)
+	}
+	return sql, args
+}
+
+// FindIDs returns the ids of every account matching filter, ignoring
+// pagination, newest first
+func (r *AccountRepository) FindIDs(filter models.AccountFilter) ([]uint, error) {
+	var ids []uint
+	err := filterQuery(filter).Order("created_at DESC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindByIDs returns the accounts with the given ids, in no particular order
+func (r *AccountRepository) FindByIDs(ids []uint) ([]models.Account, error) {
+	var accounts []models.Account
+	if len(ids) == 0 {
+		return accounts, nil
+	}
+	err := database.GetDB().Where(idSetCondition, idSet(ids)).Find(&accounts).Error
+	return accounts, err
+}
+
+// fieldFilterQuery selects the accounts whose custom field value matches f
+func fieldFilterQuery(f models.FieldFilter) *gorm.DB {
+	q := database.GetDB().Model(&models.AccountFieldValue{}).
+		Select("account_field_values.account_id").
+		Joins("JOIN custom_fields ON custom_fields.id = account_field_values.field_id").
+		Where("custom_fields.name = ? AND custom_fields.type <> ?", f.Field, models.FieldTypeSecret)
+
+	switch f.Op {
+	case models.FieldOpContains:
+		return q.Where("account_field_values.value LIKE ?", "%"+f.Value+"%")
+	case models.FieldOpGreater:
+		return q.Where(`CASE WHEN custom_fields.type = ?
+			THEN CAST(account_field_values.value AS REAL) > CAST(? AS REAL)
+			ELSE account_field_values.value > ? END`, models.FieldTypeNumber, f.Value, f.Value)
+	case models.FieldOpLess:
+		return q.Where(`CASE WHEN custom_fields.type = ?
+			THEN CAST(account_field_values.value AS REAL) < CAST(? AS REAL)
+			ELSE account_field_values.value < ? END`, models.FieldTypeNumber, f.Value, f.Value)
+	default:
+		return q.Where("account_field_values.value = ?", f.Value)
+	}
+}
+
+// FindAllSecrets returns the id and encrypted fields of every account
+func (r *AccountRepository) FindAllSecrets() ([]models.Account, error) {
+	var accounts []models.Account
+	err := database.GetDB().Select("id, password, notes").Find(&accounts).Error
+	return accounts, err
+}
+
+// FindUncheckedSecrets returns the id and password of accounts whose password
+// health has not been computed yet
+func (r *AccountRepository) FindUncheckedSecrets() ([]models.Account, error) {
+	var accounts []models.Account
+	err := database.GetDB().Select("id, password").
+		Where("password_checked_at IS NULL AND password IS NOT NULL AND password <> ''").
+		Find(&accounts).Error
+	return accounts, err
+}
+
+// UpdatePasswordHealth stores the health fields of one account without
+// touching the rest of the row
+func (r *AccountRepository) UpdatePasswordHealth(account *models.Account) error {
+	return database.GetDB().Model(&models.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+		"password_score":       account.PasswordScore,
+		"password_breached":    account.PasswordBreached,
+		"password_fingerprint": account.PasswordFingerprint,
+		"password_checked_at":  account.PasswordCheckedAt,
+	}).Error
+}
+
+// RefreshReusedFlags marks every account whose password fingerprint is
+// shared with another account
+func (r *AccountRepository) RefreshReusedFlags() error {
+	return database.GetDB().Exec(`
+		UPDATE accounts SET password_reused = (
+			password_fingerprint IS NOT NULL AND password_fingerprint <> '' AND password_fingerprint IN (
+				SELECT password_fingerprint FROM accounts
+				WHERE password_fingerprint IS NOT NULL AND password_fingerprint <> '' AND deleted_at IS NULL
+				GROUP BY password_fingerprint HAVING COUNT(*) > 1
+			)
+		)`).Error
+}
+
+// GetPasswordHealthStats counts accounts per password health problem
+func (r *AccountRepository) GetPasswordHealthStats() (*models.PasswordHealthStats, error) {
+	var stats models.PasswordHealthStats
+	err := database.GetDB().Model(&models.Account{}).
+		Select(`
+			COALESCE(SUM(CASE WHEN password_checked_at IS NOT NULL THEN 1 ELSE 0 END), 0) as checked,
+			COALESCE(SUM(CASE WHEN password_checked_at IS NOT NULL AND password_score <= ? THEN 1 ELSE 0 END), 0) as weak,
+			COALESCE(SUM(CASE WHEN password_reused = 1 THEN 1 ELSE 0 END), 0) as reused,
+			COALESCE(SUM(CASE WHEN password_breached = 1 THEN 1 ELSE 0 END), 0) as breached
+		`, models.WeakPasswordScore).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 func (r *AccountRepository) GetStats() (*models.AccountStats, error) {
 	db := database.GetDB()
 	var stats models.AccountStats
 
-	// Single aggregated query instead of 7 separate queries
+	now := time.Now()
+	sevenDaysLater := now.AddDate(0, 0, 7)
+
+	// Single aggregated query for the totals
 	type statsResult struct {
 		Total           int64
-		PlusCount       int64
-		BusinessCount   int64
-		FreeCount       int64
 		SoldCount       int64
 		ExpiredCount    int64
 		ExpiringIn7Days int64
 	}
 
-	now := time.Now()
-	sevenDaysLater := now.AddDate(0, 0, 7)
-
 	var result statsResult
 	err := db.Model(&models.Account{}).
 		Select(`
 			COUNT(*) as total,
-			SUM(CASE WHEN account_type = ? THEN 1 ELSE 0 END) as plus_count,
-			SUM(CASE WHEN account_type = ? THEN 1 ELSE 0 END) as business_count,
-			SUM(CASE WHEN account_type = ? THEN 1 ELSE 0 END) as free_count,
-			SUM(CASE WHEN is_sold = 1 THEN 1 ELSE 0 END) as sold_count,
-			SUM(CASE WHEN expire_at IS NOT NULL AND expire_at < ? THEN 1 ELSE 0 END) as expired_count,
-			SUM(CASE WHEN expire_at IS NOT NULL AND expire_at > ? AND expire_at <= ? THEN 1 ELSE 0 END) as expiring_in7_days
-		`, models.AccountTypePLUS, models.AccountTypeBUSINESS, models.AccountTypeFREE, now, now, sevenDaysLater).
+			COALESCE(SUM(CASE WHEN is_sold = 1 THEN 1 ELSE 0 END), 0) as sold_count,
+			COALESCE(SUM(CASE WHEN expire_at IS NOT NULL AND expire_at < ? THEN 1 ELSE 0 END), 0) as expired_count,
+			COALESCE(SUM(CASE WHEN expire_at IS NOT NULL AND expire_at > ? AND expire_at <= ? THEN 1 ELSE 0 END), 0) as expiring_in7_days
+		`, now, now, sevenDaysLater).
 		Scan(&result).Error
-
 	if err != nil {
 		return nil, err
 	}
 
 	stats.Total = result.Total
-	stats.PlusCount = result.PlusCount
-	stats.BusinessCount = result.BusinessCount
-	stats.FreeCount = result.FreeCount
 	stats.SoldCount = result.SoldCount
 	stats.ExpiredCount = result.ExpiredCount
 	stats.ExpiringIn7Days = result.ExpiringIn7Days
 
+	// One grouped query for the per type counts
+	var counts []models.AccountTypeStats
+	err = db.Model(&models.Account{}).
+		Select(`
+			account_type as type,
+			COUNT(*) as total,
+			SUM(CASE WHEN is_sold = 1 THEN 1 ELSE 0 END) as sold,
+			SUM(CASE WHEN expire_at IS NOT NULL AND expire_at < ? THEN 1 ELSE 0 END) as expired
+		`, now).
+		Group("account_type").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var types []models.AccountTypeConfig
+	if err := db.Order("sort_order, name").Find(&types).Error; err != nil {
+		return nil, err
+	}
+
+	byType := make(map[models.AccountType]models.AccountTypeStats, len(counts))
+	for _, c := range counts {
+		byType[c.Type] = c
+	}
+	stats.ByType = make([]models.AccountTypeStats, 0, len(types))
+	for _, t := range types {
+		c := byType[t.Name]
+		c.Type = t.Name
+		c.Color = t.Color
+		stats.ByType = append(stats.ByType, c)
+		delete(byType, t.Name)
+	}
+	// Accounts whose type is no longer configured are still counted
+	for _, c := range counts {
+		if _, ok := byType[c.Type]; ok {
+			stats.ByType = append(stats.ByType, c)
+		}
+	}
+
+	// And one for the per status counts
+	var statusCounts []models.AccountStatusStats
+	err = db.Model(&models.Account{}).
+		Select("status, COUNT(*) as total").
+		Group("status").
+		Scan(&statusCounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []models.AccountStatusConfig
+	if err := db.Order("sort_order, name").Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+
+	byStatus := make(map[string]int64, len(statusCounts))
+	for _, c := range statusCounts {
+		byStatus[c.Status] = c.Total
+	}
+	stats.ByStatus = make([]models.AccountStatusStats, 0, len(statuses))
+	for _, st := range statuses {
+		stats.ByStatus = append(stats.ByStatus, models.AccountStatusStats{
+			Status: st.Name,
+			Label:  st.Label,
+			Color:  st.Color,
+			Total:  byStatus[st.Name],
+		})
+	}
+
+	// Tags without accounts are listed with a zero count
+	err = db.Model(&models.Tag{}).
+		Select("tags.id as tag_id, tags.name, tags.color, COUNT(accounts.id) as total").
+		Joins("LEFT JOIN account_tags ON account_tags.tag_id = tags.id").
+		Joins("LEFT JOIN accounts ON accounts.id = account_tags.account_id AND accounts.deleted_at IS NULL").
+		Group("tags.id").
+		Order("tags.name").
+		Scan(&stats.ByTag).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Revenue comes from the ledger, so it includes purged accounts
+	err = db.Model(&models.Sale{}).
+		Select(`
+			account_type as key,
+			currency,
+			SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END) as sales,
+			SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END) as reversals,
+			SUM(CASE WHEN kind = ? THEN price_cents ELSE 0 END) as gross,
+			SUM(price_cents) as net
+		`, models.SaleKindSale, models.SaleKindReversal, models.SaleKindSale).
+		Group("account_type, currency").
+		Order("account_type, currency").
+		Scan(&stats.Revenue).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range stats.ByType {
+		switch c.Type {
+		case models.AccountTypePLUS:
+			stats.PlusCount = c.Total
+		case models.AccountTypeBUSINESS:
+			stats.BusinessCount = c.Total
+		case models.AccountTypeFREE:
+			stats.FreeCount = c.Total
+		}
+	}
+
 	return &stats, nil
 }
 
+// CountByStatus returns the number of accounts in a status, including the
+// trash so a restored account never refers to a missing status
+func (r *AccountRepository) CountByStatus(status string) (int64, error) {
+	var count int64
+	err := database.GetDB().Unscoped().Model(&models.Account{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+// CountByType returns the number of accounts of a type, including the trash
+func (r *AccountRepository) CountByType(accountType models.AccountType) (int64, error) {
+	var count int64
+	err := database.GetDB().Unscoped().Model(&models.Account{}).Where("account_type = ?", accountType).Count(&count).Error
+	return count, err
+}
+
 func (r *AccountRepository) FindExpiringAccounts(daysBefore int) ([]models.Account, error) {
 	now := time.Now()
 	targetDate := now.AddDate(0, 0, daysBefore)
 	startOfDay := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 0, 0, 0, 0, targetDate.Location())
 	endOfDay := startOfDay.AddDate(0, 0, 1)
 
+	// Only non-secret columns are loaded so reminders work while the vault is locked
 	var accounts []models.Account
-	err := database.GetDB().Where(
+	err := database.GetDB().Select("id, account, account_type, expire_at, is_sold, reminder_sent").Where(
 		"expire_at >= ? AND expire_at < ? AND reminder_sent = ? AND is_sold = ?",
 		startOfDay, endOfDay, false, false,
 	).Find(&accounts).Error
@@ -155,7 +661,7 @@ func (r *AccountRepository) FindExpiringAccounts(daysBefore int) ([]models.Accou
 }
 
 func (r *AccountRepository) MarkReminderSent(ids []uint) error {
-	return database.GetDB().Model(&models.Account{}).Where("id IN ?", ids).Update("reminder_sent", true).Error
+	return database.GetDB().Model(&models.Account{}).Where(idSetCondition, idSet(ids)).Update("reminder_sent", true).Error
 }
 
 func (r *AccountRepository) BatchCreate(accounts []models.Account) error {
@@ -168,3 +674,64 @@ func (r *AccountRepository) BatchCreate(accounts []models.Account) error {
 		return nil
 	})
 }
+
+// BulkWrite holds everything a bulk operation writes
+type BulkWrite struct {
+	Updated       []*models.Account
+	ClearFields   map[uint][]uint // Custom field ids to clear, by account id
+	Deleted       []uint          // Accounts moved to the trash
+	DeletedBy     string
+	StatusChanges []models.AccountStatusChange
+	ChangeSets    []models.AccountChangeSet
+	Renewals      []models.Renewal
+	Sales         []models.Sale
+}
+
+// ApplyBulk writes the result of a bulk operation in one transaction, so
+// either every account changes or none does
+func (r *AccountRepository) ApplyBulk(write *BulkWrite) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, account := range write.Updated {
+			if err := tx.Omit("Tags").Save(account).Error; err != nil {
+				return err
+			}
+		}
+		for accountID, fieldIDs := range write.ClearFields {
+			if err := tx.Where("account_id = ? AND field_id IN ?", accountID, fieldIDs).
+				Delete(&models.AccountFieldValue{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(write.Deleted) > 0 {
+			deleted := idSet(write.Deleted)
+			if err := tx.Model(&models.Account{}).Where(idSetCondition, deleted).
+				UpdateColumn("deleted_by", write.DeletedBy).Error; err != nil {
+				return err
+			}
+			if err := tx.Where(idSetCondition, deleted).Delete(&models.Account{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(write.StatusChanges) > 0 {
+			if err := tx.CreateInBatches(write.StatusChanges, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(write.ChangeSets) > 0 {
+			if err := tx.CreateInBatches(write.ChangeSets, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(write.Renewals) > 0 {
+			if err := tx.CreateInBatches(write.Renewals, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(write.Sales) > 0 {
+			if err := tx.CreateInBatches(write.Sales, 100).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AccountStatusRepository struct{}
+
+func NewAccountStatusRepository() *AccountStatusRepository {
+	return &AccountStatusRepository{}
+}
+
+// FindAll returns all statuses in workflow order
+func (r *AccountStatusRepository) FindAll() ([]models.AccountStatusConfig, error) {
+	var statuses []models.AccountStatusConfig
+	err := database.GetDB().Order("sort_order, name").Find(&statuses).Error
+	return statuses, err
+}
+
+func (r *AccountStatusRepository) FindByID(id uint) (*models.AccountStatusConfig, error) {
+	var status models.AccountStatusConfig
+	err := database.GetDB().First(&status, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (r *AccountStatusRepository) FindByName(name string) (*models.AccountStatusConfig, error) {
+	var status models.AccountStatusConfig
+	err := database.GetDB().Where("name = ?", name).First(&status).Error
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// FindInitial returns the status of new accounts
+func (r *AccountStatusRepository) FindInitial() (*models.AccountStatusConfig, error) {
+	var status models.AccountStatusConfig
+	err := database.GetDB().Where("initial = ?", true).Order("sort_order").First(&status).Error
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// FindFirstSold returns the first status in workflow order that counts as sold
+func (r *AccountStatusRepository) FindFirstSold() (*models.AccountStatusConfig, error) {
+	var status models.AccountStatusConfig
+	err := database.GetDB().Where("sold = ?", true).Order("sort_order").First(&status).Error
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Create stores a status. When it is the new initial status the flag is
+// cleared on the others in the same transaction.
+func (r *AccountStatusRepository) Create(status *models.AccountStatusConfig) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(status).Error; err != nil {
+			return err
+		}
+		return clearOtherInitial(tx, status)
+	})
+}
+
+// Update saves a status. When it was renamed, accounts and transitions are
+// moved to the new name in the same transaction.
+func (r *AccountStatusRepository) Update(status *models.AccountStatusConfig, oldName string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(status).Error; err != nil {
+			return err
+		}
+		if err := clearOtherInitial(tx, status); err != nil {
+			return err
+		}
+		if oldName == status.Name {
+			return nil
+		}
+
+		if err := tx.Unscoped().Model(&models.Account{}).Where("status = ?", oldName).
+			UpdateColumn("status", status.Name).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AccountStatusTransition{}).Where("from_status = ?", oldName).
+			UpdateColumn("from_status", status.Name).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AccountStatusTransition{}).Where("to_status = ?", oldName).
+			UpdateColumn("to_status", status.Name).Error
+	})
+}
+
+func clearOtherInitial(tx *gorm.DB, status *models.AccountStatusConfig) error {
+	if !status.Initial {
+		return nil
+	}
+	return tx.Model(&models.AccountStatusConfig{}).Where("id <> ?", status.ID).
+		UpdateColumn("initial", false).Error
+}
+
+// Delete removes a status and its transitions
+func (r *AccountStatusRepository) Delete(status *models.AccountStatusConfig) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("from_status = ? OR to_status = ?", status.Name, status.Name).
+			Delete(&models.AccountStatusTransition{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AccountStatusConfig{}, status.ID).Error
+	})
+}
+
+// FindTransitions returns every allowed transition
+func (r *AccountStatusRepository) FindTransitions() ([]models.AccountStatusTransition, error) {
+	var transitions []models.AccountStatusTransition
+	err := database.GetDB().Order("from_status, to_status").Find(&transitions).Error
+	return transitions, err
+}
+
+// TransitionAllowed reports whether accounts may move from one status to another
+func (r *AccountStatusRepository) TransitionAllowed(from, to string) (bool, error) {
+	var count int64
+	err := database.GetDB().Model(&models.AccountStatusTransition{}).
+		Where("from_status = ? AND to_status = ?", from, to).Count(&count).Error
+	return count > 0, err
+}
+
+// SetTransitions replaces the statuses reachable from one status
+func (r *AccountStatusRepository) SetTransitions(from string, to []string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("from_status = ?", from).Delete(&models.AccountStatusTransition{}).Error; err != nil {
+			return err
+		}
+		for _, name := range to {
+			if err := tx.Create(&models.AccountStatusTransition{FromStatus: from, ToStatus: name}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateChange records a status transition of an account
+func (r *AccountStatusRepository) CreateChange(change *models.AccountStatusChange) error {
+	return database.GetDB().Create(change).Error
+}
+
+// FindChanges returns the status transitions of an account, newest first
+func (r *AccountStatusRepository) FindChanges(accountID uint) ([]models.AccountStatusChange, error) {
+	var changes []models.AccountStatusChange
+	err := database.GetDB().Where("account_id = ?", accountID).Order("changed_at DESC, id DESC").Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AccountTypeRepository struct{}
+
+func NewAccountTypeRepository() *AccountTypeRepository {
+	return &AccountTypeRepository{}
+}
+
+// FindAll returns all account types in display order
+func (r *AccountTypeRepository) FindAll() ([]models.AccountTypeConfig, error) {
+	var types []models.AccountTypeConfig
+	err := database.GetDB().Order("sort_order, name").Find(&types).Error
+	return types, err
+}
+
+func (r *AccountTypeRepository) FindByID(id uint) (*models.AccountTypeConfig, error) {
+	var accountType models.AccountTypeConfig
+	err := database.GetDB().First(&accountType, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accountType, nil
+}
+
+func (r *AccountTypeRepository) FindByName(name models.AccountType) (*models.AccountTypeConfig, error) {
+	var accountType models.AccountTypeConfig
+	err := database.GetDB().Where("name = ?", name).First(&accountType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accountType, nil
+}
+
+func (r *AccountTypeRepository) Create(accountType *models.AccountTypeConfig) error {
+	return database.GetDB().Create(accountType).Error
+}
+
+// Update saves an account type. When it was renamed, accounts and custom
+// fields of the old name are moved to the new one in the same transaction.
+func (r *AccountTypeRepository) Update(accountType *models.AccountTypeConfig, oldName models.AccountType) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(accountType).Error; err != nil {
+			return err
+		}
+		if oldName == accountType.Name {
+			return nil
+		}
+		if err := tx.Unscoped().Model(&models.Account{}).
+			Where("account_type = ?", oldName).
+			UpdateColumn("account_type", accountType.Name).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CustomField{}).
+			Where("account_type = ?", oldName).
+			UpdateColumn("account_type", accountType.Name).Error
+	})
+}
+
+// Delete removes an account type and its custom fields
+func (r *AccountTypeRepository) Delete(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var accountType models.AccountTypeConfig
+		if err := tx.First(&accountType, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("field_id IN (?)",
+			tx.Model(&models.CustomField{}).Select("id").Where("account_type = ?", accountType.Name),
+		).Delete(&models.AccountFieldValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_type = ?", accountType.Name).Delete(&models.CustomField{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&accountType).Error
+	})
+}
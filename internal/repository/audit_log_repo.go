@@ -5,6 +5,8 @@ import (
 
 	"account-manager/internal/database"
 	"account-manager/internal/models"
+
+	"gorm.io/gorm"
 )
 
 type AuditLogRepository struct{}
@@ -112,3 +114,146 @@ func (r *AuditLogRepository) GetStats() (map[string]int64, error) {
 
 	return stats, nil
 }
+
+// Last returns the id and hash of the newest entry, nil when there is none
+func (r *AuditLogRepository) Last() (*models.AuditLog, error) {
+	db := database.GetDB()
+	var logs []models.AuditLog
+	if err := db.Select("id, hash").Order("id DESC").Limit(1).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return &logs[0], nil
+}
+
+// FindUnchained returns the entries written before hash chaining was
+// introduced, oldest first. Nothing is returned once the chain has started.
+func (r *AuditLogRepository) FindUnchained() ([]models.AuditLog, error) {
+	db := database.GetDB()
+
+	var chained int64
+	if err := db.Model(&models.AuditLog{}).Where("hash <> ''").Count(&chained).Error; err != nil {
+		return nil, err
+	}
+	if chained > 0 {
+		return nil, nil
+	}
+
+	var logs []models.AuditLog
+	err := db.Order("id").Find(&logs).Error
+	return logs, err
+}
+
+// UpdateChain sets the chain hashes of an entry
+func (r *AuditLogRepository) UpdateChain(id uint, prevHash, hash string) error {
+	db := database.GetDB()
+	return db.Model(&models.AuditLog{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"prev_hash": prevHash, "hash": hash}).Error
+}
+
+// FindAfter returns up to limit entries with an id above afterID, oldest first
+func (r *AuditLogRepository) FindAfter(afterID uint, limit int) ([]models.AuditLog, error) {
+	db := database.GetDB()
+	var logs []models.AuditLog
+	err := db.Where("id > ?", afterID).Order("id").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// FindByID returns a single entry
+func (r *AuditLogRepository) FindByID(id uint) (*models.AuditLog, error) {
+	db := database.GetDB()
+	var log models.AuditLog
+	if err := db.First(&log, id).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// PruneRange returns the ids and number of the leading entries older than
+// date. Entries are pruned as a prefix so the remaining chain stays whole.
+func (r *AuditLogRepository) PruneRange(date time.Time) (firstID, lastID uint, count int64, err error) {
+	db := database.GetDB()
+
+	// The first newer entry bounds the prefix, in case clocks went backwards
+	var newer []models.AuditLog
+	if err = db.Select("id").Where("timestamp >= ?", date).Order("id").Limit(1).Find(&newer).Error; err != nil {
+		return
+	}
+	query := db.Model(&models.AuditLog{}).Where("timestamp < ?", date)
+	if len(newer) > 0 {
+		query = query.Where("id < ?", newer[0].ID)
+	}
+
+	var bounds struct {
+		FirstID uint
+		LastID  uint
+		Count   int64
+	}
+	err = query.Select("COALESCE(MIN(id), 0) AS first_id, COALESCE(MAX(id), 0) AS last_id, COUNT(*) AS count").
+		Scan(&bounds).Error
+	return bounds.FirstID, bounds.LastID, bounds.Count, err
+}
+
+// Prune deletes the entries up to lastID and the checkpoints covering them,
+// and stores the anchor that replaces them, in one transaction
+func (r *AuditLogRepository) Prune(lastID uint, anchor *models.AuditCheckpoint) error {
+	db := database.GetDB()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(anchor).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kind = ? AND last_log_id <= ?", models.CheckpointPeriodic, lastID).
+			Delete(&models.AuditCheckpoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id <= ?", lastID).Delete(&models.AuditLog{}).Error
+	})
+}
+
+// CreateCheckpoint stores a signed checkpoint
+func (r *AuditLogRepository) CreateCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	db := database.GetDB()
+	return db.Create(checkpoint).Error
+}
+
+// LastCheckpoint returns the newest checkpoint of a kind, nil when there is none
+func (r *AuditLogRepository) LastCheckpoint(kind string) (*models.AuditCheckpoint, error) {
+	db := database.GetDB()
+	var checkpoints []models.AuditCheckpoint
+	if err := db.Where("kind = ?", kind).Order("last_log_id DESC").Limit(1).Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, nil
+	}
+	return &checkpoints[0], nil
+}
+
+// FindCheckpoints returns the checkpoints of a kind in chain order
+func (r *AuditLogRepository) FindCheckpoints(kind string) ([]models.AuditCheckpoint, error) {
+	db := database.GetDB()
+	var checkpoints []models.AuditCheckpoint
+	err := db.Where("kind = ?", kind).Order("last_log_id, id").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// GetSigningKey returns the checkpoint signing key, nil when none exists yet
+func (r *AuditLogRepository) GetSigningKey() (*models.AuditSigningKey, error) {
+	db := database.GetDB()
+	var keys []models.AuditSigningKey
+	if err := db.Order("id").Limit(1).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+// CreateSigningKey stores the checkpoint signing key
+func (r *AuditLogRepository) CreateSigningKey(key *models.AuditSigningKey) error {
+	db := database.GetDB()
+	return db.Create(key).Error
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+type BreachRepository struct{}
+
+func NewBreachRepository() *BreachRepository {
+	return &BreachRepository{}
+}
+
+// Upsert stores a batch of breached hashes, keeping the highest count
+func (r *BreachRepository) Upsert(entries []models.BreachedPassword) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": clause.Expr{SQL: "MAX(count, excluded.count)"}}),
+	}).CreateInBatches(entries, 500).Error
+}
+
+// FindByHash returns the breach entry for an upper-case hex SHA-1 hash
+func (r *BreachRepository) FindByHash(hash string) (*models.BreachedPassword, error) {
+	var entry models.BreachedPassword
+	err := database.GetDB().Where("hash = ?", hash).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *BreachRepository) Count() (int64, error) {
+	var count int64
+	err := database.GetDB().Model(&models.BreachedPassword{}).Count(&count).Error
+	return count, err
+}
+
+// DeleteAll removes the imported breach list
+func (r *BreachRepository) DeleteAll() error {
+	return database.GetDB().Where("1 = 1").Delete(&models.BreachedPassword{}).Error
+}
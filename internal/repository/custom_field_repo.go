@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomFieldRepository struct{}
+
+func NewCustomFieldRepository() *CustomFieldRepository {
+	return &CustomFieldRepository{}
+}
+
+// FindByType returns the custom fields of an account type in display order
+func (r *CustomFieldRepository) FindByType(accountType models.AccountType) ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := database.GetDB().Where("account_type = ?", accountType).Order("sort_order, id").Find(&fields).Error
+	return fields, err
+}
+
+// FindAll returns every custom field, grouped by account type
+func (r *CustomFieldRepository) FindAll() ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := database.GetDB().Order("account_type, sort_order, id").Find(&fields).Error
+	return fields, err
+}
+
+func (r *CustomFieldRepository) FindByID(id uint) (*models.CustomField, error) {
+	var field models.CustomField
+	err := database.GetDB().First(&field, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (r *CustomFieldRepository) FindByName(accountType models.AccountType, name string) (*models.CustomField, error) {
+	var field models.CustomField
+	err := database.GetDB().Where("account_type = ? AND name = ?", accountType, name).First(&field).Error
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (r *CustomFieldRepository) Create(field *models.CustomField) error {
+	return database.GetDB().Create(field).Error
+}
+
+func (r *CustomFieldRepository) Update(field *models.CustomField) error {
+	return database.GetDB().Save(field).Error
+}
+
+// Delete removes a custom field and its value on every account
+func (r *CustomFieldRepository) Delete(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", id).Delete(&models.AccountFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.CustomField{}, id).Error
+	})
+}
+
+// CountValues returns the number of accounts that have a value for a field
+func (r *CustomFieldRepository) CountValues(fieldID uint) (int64, error) {
+	var count int64
+	err := database.GetDB().Model(&models.AccountFieldValue{}).Where("field_id = ?", fieldID).Count(&count).Error
+	return count, err
+}
+
+// FindValues returns the custom field values of the given accounts
+func (r *CustomFieldRepository) FindValues(accountIDs []uint) ([]models.AccountFieldValue, error) {
+	var values []models.AccountFieldValue
+	if len(accountIDs) == 0 {
+		return values, nil
+	}
+	err := database.GetDB().Where(accountIDSetCondition, idSet(accountIDs)).Find(&values).Error
+	return values, err
+}
+
+// FindSecretValues returns every encrypted custom field value
+func (r *CustomFieldRepository) FindSecretValues() ([]models.AccountFieldValue, error) {
+	var values []models.AccountFieldValue
+	err := database.GetDB().Select("id, account_id, field_id, secret_value").
+		Where("secret_value IS NOT NULL AND secret_value <> ''").
+		Find(&values).Error
+	return values, err
+}
+
+// SetValues writes the given values of an account and removes the values of
+// the fields in clear, in one transaction
+func (r *CustomFieldRepository) SetValues(accountID uint, values []models.AccountFieldValue, clear []uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if len(clear) > 0 {
+			if err := tx.Where("account_id = ? AND field_id IN ?", accountID, clear).
+				Delete(&models.AccountFieldValue{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range values {
+			values[i].AccountID = accountID
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "account_id"}, {Name: "field_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "secret_value"}),
+			}).Create(&values[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteValues removes every custom field value of an account
+func (r *CustomFieldRepository) DeleteValues(accountID uint) error {
+	return database.GetDB().Where("account_id = ?", accountID).Delete(&models.AccountFieldValue{}).Error
+}
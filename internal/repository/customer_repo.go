@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CustomerRepository struct{}
+
+func NewCustomerRepository() *CustomerRepository {
+	return &CustomerRepository{}
+}
+
+// FindAll returns the customers whose name, email or handle contains search,
+// by name, with the number of accounts each holds
+func (r *CustomerRepository) FindAll(search string, page, pageSize int) (*models.PaginatedCustomers, error) {
+	db := database.GetDB().Model(&models.Customer{})
+	if search != "" {
+		like := "%" + search + "%"
+		db = db.Where("name LIKE ? OR email LIKE ? OR handle LIKE ?", like, like, like)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	var customers []models.Customer
+	err := db.Order("name, id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&customers).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.countAccounts(customers); err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedCustomers{
+		Data:       customers,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// countAccounts fills AccountCount of the given customers
+func (r *CustomerRepository) countAccounts(customers []models.Customer) error {
+	if len(customers) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(customers))
+	for i, c := range customers {
+		ids[i] = c.ID
+	}
+
+	var counts []struct {
+		CustomerID uint
+		Total      int64
+	}
+	err := database.GetDB().Model(&models.Account{}).
+		Select("customer_id, COUNT(*) as total").
+		Where("customer_id IN ?", ids).
+		Group("customer_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	byCustomer := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byCustomer[c.CustomerID] = c.Total
+	}
+	for i := range customers {
+		customers[i].AccountCount = byCustomer[customers[i].ID]
+	}
+	return nil
+}
+
+func (r *CustomerRepository) FindByID(id uint) (*models.Customer, error) {
+	var customer models.Customer
+	err := database.GetDB().First(&customer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	customers := []models.Customer{customer}
+	if err := r.countAccounts(customers); err != nil {
+		return nil, err
+	}
+	return &customers[0], nil
+}
+
+// FindByIDs returns the customers with the given ids, in no particular order
+func (r *CustomerRepository) FindByIDs(ids []uint) ([]models.Customer, error) {
+	var customers []models.Customer
+	if len(ids) == 0 {
+		return customers, nil
+	}
+	err := database.GetDB().Where("id IN ?", ids).Find(&customers).Error
+	return customers, err
+}
+
+func (r *CustomerRepository) Create(customer *models.Customer) error {
+	return database.GetDB().Create(customer).Error
+}
+
+func (r *CustomerRepository) Update(customer *models.Customer) error {
+	return database.GetDB().Save(customer).Error
+}
+
+// Delete removes a customer. Its accounts, including those in the trash,
+// and its ledger entries are unlinked; the buyer name stays on the entries.
+func (r *CustomerRepository) Delete(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Account{}).Where("customer_id = ?", id).
+			UpdateColumn("customer_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Sale{}).Where("customer_id = ?", id).
+			UpdateColumn("customer_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Customer{}, id).Error
+	})
+}
+
+// AssignAccounts links the sold accounts among accountIDs to a customer, or
+// unlinks them when customerID is nil, and returns how many changed
+func (r *CustomerRepository) AssignAccounts(customerID *uint, accountIDs []uint) (int64, error) {
+	if len(accountIDs) == 0 {
+		return 0, nil
+	}
+	db := database.GetDB().Model(&models.Account{}).Where("id IN ?", accountIDs)
+	if customerID != nil {
+		db = db.Where("is_sold = ?", true)
+	}
+	result := db.UpdateColumn("customer_id", customerID)
+	return result.RowsAffected, result.Error
+}
+
+// FindExpiring returns the accounts held by customers that expire in
+// [from, to), by customer and soonest first. customerID 0 means every
+// customer. Only non-secret columns are loaded.
+func (r *CustomerRepository) FindExpiring(customerID uint, from, to time.Time) ([]models.Account, error) {
+	db := database.GetDB().Select("id, account, account_type, status, expire_at, customer_id").
+		Where("expire_at >= ? AND expire_at < ?", from, to)
+	if customerID != 0 {
+		db = db.Where("customer_id = ?", customerID)
+	} else {
+		db = db.Where("customer_id IS NOT NULL")
+	}
+
+	var accounts []models.Account
+	err := db.Order("customer_id, expire_at, id").Find(&accounts).Error
+	return accounts, err
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+)
+
+type PasswordHistoryRepository struct{}
+
+func NewPasswordHistoryRepository() *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{}
+}
+
+func (r *PasswordHistoryRepository) Create(entry *models.PasswordHistory) error {
+	return database.GetDB().Create(entry).Error
+}
+
+func (r *PasswordHistoryRepository) FindByID(id uint) (*models.PasswordHistory, error) {
+	var entry models.PasswordHistory
+	err := database.GetDB().First(&entry, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindByAccount returns the password history of an account, newest first
+func (r *PasswordHistoryRepository) FindByAccount(accountID uint) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := database.GetDB().Where("account_id = ?", accountID).Order("changed_at DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+)
+
+type PasswordPolicyRepository struct{}
+
+func NewPasswordPolicyRepository() *PasswordPolicyRepository {
+	return &PasswordPolicyRepository{}
+}
+
+// FindAll returns all password policies, built-in ones first
+func (r *PasswordPolicyRepository) FindAll() ([]models.PasswordPolicy, error) {
+	var policies []models.PasswordPolicy
+	err := database.GetDB().Order("built_in DESC, name ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *PasswordPolicyRepository) FindByID(id uint) (*models.PasswordPolicy, error) {
+	var policy models.PasswordPolicy
+	err := database.GetDB().First(&policy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PasswordPolicyRepository) FindByName(name string) (*models.PasswordPolicy, error) {
+	var policy models.PasswordPolicy
+	err := database.GetDB().Where("name = ?", name).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PasswordPolicyRepository) Create(policy *models.PasswordPolicy) error {
+	return database.GetDB().Create(policy).Error
+}
+
+func (r *PasswordPolicyRepository) Update(policy *models.PasswordPolicy) error {
+	return database.GetDB().Save(policy).Error
+}
+
+func (r *PasswordPolicyRepository) Delete(id uint) error {
+	return database.GetDB().Delete(&models.PasswordPolicy{}, id).Error
+}
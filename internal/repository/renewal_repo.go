@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RenewalRepository struct{}
+
+func NewRenewalRepository() *RenewalRepository {
+	return &RenewalRepository{}
+}
+
+func (r *RenewalRepository) Create(renewal *models.Renewal) error {
+	return database.GetDB().Create(renewal).Error
+}
+
+// FindByAccount returns the renewals of an account, newest first
+func (r *RenewalRepository) FindByAccount(accountID uint) ([]models.Renewal, error) {
+	var renewals []models.Renewal
+	err := database.GetDB().Where("account_id = ?", accountID).
+		Order("renewed_at DESC, id DESC").
+		Find(&renewals).Error
+	return renewals, err
+}
+
+// FindBetween returns the renewals made in [from, to), oldest first
+func (r *RenewalRepository) FindBetween(from, to time.Time) ([]models.Renewal, error) {
+	var renewals []models.Renewal
+	err := database.GetDB().Where("renewed_at >= ? AND renewed_at < ?", from, to).
+		Order("renewed_at, id").
+		Find(&renewals).Error
+	return renewals, err
+}
+
+// Renew saves an account whose expiry was extended together with the
+// renewal record, in one transaction
+func (r *RenewalRepository) Renew(account *models.Account, renewal *models.Renewal) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(account).Error; err != nil {
+			return err
+		}
+		return tx.Create(renewal).Error
+	})
+}
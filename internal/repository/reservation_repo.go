@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrAccountsTaken is returned by Allocate when another reservation took
+// some of the picked accounts first
+var ErrAccountsTaken = errors.New("accounts were reserved concurrently")
+
+type ReservationRepository struct{}
+
+func NewReservationRepository() *ReservationRepository {
+	return &ReservationRepository{}
+}
+
+// allocationOrder returns the order in which a strategy picks accounts
+func allocationOrder(strategy string) string {
+	switch strategy {
+	case models.AllocateExpiring:
+		return "expire_at IS NULL, expire_at, id"
+	case models.AllocateLongest:
+		return "expire_at IS NOT NULL, expire_at DESC, id"
+	default:
+		return "created_at, id"
+	}
+}
+
+// whereNotHeld limits a query on accounts to those no held reservation
+// holds at now
+func whereNotHeld(tx *gorm.DB, now time.Time) *gorm.DB {
+	held := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Reservation{}).
+		Select("id").
+		Where("status = ? AND held_until > ?", models.ReservationHeld, now)
+	return tx.Where("reservation_id IS NULL OR reservation_id NOT IN (?)", held)
+}
+
+// Allocate picks reservation.Quantity accounts of reservation.AccountType in
+// status that are unsold, not expired and not held, in the order of
+// reservation.Strategy, then creates the reservation and links the accounts
+// to it in one transaction. The accounts are claimed with a conditional
+// update, so concurrent allocations never share an account; the loser gets
+// ErrAccountsTaken.
+//
+// It returns the picked ids. When fewer accounts are available nothing is
+// written and the reservation keeps a zero ID.
+func (r *ReservationRepository) Allocate(reservation *models.Reservation, status string, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Account{}).
+			Where("account_type = ? AND status = ? AND is_sold = ?", reservation.AccountType, status, false).
+			Where("expire_at IS NULL OR expire_at > ?", now)
+		err := whereNotHeld(query, now).
+			Order(allocationOrder(reservation.Strategy)).
+			Limit(reservation.Quantity).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) < reservation.Quantity {
+			return err
+		}
+
+		if err := tx.Create(reservation).Error; err != nil {
+			return err
+		}
+		claim := whereNotHeld(tx.Model(&models.Account{}).Where("id IN ?", ids), now).
+			UpdateColumn("reservation_id", reservation.ID)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected != int64(len(ids)) {
+			return ErrAccountsTaken
+		}
+		return nil
+	})
+	if err != nil {
+		reservation.ID = 0
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Close saves a reservation that was confirmed, released or expired and
+// unlinks its accounts that are not sold, including those in the trash
+func (r *ReservationRepository) Close(reservation *models.Reservation) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(reservation).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Account{}).
+			Where("reservation_id = ? AND is_sold = ?", reservation.ID, false).
+			UpdateColumn("reservation_id", nil).Error
+	})
+}
+
+func (r *ReservationRepository) FindByID(id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := database.GetDB().First(&reservation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// FindAll returns the reservations in status, or all when status is empty,
+// newest first
+func (r *ReservationRepository) FindAll(status string, page, pageSize int) (*models.PaginatedReservations, error) {
+	db := database.GetDB().Model(&models.Reservation{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	var reservations []models.Reservation
+	err := db.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedReservations{
+		Data:       reservations,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// FindExpired returns the held reservations whose hold ended before now
+func (r *ReservationRepository) FindExpired(now time.Time) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	err := database.GetDB().Where("status = ? AND held_until <= ?", models.ReservationHeld, now).
+		Order("id").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// FindAccounts returns the accounts linked to the given reservations, by
+// reservation and id. Only non-secret columns are loaded.
+func (r *ReservationRepository) FindAccounts(reservationIDs []uint) ([]models.Account, error) {
+	var accounts []models.Account
+	if len(reservationIDs) == 0 {
+		return accounts, nil
+	}
+	err := database.GetDB().Select("id, account, account_type, status, is_sold, expire_at, created_at, reservation_id").
+		Where("reservation_id IN ?", reservationIDs).
+		Order("reservation_id, id").
+		Find(&accounts).Error
+	return accounts, err
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SaleRepository struct{}
+
+func NewSaleRepository() *SaleRepository {
+	return &SaleRepository{}
+}
+
+func (r *SaleRepository) Create(sale *models.Sale) error {
+	return database.GetDB().Create(sale).Error
+}
+
+// Update saves the details of a ledger entry
+func (r *SaleRepository) Update(sale *models.Sale) error {
+	return database.GetDB().Save(sale).Error
+}
+
+// Record saves an account whose sold state changed together with its ledger
+// entries, in one transaction
+func (r *SaleRepository) Record(account *models.Account, sales []models.Sale) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(account).Error; err != nil {
+			return err
+		}
+		if len(sales) == 0 {
+			return nil
+		}
+		return tx.Create(sales).Error
+	})
+}
+
+func (r *SaleRepository) FindByID(id uint) (*models.Sale, error) {
+	var sale models.Sale
+	err := database.GetDB().First(&sale, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// FindOpenSale returns the latest sale of an account that was not reversed
+func (r *SaleRepository) FindOpenSale(accountID uint) (*models.Sale, error) {
+	db := database.GetDB()
+	var sale models.Sale
+	err := db.Where("account_id = ? AND kind = ?", accountID, models.SaleKindSale).
+		Where("id NOT IN (?)", db.Model(&models.Sale{}).Select("reversal_of").Where("reversal_of IS NOT NULL")).
+		Order("sold_at DESC, id DESC").
+		First(&sale).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// FindReversal returns the reversal of a sale
+func (r *SaleRepository) FindReversal(saleID uint) (*models.Sale, error) {
+	var sale models.Sale
+	err := database.GetDB().Where("reversal_of = ?", saleID).First(&sale).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// FindByAccount returns the ledger entries of an account, newest first
+func (r *SaleRepository) FindByAccount(accountID uint) ([]models.Sale, error) {
+	var sales []models.Sale
+	err := database.GetDB().Where("account_id = ?", accountID).
+		Order("sold_at DESC, id DESC").
+		Find(&sales).Error
+	return sales, err
+}
+
+// FindAll returns the ledger entries matching filter, newest first
+func (r *SaleRepository) FindAll(filter models.SaleFilter) (*models.PaginatedSales, error) {
+	db := database.GetDB().Model(&models.Sale{})
+	if filter.AccountID != 0 {
+		db = db.Where("account_id = ?", filter.AccountID)
+	}
+	if filter.AccountType != "" {
+		db = db.Where("account_type = ?", filter.AccountType)
+	}
+	if filter.Channel != "" {
+		db = db.Where("channel = ?", filter.Channel)
+	}
+	if filter.Buyer != "" {
+		db = db.Where("buyer LIKE ?", "%"+filter.Buyer+"%")
+	}
+	if filter.From != nil {
+		db = db.Where("sold_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("sold_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	var sales []models.Sale
+	err := db.Order("sold_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&sales).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / filter.PageSize
+	if int(total)%filter.PageSize > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedSales{
+		Data:       sales,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// FindBetween returns the ledger entries made in [from, to), oldest first
+func (r *SaleRepository) FindBetween(from, to time.Time) ([]models.Sale, error) {
+	var sales []models.Sale
+	err := database.GetDB().Where("sold_at >= ? AND sold_at < ?", from, to).
+		Order("sold_at, id").
+		Find(&sales).Error
+	return sales, err
+}
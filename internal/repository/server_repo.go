@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"account-manager/internal/database"
 	"account-manager/internal/models"
 )
@@ -26,3 +28,13 @@ func (r *ServerRepository) UpdateConfig(config *models.ServerConfig) error {
 	}
 	return database.DB.Save(config).Error
 }
+
+// UpdateServiceStatus saves the service status, and the deployment time when
+// set, without writing back the encrypted credentials of the config
+func (r *ServerRepository) UpdateServiceStatus(id uint, status string, deployedAt *time.Time) error {
+	updates := map[string]interface{}{"service_status": status}
+	if deployedAt != nil {
+		updates["last_deployed_at"] = deployedAt
+	}
+	return database.DB.Model(&models.ServerConfig{}).Where("id = ?", id).Updates(updates).Error
+}
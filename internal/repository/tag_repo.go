@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository struct{}
+
+func NewTagRepository() *TagRepository {
+	return &TagRepository{}
+}
+
+// FindAll returns all tags by name
+func (r *TagRepository) FindAll() ([]models.Tag, error) {
+	var tags []models.Tag
+	err := database.GetDB().Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (r *TagRepository) FindByID(id uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := database.GetDB().First(&tag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) FindByName(name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := database.GetDB().Where("name = ?", name).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FindByIDs returns the tags with the given ids; unknown ids are skipped
+func (r *TagRepository) FindByIDs(ids []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	err := database.GetDB().Where("id IN ?", ids).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (r *TagRepository) Create(tag *models.Tag) error {
+	return database.GetDB().Create(tag).Error
+}
+
+func (r *TagRepository) Update(tag *models.Tag) error {
+	return database.GetDB().Save(tag).Error
+}
+
+// Delete removes a tag from every account and then the tag itself
+func (r *TagRepository) Delete(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&models.AccountTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tag{}, id).Error
+	})
+}
+
+// AddToAccounts tags every account with every tag. Tags an account already
+// has are left alone. It returns the number of tags added.
+func (r *TagRepository) AddToAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	accountIDs, tagIDs = uniqueIDs(accountIDs), uniqueIDs(tagIDs)
+	if len(accountIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+
+	var added int64
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Only existing accounts are tagged
+		var existing []uint
+		if err := tx.Model(&models.Account{}).Where("id IN ?", accountIDs).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		rows := make([]models.AccountTag, 0, len(existing)*len(tagIDs))
+		for _, accountID := range existing {
+			for _, tagID := range tagIDs {
+				rows = append(rows, models.AccountTag{AccountID: accountID, TagID: tagID, CreatedAt: now})
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 500)
+		added = result.RowsAffected
+		return result.Error
+	})
+	return added, err
+}
+
+// RemoveFromAccounts removes the tags from the accounts and returns the
+// number of tags removed
+func (r *TagRepository) RemoveFromAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	if len(accountIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+	result := database.GetDB().Where("account_id IN ? AND tag_id IN ?", accountIDs, tagIDs).Delete(&models.AccountTag{})
+	return result.RowsAffected, result.Error
+}
+
+// SetAccountTags replaces the tags of one account
+func (r *TagRepository) SetAccountTags(accountID uint, tagIDs []uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountID).Delete(&models.AccountTag{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, tagID := range uniqueIDs(tagIDs) {
+			if err := tx.Create(&models.AccountTag{AccountID: accountID, TagID: tagID, CreatedAt: now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// uniqueIDs drops repeated ids, keeping the first occurrence
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
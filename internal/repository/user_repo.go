@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+)
+
+type UserRepository struct{}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{}
+}
+
+func (r *UserRepository) Create(user *models.User) error {
+	return database.GetDB().Create(user).Error
+}
+
+func (r *UserRepository) Update(user *models.User) error {
+	return database.GetDB().Save(user).Error
+}
+
+func (r *UserRepository) Delete(id uint) error {
+	return database.GetDB().Delete(&models.User{}, id).Error
+}
+
+func (r *UserRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	err := database.GetDB().First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := database.GetDB().Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) FindAll() ([]models.User, error) {
+	var users []models.User
+	err := database.GetDB().Order("username ASC").Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) Count() (int64, error) {
+	var count int64
+	err := database.GetDB().Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+// CountActiveAdmins counts enabled administrators
+func (r *UserRepository) CountActiveAdmins() (int64, error) {
+	var count int64
+	err := database.GetDB().Model(&models.User{}).
+		Where("role = ? AND disabled = ?", models.RoleAdmin, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *UserRepository) UpdateLastLogin(id uint) error {
+	return database.GetDB().Model(&models.User{}).Where("id = ?", id).Update("last_login_at", time.Now()).Error
+}
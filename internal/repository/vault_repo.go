@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"account-manager/internal/database"
+	"account-manager/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type VaultRepository struct{}
+
+func NewVaultRepository() *VaultRepository {
+	return &VaultRepository{}
+}
+
+// GetConfig returns the vault key derivation settings
+func (r *VaultRepository) GetConfig() (*models.VaultConfig, error) {
+	var config models.VaultConfig
+	err := database.GetDB().First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SaveConfig creates or updates the vault key derivation settings
+func (r *VaultRepository) SaveConfig(config *models.VaultConfig) error {
+	return database.GetDB().Save(config).Error
+}
+
+// Exists reports whether the vault has been set up
+func (r *VaultRepository) Exists() (bool, error) {
+	var count int64
+	err := database.GetDB().Model(&models.VaultConfig{}).Count(&count).Error
+	return count > 0, err
+}
+
+// Transaction runs fn inside a single database transaction
+func (r *VaultRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return database.GetDB().Transaction(fn)
+}
@@ -5,26 +5,31 @@ import (
 	"strings"
 	"time"
 
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
 	"account-manager/internal/models"
 	"account-manager/internal/repository"
 	"account-manager/internal/service"
+	"account-manager/internal/utils"
 
 	"github.com/robfig/cron/v3"
 )
 
 type Scheduler struct {
-	cron         *cron.Cron
-	accountRepo  *repository.AccountRepository
-	emailRepo    *repository.EmailRepository
-	emailService *service.EmailService
+	cron           *cron.Cron
+	accountRepo    *repository.AccountRepository
+	emailRepo      *repository.EmailRepository
+	emailService   *service.EmailService
+	accountService *service.AccountService
 }
 
 func NewScheduler() *Scheduler {
 	return &Scheduler{
-		cron:         cron.New(),
-		accountRepo:  repository.NewAccountRepository(),
-		emailRepo:    repository.NewEmailRepository(),
-		emailService: service.NewEmailService(),
+		cron:           cron.New(),
+		accountRepo:    repository.NewAccountRepository(),
+		emailRepo:      repository.NewEmailRepository(),
+		emailService:   service.NewEmailService(),
+		accountService: service.NewAccountService(),
 	}
 }
 
@@ -32,6 +37,13 @@ func (s *Scheduler) Start() {
 	// Run expiry check every hour
 	s.cron.AddFunc("0 * * * *", s.CheckExpiringAccounts)
 
+	// Purge old accounts from the trash every hour, since the app is not
+	// running at any fixed time of day
+	s.cron.AddFunc("30 * * * *", s.PurgeTrash)
+
+	// Free accounts held by reservations that were not confirmed in time
+	s.cron.AddFunc("*/5 * * * *", s.ExpireReservations)
+
 	s.cron.Start()
 }
 
@@ -51,6 +63,14 @@ func (s *Scheduler) CheckExpiringAccounts() {
 		return
 	}
 
+	// The SMTP password is encrypted and never kept in memory while the vault
+	// is locked. Reminders stay unsent until the run started at the next
+	// unlock, see App.startup.
+	if !utils.HasEncryptionKey() {
+		logger.WithField("accounts", len(accounts)).Info("Vault locked, expiry reminder deferred until unlock")
+		return
+	}
+
 	// Send reminder email
 	err = s.sendExpiryReminder(accounts, daysBefore)
 	if err != nil {
@@ -111,6 +131,21 @@ func (s *Scheduler) sendExpiryReminder(accounts []models.Account, daysBefore int
 	return s.emailService.SendEmail(subject, content)
 }
 
+// PurgeTrash removes accounts that have been in the trash longer than the
+// configured retention
+func (s *Scheduler) PurgeTrash() {
+	if _, err := s.accountService.PurgeExpiredTrash(); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to purge trash")
+	}
+}
+
+// ExpireReservations releases reservations whose hold has ended
+func (s *Scheduler) ExpireReservations() {
+	if _, err := s.accountService.ExpireReservations(); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to expire reservations")
+	}
+}
+
 // ManualCheck allows manual triggering of expiry check
 func (s *Scheduler) ManualCheck() (int, error) {
 	sysConfig, err := s.emailRepo.GetSystemConfig()
@@ -128,6 +163,10 @@ func (s *Scheduler) ManualCheck() (int, error) {
 		return 0, nil
 	}
 
+	if !utils.HasEncryptionKey() {
+		return 0, apperrors.NewVaultLocked()
+	}
+
 	err = s.sendExpiryReminder(accounts, daysBefore)
 	if err != nil {
 		return 0, err
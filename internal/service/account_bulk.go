@@ -0,0 +1,332 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// bulkChange is what a bulk operation does to one account, on top of the
+// edits made to the account itself
+type bulkChange struct {
+	status      *models.AccountStatusConfig // Set when the status changes
+	note        string                      // Stored with the status change
+	renewal     *models.Renewal             // Set when the expiry was extended
+	sales       []models.Sale               // Ledger entries when the sold state changed
+	clearFields []uint
+	delete      bool
+}
+
+// bulkApply edits one account of a bulk operation. values are its custom
+// field values. An error skips the account.
+type bulkApply func(account *models.Account, values []models.AccountFieldValue, now time.Time) (bulkChange, error)
+
+// BulkChangeStatus moves the selected accounts to a status. Accounts the
+// workflow does not allow to move, or held by a reservation, are skipped.
+func (s *AccountService) BulkChangeStatus(selection models.BulkSelection, status string, note string) (*models.BulkResult, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if _, err := findAccountStatus(s.statusRepo, status); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"status": status}
+	checkHold := s.holdCheck(time.Now())
+	return s.runBulk(models.BulkActionStatus, selection, details, func(account *models.Account, _ []models.AccountFieldValue, now time.Time) (bulkChange, error) {
+		if err := checkHold(account); err != nil {
+			return bulkChange{}, err
+		}
+		if account.Status == status {
+			return bulkChange{}, nil
+		}
+		target, err := checkTransition(s.statusRepo, account.Status, status)
+		if err != nil {
+			return bulkChange{}, err
+		}
+		wasSold := account.IsSold
+		applyStatus(account, target, now)
+		sales, err := s.saleEntries(account, wasSold, nil, now)
+		if err != nil {
+			return bulkChange{}, err
+		}
+		return bulkChange{status: target, note: note, sales: sales}, nil
+	})
+}
+
+// BulkMarkAsSold moves the selected accounts to the first status that counts as sold
+func (s *AccountService) BulkMarkAsSold(selection models.BulkSelection) (*models.BulkResult, error) {
+	target, err := s.soldTarget(true)
+	if err != nil {
+		return nil, err
+	}
+	return s.BulkChangeStatus(selection, target.Name, "")
+}
+
+// BulkMarkAsUnsold moves the selected accounts back to the initial status
+func (s *AccountService) BulkMarkAsUnsold(selection models.BulkSelection) (*models.BulkResult, error) {
+	target, err := s.soldTarget(false)
+	if err != nil {
+		return nil, err
+	}
+	return s.BulkChangeStatus(selection, target.Name, "")
+}
+
+// BulkExtendExpiry renews the selected accounts for days, like ExtendExpiry,
+// and records a renewal for each. Accounts of types that never expire are
+// skipped.
+func (s *AccountService) BulkExtendExpiry(selection models.BulkSelection, days int) (*models.BulkResult, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if days <= 0 || days > maxRenewalDays {
+		return nil, apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("延长天数应在 1 到 %d 之间", maxRenewalDays))
+	}
+
+	types := make(map[models.AccountType]*models.AccountTypeConfig)
+	details := map[string]interface{}{"days": days}
+	return s.runBulk(models.BulkActionExtendExpiry, selection, details, func(account *models.Account, _ []models.AccountFieldValue, now time.Time) (bulkChange, error) {
+		typeConfig, ok := types[account.AccountType]
+		if !ok {
+			var err error
+			if typeConfig, err = findAccountType(s.typeRepo, string(account.AccountType)); err != nil {
+				return bulkChange{}, err
+			}
+			types[account.AccountType] = typeConfig
+		}
+		renewal, err := s.renew(account, typeConfig, days, nil, now)
+		if err != nil {
+			return bulkChange{}, err
+		}
+		return bulkChange{renewal: renewal}, nil
+	})
+}
+
+// BulkChangeType moves the selected accounts to another type. Custom field
+// values the new type lacks are cleared; accounts missing a value the new
+// type requires are skipped.
+func (s *AccountService) BulkChangeType(selection models.BulkSelection, accountType string) (*models.BulkResult, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	typeConfig, err := findAccountType(s.typeRepo, accountType)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"type": typeConfig.Name}
+	checkHold := s.holdCheck(time.Now())
+	return s.runBulk(models.BulkActionChangeType, selection, details, func(account *models.Account, values []models.AccountFieldValue, _ time.Time) (bulkChange, error) {
+		if err := checkHold(account); err != nil {
+			return bulkChange{}, err
+		}
+		if account.AccountType == typeConfig.Name {
+			return bulkChange{}, nil
+		}
+		_, clear, err := prepareFieldValues(s.fieldRepo, typeConfig.Name, nil, values, true)
+		if err != nil {
+			return bulkChange{}, err
+		}
+		account.AccountType = typeConfig.Name
+		if !typeConfig.Expires {
+			account.ExpireAt = nil
+		} else if account.ExpireAt == nil {
+			account.ExpireAt = defaultExpiry(s.emailRepo, typeConfig)
+		}
+		return bulkChange{clearFields: clear}, nil
+	})
+}
+
+// BulkDelete moves the selected accounts to the trash
+func (s *AccountService) BulkDelete(selection models.BulkSelection) (*models.BulkResult, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	checkHold := s.holdCheck(time.Now())
+	return s.runBulk(models.BulkActionDelete, selection, nil, func(account *models.Account, _ []models.AccountFieldValue, _ time.Time) (bulkChange, error) {
+		if err := checkHold(account); err != nil {
+			return bulkChange{}, err
+		}
+		return bulkChange{delete: true}, nil
+	})
+}
+
+// runBulk applies a change to every selected account and writes the
+// accounts that passed in one transaction. The stats cache is invalidated
+// once and the operation is audited as a single entry.
+func (s *AccountService) runBulk(action string, selection models.BulkSelection, details map[string]interface{}, apply bulkApply) (*models.BulkResult, error) {
+	// Changed accounts are saved back whole, secrets included
+	release := utils.HoldKey()
+	defer release()
+
+	ids, err := s.selectAccounts(selection)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Account, len(accounts))
+	for i := range accounts {
+		byID[accounts[i].ID] = &accounts[i]
+	}
+
+	values, err := s.fieldRepo.FindValues(ids)
+	if err != nil {
+		return nil, err
+	}
+	valuesByAccount := make(map[uint][]models.AccountFieldValue)
+	for _, v := range values {
+		valuesByAccount[v.AccountID] = append(valuesByAccount[v.AccountID], v)
+	}
+	names, err := s.customFieldNames()
+	if err != nil {
+		return nil, err
+	}
+
+	actor := currentActor()
+	now := time.Now()
+	result := &models.BulkResult{Action: action, Items: make([]models.BulkItemResult, 0, len(ids))}
+	write := &repository.BulkWrite{ClearFields: make(map[uint][]uint), DeletedBy: actor}
+	var changedIDs, failedIDs []uint
+
+	for _, id := range ids {
+		item := models.BulkItemResult{ID: id}
+		account := byID[id]
+		if account == nil {
+			item.Error = apperrors.NewAccountNotFound().Error()
+			result.Items = append(result.Items, item)
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+		item.Account = account.Account
+
+		fromStatus := account.Status
+		before := snapshotAccount(account, valuesByAccount[id], names)
+		change, err := apply(account, valuesByAccount[id], now)
+		if err != nil {
+			item.Error = err.Error()
+			result.Items = append(result.Items, item)
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+		item.Success = true
+
+		if change.delete {
+			write.Deleted = append(write.Deleted, id)
+			item.Changed = true
+		} else {
+			after := snapshotAccount(account, remainingValues(valuesByAccount[id], change.clearFields), names)
+			if changes := diffSnapshots(before, after); len(changes) > 0 {
+				write.Updated = append(write.Updated, account)
+				if len(change.clearFields) > 0 {
+					write.ClearFields[id] = change.clearFields
+				}
+				write.ChangeSets = append(write.ChangeSets, models.AccountChangeSet{
+					AccountID: id,
+					ChangedBy: actor,
+					ChangedAt: now,
+					Source:    models.ChangeSourceBulk,
+					Changes:   changes,
+				})
+				item.Changed = true
+			}
+			write.Sales = append(write.Sales, change.sales...)
+			if change.renewal != nil {
+				write.Renewals = append(write.Renewals, *change.renewal)
+			}
+			if change.status != nil {
+				write.StatusChanges = append(write.StatusChanges, models.AccountStatusChange{
+					AccountID:  id,
+					FromStatus: fromStatus,
+					ToStatus:   change.status.Name,
+					ChangedBy:  actor,
+					ChangedAt:  now,
+					Note:       change.note,
+				})
+			}
+		}
+		if item.Changed {
+			changedIDs = append(changedIDs, id)
+		}
+		result.Items = append(result.Items, item)
+	}
+	result.Succeeded = len(ids) - len(failedIDs)
+	result.Failed = len(failedIDs)
+
+	if len(write.Updated) > 0 || len(write.Deleted) > 0 {
+		if err := s.repo.ApplyBulk(write); err != nil {
+			return nil, err
+		}
+		cache.InvalidateStats()
+	}
+	if len(write.Deleted) > 0 {
+		for _, id := range write.Deleted {
+			secretIndex.Remove(id)
+		}
+		s.refreshReusedFlags()
+	}
+
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["accounts"] = changedIDs
+	details["unchanged"] = result.Succeeded - len(changedIDs)
+	if len(failedIDs) > 0 {
+		details["failed"] = failedIDs
+	}
+	s.auditLog.LogAccountBulk(actor, action, details)
+	return result, nil
+}
+
+// selectAccounts resolves a selection to account ids without duplicates
+func (s *AccountService) selectAccounts(selection models.BulkSelection) ([]uint, error) {
+	ids := selection.IDs
+	if len(ids) == 0 {
+		if selection.Filter == nil {
+			return nil, apperrors.New(apperrors.ErrCodeInvalidInput, "请选择要操作的账号")
+		}
+		filter := *selection.Filter
+		if err := prepareFilter(&filter); err != nil {
+			return nil, err
+		}
+		var err error
+		if ids, err = s.repo.FindIDs(filter); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// remainingValues returns the custom field values left after clearing fieldIDs
+func remainingValues(values []models.AccountFieldValue, fieldIDs []uint) []models.AccountFieldValue {
+	if len(fieldIDs) == 0 {
+		return values
+	}
+	cleared := make(map[uint]bool, len(fieldIDs))
+	for _, id := range fieldIDs {
+		cleared[id] = true
+	}
+	var remaining []models.AccountFieldValue
+	for _, v := range values {
+		if !cleared[v.FieldID] {
+			remaining = append(remaining, v)
+		}
+	}
+	return remaining
+}
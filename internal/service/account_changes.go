@@ -0,0 +1,326 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/utils"
+)
+
+// accountSnapshot is the state of the tracked fields of an account, see
+// models.AccountFieldChange. Secret fields are kept encrypted.
+type accountSnapshot struct {
+	plain   map[string]string
+	secrets map[string]string
+}
+
+// snapshotAccount captures an account and its custom field values. names maps
+// custom field ids to their names.
+func snapshotAccount(account *models.Account, values []models.AccountFieldValue, names map[uint]string) accountSnapshot {
+	snapshot := accountSnapshot{
+		plain: map[string]string{
+			models.ChangeFieldAccount:     account.Account,
+			models.ChangeFieldAccountType: string(account.AccountType),
+			models.ChangeFieldStatus:      account.Status,
+			models.ChangeFieldExpireAt:    formatExpiry(account.ExpireAt),
+		},
+		secrets: map[string]string{
+			models.ChangeFieldPassword: account.Password,
+			models.ChangeFieldNotes:    account.Notes,
+			models.ChangeFieldTOTP:     account.TOTP,
+		},
+	}
+	for _, v := range values {
+		name, ok := names[v.FieldID]
+		if !ok {
+			continue
+		}
+		if v.SecretValue != "" {
+			snapshot.secrets[models.ChangeFieldCustomPrefix+name] = v.SecretValue
+		} else {
+			snapshot.plain[models.ChangeFieldCustomPrefix+name] = v.Value
+		}
+	}
+	return snapshot
+}
+
+// diffSnapshots lists the fields that differ between two snapshots, in name
+// order. Secrets are compared by plaintext since every save re-encrypts them.
+func diffSnapshots(before, after accountSnapshot) []models.AccountFieldChange {
+	var changes []models.AccountFieldChange
+	for _, field := range snapshotFields(before, after) {
+		oldSecret, oldIsSecret := before.secrets[field]
+		newSecret, newIsSecret := after.secrets[field]
+		if oldIsSecret || newIsSecret {
+			if oldSecret == newSecret {
+				continue
+			}
+			oldPlain, err1 := decryptField(oldSecret)
+			newPlain, err2 := decryptField(newSecret)
+			if err1 == nil && err2 == nil && oldPlain == newPlain {
+				continue
+			}
+			changes = append(changes, models.AccountFieldChange{
+				Field:     field,
+				OldValue:  secretChangeMarker(oldSecret),
+				NewValue:  secretChangeMarker(newSecret),
+				Secret:    true,
+				OldSecret: oldSecret,
+				NewSecret: newSecret,
+			})
+			continue
+		}
+		if before.plain[field] != after.plain[field] {
+			changes = append(changes, models.AccountFieldChange{
+				Field:    field,
+				OldValue: before.plain[field],
+				NewValue: after.plain[field],
+			})
+		}
+	}
+	return changes
+}
+
+// formatExpiry formats an expiry date as tracked by AccountFieldChange
+func formatExpiry(expireAt *time.Time) string {
+	if expireAt == nil {
+		return ""
+	}
+	return expireAt.Format(time.RFC3339)
+}
+
+// secretChangeMarker is shown instead of a secret value in the history
+func secretChangeMarker(encrypted string) string {
+	if encrypted == "" {
+		return ""
+	}
+	return "changed"
+}
+
+func snapshotFields(snapshots ...accountSnapshot) []string {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, snapshot := range snapshots {
+		for _, m := range []map[string]string{snapshot.plain, snapshot.secrets} {
+			for field := range m {
+				if !seen[field] {
+					seen[field] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// customFieldNames maps custom field ids to their names
+func (s *AccountService) customFieldNames() (map[uint]string, error) {
+	fields, err := s.fieldRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(fields))
+	for _, field := range fields {
+		names[field.ID] = field.Name
+	}
+	return names, nil
+}
+
+// snapshot loads the current state of an account for change tracking
+func (s *AccountService) snapshot(account *models.Account) (accountSnapshot, error) {
+	values, err := s.fieldRepo.FindValues([]uint{account.ID})
+	if err != nil {
+		return accountSnapshot{}, err
+	}
+	names, err := s.customFieldNames()
+	if err != nil {
+		return accountSnapshot{}, err
+	}
+	return snapshotAccount(account, values, names), nil
+}
+
+// newChangeSet returns the differences between two snapshots of an account
+// as a change set to store, nil when nothing changed
+func newChangeSet(accountID uint, before, after accountSnapshot, source string, revertOf *uint) *models.AccountChangeSet {
+	changes := diffSnapshots(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return &models.AccountChangeSet{
+		AccountID: accountID,
+		ChangedBy: currentActor(),
+		ChangedAt: time.Now(),
+		Source:    source,
+		RevertOf:  revertOf,
+		Changes:   changes,
+	}
+}
+
+// changedFields returns the names of the fields changed by a change set
+func changedFields(set *models.AccountChangeSet) []string {
+	if set == nil {
+		return nil
+	}
+	fields := make([]string, len(set.Changes))
+	for i, c := range set.Changes {
+		fields[i] = c.Field
+	}
+	return fields
+}
+
+// recordChangeSet stores the differences between two snapshots of an account
+// and returns the changed field names. Nothing is stored when nothing
+// changed. A failure only leaves a gap in the history.
+func (s *AccountService) recordChangeSet(accountID uint, before, after accountSnapshot, source string, revertOf *uint) []string {
+	set := newChangeSet(accountID, before, after, source, revertOf)
+	if set == nil {
+		return nil
+	}
+	if err := s.changeRepo.Create(set); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to record account changes")
+	}
+	return changedFields(set)
+}
+
+// GetChangeHistory returns the change sets of an account, newest first
+func (s *AccountService) GetChangeHistory(accountID uint) ([]models.AccountChangeSet, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.changeRepo.FindByAccount(accountID)
+}
+
+// RevertChangeSet puts the fields changed by a change set back to their
+// previous values. It fails when any of them was changed again since, and is
+// itself recorded as a new change set.
+func (s *AccountService) RevertChangeSet(changeSetID uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
+	set, err := s.changeRepo.FindByID(changeSetID)
+	if err != nil {
+		return apperrors.NewChangeSetNotFound(changeSetID)
+	}
+	account, err := s.repo.FindByID(set.AccountID)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	current, err := s.snapshot(account)
+	if err != nil {
+		return err
+	}
+
+	// Only values that are still what the change set left can be reverted
+	var conflicts []string
+	for _, c := range set.Changes {
+		if !snapshotHas(current, c) {
+			conflicts = append(conflicts, c.Field)
+		}
+	}
+	if len(conflicts) > 0 {
+		return apperrors.NewRevertConflict(strings.Join(conflicts, ", "))
+	}
+
+	name := account.Account
+	accountType := string(account.AccountType)
+	expireAt := account.ExpireAt
+	password := ""
+	notes, err := decryptField(account.Notes)
+	if err != nil {
+		return apperrors.NewDecryptionFailed(err)
+	}
+	status := ""
+	var totp *utils.TOTPKey
+	revertTOTP := false
+	fields := make(map[string]string)
+
+	for _, c := range set.Changes {
+		old := c.OldValue
+		if c.Secret {
+			if old, err = decryptField(c.OldSecret); err != nil {
+				return apperrors.NewDecryptionFailed(err)
+			}
+		}
+		switch {
+		case c.Field == models.ChangeFieldAccount:
+			name = old
+		case c.Field == models.ChangeFieldAccountType:
+			accountType = old
+		case c.Field == models.ChangeFieldStatus:
+			status = old
+		case c.Field == models.ChangeFieldExpireAt:
+			if t, err := time.Parse(time.RFC3339, old); err == nil {
+				expireAt = &t
+			}
+		case c.Field == models.ChangeFieldPassword:
+			password = old
+		case c.Field == models.ChangeFieldNotes:
+			notes = old
+		case c.Field == models.ChangeFieldTOTP:
+			revertTOTP = true
+			if old != "" {
+				if totp, err = utils.ParseTOTP(old); err != nil {
+					return apperrors.NewInvalidTOTP(err)
+				}
+			}
+		case strings.HasPrefix(c.Field, models.ChangeFieldCustomPrefix):
+			fields[strings.TrimPrefix(c.Field, models.ChangeFieldCustomPrefix)] = old
+		}
+	}
+
+	// Check the workflow first so the revert does not stop halfway
+	if status != "" && status != account.Status {
+		if _, err := checkTransition(s.statusRepo, account.Status, status); err != nil {
+			return err
+		}
+	}
+
+	// Values of fields the restored type lacks are cleared by the type change
+	typeFields, err := s.fieldRepo.FindByType(models.AccountType(accountType))
+	if err != nil {
+		return err
+	}
+	inType := make(map[string]bool, len(typeFields))
+	for _, field := range typeFields {
+		inType[field.Name] = true
+	}
+	for name := range fields {
+		if !inType[name] {
+			delete(fields, name)
+		}
+	}
+
+	if err := s.updateAccount(account.ID, name, password, accountType, expireAt, notes, account.IsSold, fields, models.ChangeSourceRevert, &set.ID); err != nil {
+		return err
+	}
+	if revertTOTP {
+		if err := s.writeTOTP(account.ID, totp, models.ChangeSourceRevert, &set.ID); err != nil {
+			return err
+		}
+	}
+	if status != "" && status != account.Status {
+		_, err := s.changeStatus(account.ID, status, fmt.Sprintf("撤销变更 #%d", set.ID), models.ChangeSourceRevert, &set.ID, nil)
+		return err
+	}
+	return nil
+}
+
+// snapshotHas reports whether a field still has the value a change left it with
+func snapshotHas(snapshot accountSnapshot, c models.AccountFieldChange) bool {
+	if c.Secret {
+		current, err1 := decryptField(snapshot.secrets[c.Field])
+		after, err2 := decryptField(c.NewSecret)
+		return err1 == nil && err2 == nil && current == after
+	}
+	return snapshot.plain[c.Field] == c.NewValue
+}
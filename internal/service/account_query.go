@@ -0,0 +1,370 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"account-manager/internal/database"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+)
+
+// Account searches accept a small query syntax. A query is a list of terms
+// separated by spaces, all of which must match:
+//
+//	type:PLUS sold:false expires:<7d created:2026-01..2026-03 notes:"recovery"
+//
+//	account:TEXT        account name contains TEXT
+//	notes:TEXT          notes contain TEXT, matched in the secret search index
+//	type:NAME           account type
+//	status:NAME         account status
+//	tag:NAME            has the tag
+//	sold:true|false     sold or not
+//	expired:true|false  expiry date has passed or not
+//	expires:RANGE       expiry date in RANGE; expires:none for no expiry
+//	created:RANGE       creation date in RANGE
+//
+// A RANGE is a date (2026, 2026-03 or 2026-03-15), which covers the whole
+// year, month or day, optionally prefixed with <, <=, > or >=, or two dates
+// joined by ".." where either side may be left out. A relative time such as
+// 7d, 2w, 3m or 1y must come with < or >: expires:<7d is not expired yet and
+// expires within 7 days, created:<7d was created less than 7 days ago.
+//
+// Values with spaces are quoted ("..." with \" for a quote) and a leading "-"
+// negates a term. Words without a key are searched like a plain search.
+// A search that uses none of the keys above is a plain search as a whole.
+
+var queryFields = map[string]bool{
+	models.QueryFieldAccount: true,
+	models.QueryFieldNotes:   true,
+	models.QueryFieldType:    true,
+	models.QueryFieldStatus:  true,
+	models.QueryFieldSold:    true,
+	models.QueryFieldExpired: true,
+	models.QueryFieldTag:     true,
+	models.QueryFieldExpires: true,
+	models.QueryFieldCreated: true,
+}
+
+var relativeTimePattern = regexp.MustCompile(`^(\d{1,5})([dwmy])$`)
+
+// queryToken is one term of a query before its value is interpreted
+type queryToken struct {
+	pos    int // Position of the term in characters, from 1
+	negate bool
+	key    string // Empty for a bare word
+	value  string
+}
+
+// parseAccountQuery parses a search written in the query syntax. structured
+// is false when search uses no known key and should be matched as plain text.
+func parseAccountQuery(search string, now time.Time) (terms []models.QueryTerm, structured bool, err error) {
+	tokens, err := tokenizeQuery(search)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, token := range tokens {
+		if queryFields[token.key] {
+			structured = true
+			break
+		}
+	}
+	if !structured {
+		return nil, false, nil
+	}
+
+	for _, token := range tokens {
+		term, err := parseQueryTerm(token, now)
+		if err != nil {
+			return nil, true, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, true, nil
+}
+
+// tokenizeQuery splits a query into terms
+func tokenizeQuery(search string) ([]queryToken, error) {
+	runes := []rune(search)
+	var tokens []queryToken
+	i := 0
+	for {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return tokens, nil
+		}
+
+		token := queryToken{pos: i + 1}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.negate = true
+			i++
+		}
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || runes[j] == '_') {
+			j++
+		}
+		if j > i && j < len(runes) && runes[j] == ':' {
+			token.key = strings.ToLower(string(runes[i:j]))
+			i = j + 1
+		}
+
+		value, next, err := readQueryValue(runes, i)
+		if err != nil {
+			return nil, err
+		}
+		token.value = value
+		i = next
+		tokens = append(tokens, token)
+	}
+}
+
+// readQueryValue reads a quoted or bare value starting at runes[i] and
+// returns it with the position after it
+func readQueryValue(runes []rune, i int) (string, int, error) {
+	if i >= len(runes) || runes[i] != '"' {
+		j := i
+		for j < len(runes) && !unicode.IsSpace(runes[j]) {
+			j++
+		}
+		return string(runes[i:j]), j, nil
+	}
+
+	start := i
+	var value strings.Builder
+	for i++; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+			i++
+			value.WriteRune(runes[i])
+		case runes[i] == '"':
+			if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+				return "", 0, apperrors.NewInvalidQuery(i+2, "引号后应为空格")
+			}
+			return value.String(), i + 1, nil
+		default:
+			value.WriteRune(runes[i])
+		}
+	}
+	return "", 0, apperrors.NewInvalidQuery(start+1, "引号未闭合")
+}
+
+// parseQueryTerm interprets the value of a term
+func parseQueryTerm(token queryToken, now time.Time) (models.QueryTerm, error) {
+	term := models.QueryTerm{Field: token.key, Negate: token.negate, Text: token.value}
+	if token.key == "" {
+		term.Field = models.QueryFieldText
+	} else if !queryFields[token.key] {
+		return term, apperrors.NewInvalidQuery(token.pos, fmt.Sprintf("未知的搜索字段 %s", token.key))
+	}
+	if token.value == "" {
+		return term, apperrors.NewInvalidQuery(token.pos, fmt.Sprintf("%s 缺少值", term.Field))
+	}
+
+	var err error
+	switch term.Field {
+	case models.QueryFieldSold, models.QueryFieldExpired:
+		term.Bool, err = parseQueryBool(token.value)
+	case models.QueryFieldExpires:
+		if v := strings.ToLower(token.value); v == "none" || v == "never" {
+			term.Never = true
+			return term, nil
+		}
+		term.From, term.To, err = parseQueryRange(token.value, now, true)
+	case models.QueryFieldCreated:
+		term.From, term.To, err = parseQueryRange(token.value, now, false)
+	}
+	if err != nil {
+		return term, apperrors.NewInvalidQuery(token.pos, fmt.Sprintf("%s 的值 %s 无效，%s", term.Field, token.value, err.Error()))
+	}
+	return term, nil
+}
+
+func parseQueryBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "1":
+		return true, nil
+	case "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("应为 true 或 false")
+}
+
+// parseQueryRange parses a RANGE of the query syntax into [from, to).
+// future tells whether relative times count forward from now, as for expiry
+// dates, or backward, as for creation dates.
+func parseQueryRange(value string, now time.Time, future bool) (*time.Time, *time.Time, error) {
+	if before, after, ok := strings.Cut(value, ".."); ok {
+		if before == "" && after == "" {
+			return nil, nil, fmt.Errorf("范围两端不能都为空")
+		}
+		var from, to *time.Time
+		for _, side := range []string{before, after} {
+			if side == "" {
+				continue
+			}
+			start, end, err := parseQueryTime(side, now, future)
+			if err != nil {
+				return nil, nil, err
+			}
+			if from == nil || start.Before(*from) {
+				from = &start
+			}
+			if to == nil || end.After(*to) {
+				to = &end
+			}
+		}
+		if before == "" {
+			from = nil
+		}
+		if after == "" {
+			to = nil
+		}
+		return from, to, nil
+	}
+
+	op := ""
+	for _, prefix := range []string{"<=", ">=", "<", ">"} {
+		if strings.HasPrefix(value, prefix) {
+			op, value = prefix, value[len(prefix):]
+			break
+		}
+	}
+	start, end, err := parseQueryTime(value, now, future)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if relativeTimePattern.MatchString(value) {
+		switch {
+		case op == "":
+			return nil, nil, fmt.Errorf("相对时间需要 < 或 >，例如 <7d")
+		case future && op[0] == '<':
+			return &now, &start, nil
+		case future:
+			return &start, nil, nil
+		case op[0] == '<': // Younger than
+			return &start, nil, nil
+		default:
+			return nil, &start, nil
+		}
+	}
+
+	switch op {
+	case "<":
+		return nil, &start, nil
+	case "<=":
+		return nil, &end, nil
+	case ">":
+		return &end, nil, nil
+	case ">=":
+		return &start, nil, nil
+	}
+	return &start, &end, nil
+}
+
+// parseQueryTime parses a date into the period [start, end) it covers, or a
+// relative time into the instant it denotes
+func parseQueryTime(value string, now time.Time, future bool) (time.Time, time.Time, error) {
+	if m := relativeTimePattern.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if !future {
+			n = -n
+		}
+		var t time.Time
+		switch m[2] {
+		case "d":
+			t = now.AddDate(0, 0, n)
+		case "w":
+			t = now.AddDate(0, 0, 7*n)
+		case "m":
+			t = now.AddDate(0, n, 0)
+		default:
+			t = now.AddDate(n, 0, 0)
+		}
+		return t, t, nil
+	}
+
+	for _, layout := range []struct {
+		format              string
+		years, months, days int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if start, err := time.ParseInLocation(layout.format, value, time.Local); err == nil {
+			return start, start.AddDate(layout.years, layout.months, layout.days), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("日期格式应为 2026、2026-03、2026-03-15 或 7d 这样的相对时间")
+}
+
+// Characters of notes shown on each side of a match in a snippet
+const snippetContext = 20
+
+// searchTexts returns the texts a prepared filter searches for, to highlight
+// them in the results
+func searchTexts(filter models.AccountFilter) []string {
+	if filter.Search != "" {
+		return []string{filter.Search}
+	}
+	var texts []string
+	for _, term := range filter.Terms {
+		if !term.Negate && (term.Field == models.QueryFieldText || term.Field == models.QueryFieldNotes) {
+			texts = append(texts, term.Text)
+		}
+	}
+	return texts
+}
+
+// highlightMatch turns the snippet of a full-text match into HTML, or, when
+// the account matched on its notes instead, takes the snippet from the
+// decrypted notes, which are not in the full-text index
+func highlightMatch(account *models.Account, texts []string) {
+	if account.SearchSnippet != "" {
+		account.SearchSnippet = snippetHTML(account.SearchSnippet)
+		return
+	}
+	notes := []rune(account.Notes)
+	folded := []rune(strings.ToLower(account.Notes))
+	if len(folded) != len(notes) {
+		return
+	}
+	for _, text := range texts {
+		i := strings.Index(string(folded), strings.ToLower(text))
+		if i < 0 {
+			continue
+		}
+		start := utf8.RuneCountInString(string(folded)[:i])
+		end := start + utf8.RuneCountInString(strings.ToLower(text))
+
+		var raw strings.Builder
+		from, to := max(start-snippetContext, 0), min(end+snippetContext, len(notes))
+		if from > 0 {
+			raw.WriteString("…")
+		}
+		raw.WriteString(string(notes[from:start]))
+		raw.WriteString(database.SnippetOpen + string(notes[start:end]) + database.SnippetClose)
+		raw.WriteString(string(notes[end:to]))
+		if to < len(notes) {
+			raw.WriteString("…")
+		}
+		account.SearchSnippet = snippetHTML(raw.String())
+		return
+	}
+}
+
+// snippetHTML escapes a snippet and marks its matches with <mark>
+func snippetHTML(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, database.SnippetOpen, "<mark>")
+	return strings.ReplaceAll(escaped, database.SnippetClose, "</mark>")
+}
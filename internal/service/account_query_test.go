@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+)
+
+func day(year int, month time.Month, d int) *time.Time {
+	t := time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	return &t
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "nil"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func TestParseAccountQueryPlainSearch(t *testing.T) {
+	for _, search := range []string{"", "   ", "hello world", "foo:bar", `"type:PLUS"`, "-hello"} {
+		terms, structured, err := parseAccountQuery(search, time.Now())
+		if err != nil || structured || terms != nil {
+			t.Errorf("parseAccountQuery(%q) = %v, %v, %v, want a plain search", search, terms, structured, err)
+		}
+	}
+}
+
+func TestParseAccountQueryTerms(t *testing.T) {
+	tests := []struct {
+		search string
+		want   []models.QueryTerm
+	}{
+		{
+			search: "type:PLUS",
+			want:   []models.QueryTerm{{Field: models.QueryFieldType, Text: "PLUS"}},
+		},
+		{
+			search: "  TYPE:PLUS   Status:active ",
+			want: []models.QueryTerm{
+				{Field: models.QueryFieldType, Text: "PLUS"},
+				{Field: models.QueryFieldStatus, Text: "active"},
+			},
+		},
+		{
+			search: `tag:vip -sold:true expired:no hello notes:"two words"`,
+			want: []models.QueryTerm{
+				{Field: models.QueryFieldTag, Text: "vip"},
+				{Field: models.QueryFieldSold, Negate: true, Text: "true", Bool: true},
+				{Field: models.QueryFieldExpired, Text: "no"},
+				{Field: models.QueryFieldText, Text: "hello"},
+				{Field: models.QueryFieldNotes, Text: "two words"},
+			},
+		},
+		{
+			search: `account:"say \"hi\" \\ bye" -"bare phrase"`,
+			want: []models.QueryTerm{
+				{Field: models.QueryFieldAccount, Text: `say "hi" \ bye`},
+				{Field: models.QueryFieldText, Negate: true, Text: "bare phrase"},
+			},
+		},
+		{
+			search: "account:a:b - sold:1",
+			want: []models.QueryTerm{
+				{Field: models.QueryFieldAccount, Text: "a:b"},
+				{Field: models.QueryFieldText, Text: "-"},
+				{Field: models.QueryFieldSold, Text: "1", Bool: true},
+			},
+		},
+		{
+			search: "expires:none -expires:NEVER",
+			want: []models.QueryTerm{
+				{Field: models.QueryFieldExpires, Text: "none", Never: true},
+				{Field: models.QueryFieldExpires, Negate: true, Text: "NEVER", Never: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		terms, structured, err := parseAccountQuery(tt.search, time.Now())
+		if err != nil || !structured {
+			t.Errorf("parseAccountQuery(%q) = %v, %v", tt.search, structured, err)
+			continue
+		}
+		if len(terms) != len(tt.want) {
+			t.Errorf("parseAccountQuery(%q) = %+v, want %+v", tt.search, terms, tt.want)
+			continue
+		}
+		for i, term := range terms {
+			want := tt.want[i]
+			if term.Field != want.Field || term.Negate != want.Negate || term.Text != want.Text ||
+				term.Bool != want.Bool || term.Never != want.Never || term.From != nil || term.To != nil {
+				t.Errorf("parseAccountQuery(%q) term %d = %+v, want %+v", tt.search, i, term, want)
+			}
+		}
+	}
+}
+
+func TestParseAccountQueryRanges(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 30, 0, 0, time.Local)
+	at := func(years, months, days int) *time.Time {
+		t := now.AddDate(years, months, days)
+		return &t
+	}
+	tests := []struct {
+		search   string
+		from, to *time.Time
+	}{
+		// Dates cover the whole year, month or day
+		{"created:2026", day(2026, 1, 1), day(2027, 1, 1)},
+		{"created:2026-02", day(2026, 2, 1), day(2026, 3, 1)},
+		{"created:2026-12", day(2026, 12, 1), day(2027, 1, 1)},
+		{"created:2024-02-29", day(2024, 2, 29), day(2024, 3, 1)},
+		{"created:<2026-03", nil, day(2026, 3, 1)},
+		{"created:<=2026-03", nil, day(2026, 4, 1)},
+		{"created:>2026-03", day(2026, 4, 1), nil},
+		{"created:>=2026-03", day(2026, 3, 1), nil},
+		{"expires:<=2026-03-15", nil, day(2026, 3, 16)},
+
+		// Ranges span from the start of one side to the end of the other
+		{"created:2026-01..2026-03", day(2026, 1, 1), day(2026, 4, 1)},
+		{"created:2026-01-10..2026-01-20", day(2026, 1, 10), day(2026, 1, 21)},
+		{"created:2026-03..2026-01", day(2026, 1, 1), day(2026, 4, 1)},
+		{"created:2025..2026-06-30", day(2025, 1, 1), day(2026, 7, 1)},
+		{"created:2026-03..", day(2026, 3, 1), nil},
+		{"created:..2026-03", nil, day(2026, 4, 1)},
+		{"expires:..7d", nil, at(0, 0, 7)},
+
+		// Relative times count forward for expiry and backward for creation
+		{"expires:<7d", &now, at(0, 0, 7)},
+		{"expires:>2w", at(0, 0, 14), nil},
+		{"expires:<=1m", &now, at(0, 1, 0)},
+		{"created:<7d", at(0, 0, -7), nil},
+		{"created:>3m", nil, at(0, -3, 0)},
+		{"created:>=1y", nil, at(-1, 0, 0)},
+	}
+	for _, tt := range tests {
+		terms, _, err := parseAccountQuery(tt.search, now)
+		if err != nil {
+			t.Errorf("parseAccountQuery(%q): %v", tt.search, err)
+			continue
+		}
+		term := terms[0]
+		if formatTime(term.From) != formatTime(tt.from) || formatTime(term.To) != formatTime(tt.to) {
+			t.Errorf("parseAccountQuery(%q) = [%s, %s), want [%s, %s)", tt.search,
+				formatTime(term.From), formatTime(term.To), formatTime(tt.from), formatTime(tt.to))
+		}
+	}
+}
+
+func TestParseAccountQueryErrors(t *testing.T) {
+	tests := []struct {
+		search string
+		pos    int
+	}{
+		{"type:PLUS colour:red", 11},
+		{"type:", 1},
+		{"type:PLUS -notes:", 11},
+		{`notes:"recovery`, 7},
+		{`type:PLUS notes:"a"b`, 20},
+		{"sold:maybe", 1},
+		{"expired:2", 1},
+		{"created:2026-13", 1},
+		{"created:03/2026", 1},
+		{"created:..", 1},
+		{"created:2026..x", 1},
+		{"expires:7d", 1},
+		{"type:PLUS created:7d", 11},
+		{"created:<123456d", 1},
+	}
+	for _, tt := range tests {
+		terms, _, err := parseAccountQuery(tt.search, time.Now())
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != apperrors.ErrCodeInvalidQuery {
+			t.Errorf("parseAccountQuery(%q) = %+v, %v, want an invalid query error", tt.search, terms, err)
+			continue
+		}
+		if want := fmt.Sprintf("第 %d 个字符", tt.pos); !strings.Contains(appErr.Message, want) {
+			t.Errorf("parseAccountQuery(%q) error = %q, want position %d", tt.search, appErr.Message, tt.pos)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/utils"
+)
+
+const (
+	maxRenewalDays = 3650 // Longest period one renewal may add
+	maxRenewalNote = 500  // See models.Renewal.Note
+)
+
+// ExtendExpiry renews an account for days, or for one validity period of its
+// type when days is 0. The new period starts at the current expiry, or now
+// when the account has already expired. cost defaults to the renewal price
+// of the type. The expiry reminder is reset so the new date is reminded.
+func (s *AccountService) ExtendExpiry(id uint, days int, cost *models.Cents, note string) (*models.Renewal, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if err := validateRenewal(days, cost, note); err != nil {
+		return nil, err
+	}
+	// The whole row is saved back, secrets included
+	release := utils.HoldKey()
+	defer release()
+
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewAccountNotFound()
+	}
+	typeConfig, err := findAccountType(s.typeRepo, string(account.AccountType))
+	if err != nil {
+		return nil, err
+	}
+
+	before := accountSnapshot{plain: map[string]string{models.ChangeFieldExpireAt: formatExpiry(account.ExpireAt)}}
+	renewal, err := s.renew(account, typeConfig, days, cost, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	renewal.Note = note
+	if err := s.renewalRepo.Renew(account, renewal); err != nil {
+		return nil, err
+	}
+
+	cache.InvalidateStats()
+	after := accountSnapshot{plain: map[string]string{models.ChangeFieldExpireAt: formatExpiry(account.ExpireAt)}}
+	s.recordChangeSet(id, before, after, models.ChangeSourceRenewal, nil)
+
+	changes := map[string]interface{}{
+		"renewal":     renewal.ID,
+		"expire_at":   renewal.NewExpireAt,
+		"period_days": renewal.PeriodDays,
+	}
+	if renewal.Cost != nil {
+		changes["cost"] = *renewal.Cost
+	}
+	s.auditLog.LogAccountUpdate(id, currentActor(), changes)
+	return renewal, nil
+}
+
+// GetRenewals returns the renewals of an account, newest first
+func (s *AccountService) GetRenewals(accountID uint) ([]models.Renewal, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.renewalRepo.FindByAccount(accountID)
+}
+
+// GetRenewalReport sums the renewals made in [from, to) by period and by
+// account type
+func (s *AccountService) GetRenewalReport(from, to time.Time, granularity string) (*models.RenewalReport, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if err := validateReport(from, to, granularity); err != nil {
+		return nil, err
+	}
+
+	renewals, err := s.renewalRepo.FindBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RenewalReport{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		ByPeriod:    []models.RenewalPeriodStats{},
+		ByType:      []models.RenewalTypeStats{},
+	}
+	periods := make(map[string]int)
+	types := make(map[models.AccountType]int)
+	for _, r := range renewals {
+		var cost models.Cents
+		if r.Cost != nil {
+			cost = *r.Cost
+		}
+		report.Count++
+		report.Cost += cost
+
+		// Renewals are ordered by time, so periods come out in order
+		key := periodKey(r.RenewedAt, granularity)
+		i, ok := periods[key]
+		if !ok {
+			i = len(report.ByPeriod)
+			periods[key] = i
+			report.ByPeriod = append(report.ByPeriod, models.RenewalPeriodStats{Period: key})
+		}
+		report.ByPeriod[i].Count++
+		report.ByPeriod[i].Days += int64(r.PeriodDays)
+		report.ByPeriod[i].Cost += cost
+
+		j, ok := types[r.AccountType]
+		if !ok {
+			j = len(report.ByType)
+			types[r.AccountType] = j
+			report.ByType = append(report.ByType, models.RenewalTypeStats{Type: r.AccountType})
+		}
+		report.ByType[j].Count++
+		report.ByType[j].Cost += cost
+	}
+	return report, nil
+}
+
+// renew extends the expiry of an account in memory and returns the renewal
+// record to store with it
+func (s *AccountService) renew(account *models.Account, typeConfig *models.AccountTypeConfig, days int, cost *models.Cents, now time.Time) (*models.Renewal, error) {
+	if !typeConfig.Expires {
+		return nil, apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("%s 类型的账号不会过期，无需续费", typeConfig.Name))
+	}
+	if days == 0 {
+		days = validityDays(s.emailRepo, typeConfig)
+	}
+	if cost == nil && typeConfig.RenewalPrice != nil {
+		price := *typeConfig.RenewalPrice
+		cost = &price
+	}
+
+	oldExpireAt := account.ExpireAt
+	start := now
+	if oldExpireAt != nil && oldExpireAt.After(now) {
+		start = *oldExpireAt
+	}
+	newExpireAt := start.AddDate(0, 0, days)
+	account.ExpireAt = &newExpireAt
+	account.ReminderSent = false
+
+	return &models.Renewal{
+		AccountID:   account.ID,
+		AccountType: account.AccountType,
+		OldExpireAt: oldExpireAt,
+		NewExpireAt: newExpireAt,
+		PeriodDays:  days,
+		Cost:        cost,
+		RenewedBy:   currentActor(),
+		RenewedAt:   now,
+	}, nil
+}
+
+func validateRenewal(days int, cost *models.Cents, note string) error {
+	switch {
+	case days < 0 || days > maxRenewalDays:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("续费天数应在 1 到 %d 之间", maxRenewalDays))
+	case cost != nil && *cost < 0:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "续费费用不能为负数")
+	case utf8.RuneCountInString(note) > maxRenewalNote:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("备注不能超过 %d 个字符", maxRenewalNote))
+	}
+	return nil
+}
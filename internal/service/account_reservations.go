@@ -0,0 +1,361 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Limits of a reservation
+const (
+	defaultHoldMinutes = 30
+	maxHoldMinutes     = 7 * 24 * 60
+	maxReserveQuantity = 500
+	maxReservationRef  = 100
+)
+
+// reservationMu serializes allocating and closing reservations in this
+// process. The repository claims accounts with a conditional update, so this
+// only keeps concurrent calls from failing with ErrAccountsTaken.
+var reservationMu sync.Mutex
+
+// ReserveAccounts holds request.Quantity unsold accounts of a type for an
+// order, picked by request.Strategy among the accounts in the initial status
+// that have not expired. Either every account is held or none is.
+func (s *AccountService) ReserveAccounts(request models.ReservationRequest) (*models.Reservation, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	typeConfig, err := findAccountType(s.typeRepo, request.AccountType)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeReservationRequest(&request); err != nil {
+		return nil, err
+	}
+	initial, err := s.soldTarget(false)
+	if err != nil {
+		return nil, err
+	}
+
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+
+	now := time.Now()
+	if _, err := s.expireReservations(now); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to expire account reservations")
+	}
+	reservation := &models.Reservation{
+		AccountType: typeConfig.Name,
+		Quantity:    request.Quantity,
+		Strategy:    request.Strategy,
+		Reference:   request.Reference,
+		Status:      models.ReservationHeld,
+		HeldBy:      currentActor(),
+		HeldUntil:   now.Add(time.Duration(request.HoldMinutes) * time.Minute),
+	}
+	ids, err := s.reservationRepo.Allocate(reservation, initial.Name, now)
+	if errors.Is(err, repository.ErrAccountsTaken) {
+		return nil, apperrors.New(apperrors.ErrCodeInsufficientStock, "账号已被其他预留占用，请重试")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reservation.ID == 0 {
+		return nil, apperrors.NewInsufficientStock(string(typeConfig.Name), request.Quantity, len(ids))
+	}
+
+	s.auditLog.Log("reserve", "reservation", reservation.ID, reservation.HeldBy, map[string]interface{}{
+		"type":       reservation.AccountType,
+		"quantity":   reservation.Quantity,
+		"strategy":   reservation.Strategy,
+		"reference":  reservation.Reference,
+		"accounts":   ids,
+		"held_until": reservation.HeldUntil,
+	}, true, "")
+
+	reservations := []models.Reservation{*reservation}
+	if err := s.fillReservedAccounts(reservations); err != nil {
+		return nil, err
+	}
+	return &reservations[0], nil
+}
+
+// ConfirmReservation sells the accounts of a held reservation, recording a
+// sale for each with details; Price is per account and OrderRef defaults to
+// the reference of the reservation. The accounts are sold together in one
+// transaction. Accounts that can no longer be sold are reported in the result
+// and freed.
+func (s *AccountService) ConfirmReservation(id uint, details models.SaleDetails) (*models.BulkResult, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeSaleDetails(&details); err != nil {
+		return nil, err
+	}
+	target, err := s.soldTarget(true)
+	if err != nil {
+		return nil, err
+	}
+
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+
+	reservation, err := s.openReservation(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if details.OrderRef == "" {
+		details.OrderRef = reservation.Reference
+	}
+	accounts, err := s.reservationRepo.FindAccounts([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+
+	result := &models.BulkResult{Action: models.BulkActionStatus, Items: []models.BulkItemResult{}}
+	if len(ids) > 0 {
+		note := fmt.Sprintf("预留 #%d", id)
+		bulkDetails := map[string]interface{}{"status": target.Name, "reservation": id}
+		result, err = s.runBulk(models.BulkActionStatus, models.BulkSelection{IDs: ids}, bulkDetails, func(account *models.Account, _ []models.AccountFieldValue, now time.Time) (bulkChange, error) {
+			if account.ReservationID == nil || *account.ReservationID != id {
+				return bulkChange{}, apperrors.New(apperrors.ErrCodeReservationClosed, "账号已不在该预留中")
+			}
+			if account.IsSold {
+				return bulkChange{}, apperrors.NewAccountSold()
+			}
+			if _, err := checkTransition(s.statusRepo, account.Status, target.Name); err != nil {
+				return bulkChange{}, err
+			}
+			applyStatus(account, target, now)
+			sales, err := s.saleEntries(account, false, &details, now)
+			if err != nil {
+				return bulkChange{}, err
+			}
+			return bulkChange{status: target, note: note, sales: sales}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.closeReservation(reservation, models.ReservationConfirmed, currentActor(), time.Now()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReleaseReservation frees the accounts of a held reservation
+func (s *AccountService) ReleaseReservation(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+
+	reservation, err := s.reservationRepo.FindByID(id)
+	if err != nil {
+		return apperrors.NewReservationNotFound(id)
+	}
+	if reservation.Status != models.ReservationHeld {
+		return apperrors.NewReservationClosed(id, reservation.Status)
+	}
+	return s.closeReservation(reservation, models.ReservationReleased, currentActor(), time.Now())
+}
+
+// ExpireReservations frees the accounts of held reservations whose hold has
+// ended and returns how many reservations expired
+func (s *AccountService) ExpireReservations() (int, error) {
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+	return s.expireReservations(time.Now())
+}
+
+// GetReservations returns the reservations in status, or all when status is
+// empty, newest first, with their accounts
+func (s *AccountService) GetReservations(status string, page, pageSize int) (*models.PaginatedReservations, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	result, err := s.reservationRepo.FindAll(status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillReservedAccounts(result.Data); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetReservation returns a reservation with its accounts
+func (s *AccountService) GetReservation(id uint) (*models.Reservation, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	reservation, err := s.reservationRepo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewReservationNotFound(id)
+	}
+	reservations := []models.Reservation{*reservation}
+	if err := s.fillReservedAccounts(reservations); err != nil {
+		return nil, err
+	}
+	return &reservations[0], nil
+}
+
+// openReservation loads a reservation that is still held. One whose hold
+// ended is expired on the spot.
+func (s *AccountService) openReservation(id uint, now time.Time) (*models.Reservation, error) {
+	reservation, err := s.reservationRepo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewReservationNotFound(id)
+	}
+	if reservation.Status != models.ReservationHeld {
+		return nil, apperrors.NewReservationClosed(id, reservation.Status)
+	}
+	if !now.Before(reservation.HeldUntil) {
+		if err := s.closeReservation(reservation, models.ReservationExpired, "system", now); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.NewReservationClosed(id, reservation.Status)
+	}
+	return reservation, nil
+}
+
+// holdCheck returns a check that refuses accounts held by a reservation that
+// has not expired, so only ConfirmReservation and ReleaseReservation change
+// them. Each reservation is looked up once, for the bulk actions.
+func (s *AccountService) holdCheck(now time.Time) func(account *models.Account) error {
+	held := make(map[uint]bool)
+	return func(account *models.Account) error {
+		if account.ReservationID == nil {
+			return nil
+		}
+		id := *account.ReservationID
+		isHeld, seen := held[id]
+		if !seen {
+			reservation, err := s.reservationRepo.FindByID(id)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			isHeld = err == nil && reservation.Status == models.ReservationHeld && now.Before(reservation.HeldUntil)
+			held[id] = isHeld
+		}
+		if isHeld {
+			return apperrors.NewAccountReserved(id)
+		}
+		return nil
+	}
+}
+
+// expireReservations expires the held reservations whose hold ended before
+// now. Callers hold reservationMu.
+func (s *AccountService) expireReservations(now time.Time) (int, error) {
+	reservations, err := s.reservationRepo.FindExpired(now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range reservations {
+		if err := s.closeReservation(&reservations[i], models.ReservationExpired, "system", now); err != nil {
+			return i, err
+		}
+	}
+	if len(reservations) > 0 {
+		logger.WithField("reservations", len(reservations)).Info("Expired account reservations released")
+	}
+	return len(reservations), nil
+}
+
+// closeReservation ends a held reservation, freeing its unsold accounts
+func (s *AccountService) closeReservation(reservation *models.Reservation, status, user string, now time.Time) error {
+	reservation.Status = status
+	reservation.ResolvedBy = user
+	reservation.ResolvedAt = &now
+	if err := s.reservationRepo.Close(reservation); err != nil {
+		return err
+	}
+
+	action := map[string]string{
+		models.ReservationConfirmed: "confirm",
+		models.ReservationReleased:  "release",
+		models.ReservationExpired:   "expire",
+	}[status]
+	s.auditLog.Log(action, "reservation", reservation.ID, user, map[string]interface{}{
+		"type":      reservation.AccountType,
+		"reference": reservation.Reference,
+	}, true, "")
+	return nil
+}
+
+// fillReservedAccounts loads the accounts of the given reservations
+func (s *AccountService) fillReservedAccounts(reservations []models.Reservation) error {
+	ids := make([]uint, len(reservations))
+	for i, reservation := range reservations {
+		ids[i] = reservation.ID
+	}
+	accounts, err := s.reservationRepo.FindAccounts(ids)
+	if err != nil {
+		return err
+	}
+
+	byReservation := make(map[uint][]models.ReservedAccount)
+	for _, account := range accounts {
+		byReservation[*account.ReservationID] = append(byReservation[*account.ReservationID], models.ReservedAccount{
+			ID:        account.ID,
+			Account:   account.Account,
+			Status:    account.Status,
+			ExpireAt:  account.ExpireAt,
+			CreatedAt: account.CreatedAt,
+		})
+	}
+	for i := range reservations {
+		reservations[i].Accounts = byReservation[reservations[i].ID]
+		if reservations[i].Accounts == nil {
+			reservations[i].Accounts = []models.ReservedAccount{}
+		}
+	}
+	return nil
+}
+
+// normalizeReservationRequest checks a reservation request and fills in the
+// default strategy and hold time
+func normalizeReservationRequest(request *models.ReservationRequest) error {
+	request.Strategy = strings.TrimSpace(request.Strategy)
+	if request.Strategy == "" {
+		request.Strategy = models.AllocateOldest
+	}
+	if request.HoldMinutes == 0 {
+		request.HoldMinutes = defaultHoldMinutes
+	}
+	request.Reference = strings.TrimSpace(request.Reference)
+
+	switch request.Strategy {
+	case models.AllocateOldest, models.AllocateExpiring, models.AllocateLongest:
+	default:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "分配策略应为 oldest、expiring 或 longest")
+	}
+	switch {
+	case request.Quantity <= 0 || request.Quantity > maxReserveQuantity:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("预留数量应在 1 到 %d 之间", maxReserveQuantity))
+	case request.HoldMinutes < 0 || request.HoldMinutes > maxHoldMinutes:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("预留时长应在 1 到 %d 分钟之间", maxHoldMinutes))
+	case utf8.RuneCountInString(request.Reference) > maxReservationRef:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("订单号不能超过 %d 个字符", maxReservationRef))
+	}
+	return nil
+}
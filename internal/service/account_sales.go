@@ -0,0 +1,341 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Currency of sales when none is configured
+const fallbackCurrency = "CNY"
+
+// Longest values of a sale, see models.Sale
+const (
+	maxSaleBuyer    = 255
+	maxSaleChannel  = 30
+	maxSaleOrderRef = 100
+	maxSaleNote     = 500
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// SellAccount moves an account to the first sold status and records the sale
+// in the ledger. An account that is already sold but has no open sale, such
+// as one sold before the ledger existed, only gets the sale recorded.
+func (s *AccountService) SellAccount(id uint, details models.SaleDetails) (*models.Sale, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeSaleDetails(&details); err != nil {
+		return nil, err
+	}
+	release := utils.HoldKey()
+	defer release()
+
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewAccountNotFound()
+	}
+	if err := s.holdCheck(time.Now())(account); err != nil {
+		return nil, err
+	}
+
+	if !account.IsSold {
+		target, err := s.soldTarget(true)
+		if err != nil {
+			return nil, err
+		}
+		sales, err := s.changeStatus(id, target.Name, "", models.ChangeSourceStatus, nil, &details)
+		if err != nil {
+			return nil, err
+		}
+		return &sales[0], nil
+	}
+
+	if _, err := s.saleRepo.FindOpenSale(id); err == nil {
+		return nil, apperrors.NewAccountSold()
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	sales := []models.Sale{newSale(account, details, time.Now())}
+	if details.CustomerID != nil {
+		account.CustomerID = details.CustomerID
+	}
+	if err := s.saleRepo.Record(account, sales); err != nil {
+		return nil, err
+	}
+	cache.InvalidateStats()
+	s.auditLog.LogAccountUpdate(id, currentActor(), map[string]interface{}{
+		"sale":  sales[0].ID,
+		"price": sales[0].Price,
+	})
+	return &sales[0], nil
+}
+
+// UpdateSale corrects the details of a sale that was not reversed. The
+// account is linked to the customer of the corrected sale.
+func (s *AccountService) UpdateSale(id uint, details models.SaleDetails) (*models.Sale, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeSaleDetails(&details); err != nil {
+		return nil, err
+	}
+
+	sale, err := s.saleRepo.FindByID(id)
+	if err != nil || sale.Kind != models.SaleKindSale {
+		return nil, apperrors.NewSaleNotFound(id)
+	}
+	if _, err := s.saleRepo.FindReversal(id); err == nil {
+		return nil, apperrors.NewSaleReversed(id)
+	}
+
+	customerChanged := !sameCustomer(sale.CustomerID, details.CustomerID)
+	sale.CustomerID = details.CustomerID
+	sale.Buyer = details.Buyer
+	sale.Price = details.Price
+	sale.Currency = details.Currency
+	sale.Channel = details.Channel
+	sale.OrderRef = details.OrderRef
+	sale.Note = details.Note
+	if err := s.saleRepo.Update(sale); err != nil {
+		return nil, err
+	}
+	if customerChanged {
+		if _, err := s.customerRepo.AssignAccounts(sale.CustomerID, []uint{sale.AccountID}); err != nil {
+			return nil, err
+		}
+	}
+	cache.InvalidateStats()
+
+	s.auditLog.LogAccountUpdate(sale.AccountID, currentActor(), map[string]interface{}{
+		"sale":     sale.ID,
+		"price":    sale.Price,
+		"currency": sale.Currency,
+		"channel":  sale.Channel,
+	})
+	return sale, nil
+}
+
+// GetSales returns the ledger entries matching filter, newest first
+func (s *AccountService) GetSales(filter models.SaleFilter) (*models.PaginatedSales, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.saleRepo.FindAll(filter)
+}
+
+// GetRevenueReport sums the ledger entries made in [from, to) by period and
+// by account type, per currency
+func (s *AccountService) GetRevenueReport(from, to time.Time, granularity string) (*models.RevenueReport, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if err := validateReport(from, to, granularity); err != nil {
+		return nil, err
+	}
+
+	sales, err := s.saleRepo.FindBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, byPeriod, byType := newRevenueGroups(), newRevenueGroups(), newRevenueGroups()
+	for _, sale := range sales {
+		totals.add("", sale)
+		byPeriod.add(periodKey(sale.SoldAt, granularity), sale)
+		byType.add(string(sale.AccountType), sale)
+	}
+	return &models.RevenueReport{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Totals:      totals.stats,
+		ByPeriod:    byPeriod.stats,
+		ByType:      byType.stats,
+	}, nil
+}
+
+// GetDefaultCurrency returns the currency of sales entered without one
+func (s *AccountService) GetDefaultCurrency() string {
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil || sysConfig.DefaultCurrency == "" {
+		return fallbackCurrency
+	}
+	return sysConfig.DefaultCurrency
+}
+
+// SetDefaultCurrency updates the currency of sales entered without one, as
+// a three letter ISO 4217 code
+func (s *AccountService) SetDefaultCurrency(currency string) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyPattern.MatchString(currency) {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "货币应为三位字母代码，例如 CNY")
+	}
+
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return err
+	}
+	sysConfig.DefaultCurrency = currency
+	if err := s.emailRepo.UpdateSystemConfig(sysConfig); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("system_config", currentActor(), map[string]interface{}{
+		"default_currency": currency,
+	})
+	return nil
+}
+
+// saleEntries returns the ledger entries for an account whose sold state
+// went from wasSold to account.IsSold: a sale when it was sold, a reversal of
+// its open sale when it was taken back. details may be nil for a sale
+// without buyer or price. An account sold before the ledger existed has no
+// sale to reverse.
+func (s *AccountService) saleEntries(account *models.Account, wasSold bool, details *models.SaleDetails, now time.Time) ([]models.Sale, error) {
+	switch {
+	case !wasSold && account.IsSold:
+		if details == nil {
+			details = &models.SaleDetails{Currency: s.GetDefaultCurrency()}
+		}
+		if details.CustomerID != nil {
+			account.CustomerID = details.CustomerID
+		}
+		return []models.Sale{newSale(account, *details, now)}, nil
+	case wasSold && !account.IsSold:
+		open, err := s.saleRepo.FindOpenSale(account.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.Sale{reversalOf(open, account, now)}, nil
+	}
+	return nil, nil
+}
+
+// normalizeSaleDetails trims and checks the details of a sale and fills in
+// the default currency and the buyer of a customer
+func (s *AccountService) normalizeSaleDetails(details *models.SaleDetails) error {
+	if details.CustomerID != nil {
+		customer, err := s.customerRepo.FindByID(*details.CustomerID)
+		if err != nil {
+			return apperrors.NewCustomerNotFound(*details.CustomerID)
+		}
+		if strings.TrimSpace(details.Buyer) == "" {
+			details.Buyer = customer.Name
+		}
+	}
+	details.Buyer = strings.TrimSpace(details.Buyer)
+	details.Channel = strings.TrimSpace(details.Channel)
+	details.OrderRef = strings.TrimSpace(details.OrderRef)
+	details.Note = strings.TrimSpace(details.Note)
+	details.Currency = strings.ToUpper(strings.TrimSpace(details.Currency))
+	if details.Currency == "" {
+		details.Currency = s.GetDefaultCurrency()
+	}
+
+	switch {
+	case details.Price < 0:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "售价不能为负数")
+	case !currencyPattern.MatchString(details.Currency):
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "货币应为三位字母代码，例如 CNY")
+	case utf8.RuneCountInString(details.Buyer) > maxSaleBuyer:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("买家不能超过 %d 个字符", maxSaleBuyer))
+	case utf8.RuneCountInString(details.Channel) > maxSaleChannel:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("渠道不能超过 %d 个字符", maxSaleChannel))
+	case utf8.RuneCountInString(details.OrderRef) > maxSaleOrderRef:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("订单号不能超过 %d 个字符", maxSaleOrderRef))
+	case utf8.RuneCountInString(details.Note) > maxSaleNote:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("备注不能超过 %d 个字符", maxSaleNote))
+	}
+	return nil
+}
+
+func newSale(account *models.Account, details models.SaleDetails, now time.Time) models.Sale {
+	return models.Sale{
+		AccountID:   account.ID,
+		Account:     account.Account,
+		AccountType: account.AccountType,
+		Kind:        models.SaleKindSale,
+		CustomerID:  details.CustomerID,
+		Buyer:       details.Buyer,
+		Price:       details.Price,
+		Currency:    details.Currency,
+		Channel:     details.Channel,
+		OrderRef:    details.OrderRef,
+		Note:        details.Note,
+		SoldBy:      currentActor(),
+		SoldAt:      now,
+	}
+}
+
+// reversalOf returns the ledger entry that undoes a sale
+func reversalOf(sale *models.Sale, account *models.Account, now time.Time) models.Sale {
+	return models.Sale{
+		AccountID:   sale.AccountID,
+		Account:     account.Account,
+		AccountType: sale.AccountType,
+		Kind:        models.SaleKindReversal,
+		ReversalOf:  &sale.ID,
+		CustomerID:  sale.CustomerID,
+		Buyer:       sale.Buyer,
+		Price:       -sale.Price,
+		Currency:    sale.Currency,
+		Channel:     sale.Channel,
+		OrderRef:    sale.OrderRef,
+		SoldBy:      currentActor(),
+		SoldAt:      now,
+	}
+}
+
+func sameCustomer(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// revenueGroups sums ledger entries by key and currency, keeping the order
+// in which the groups first appear
+type revenueGroups struct {
+	index map[[2]string]int
+	stats []models.RevenueStats
+}
+
+func newRevenueGroups() *revenueGroups {
+	return &revenueGroups{index: make(map[[2]string]int), stats: []models.RevenueStats{}}
+}
+
+func (g *revenueGroups) add(key string, sale models.Sale) {
+	i, ok := g.index[[2]string{key, sale.Currency}]
+	if !ok {
+		i = len(g.stats)
+		g.index[[2]string{key, sale.Currency}] = i
+		g.stats = append(g.stats, models.RevenueStats{Key: key, Currency: sale.Currency})
+	}
+	stats := &g.stats[i]
+	if sale.Kind == models.SaleKindSale {
+		stats.Sales++
+		stats.Gross += sale.Price
+	} else {
+		stats.Reversals++
+	}
+	stats.Net += sale.Price
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// encryptField encrypts a sensitive value, leaving empty values empty
+func encryptField(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return utils.Encrypt(value)
+}
+
+// decryptField decrypts a sensitive value, leaving empty values empty
+func decryptField(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return utils.Decrypt(value)
+}
+
+// decryptAccountSecrets replaces every encrypted field of an account with its plaintext.
+// On failure the fields are cleared so ciphertext is never shown.
+func decryptAccountSecrets(account *models.Account) error {
+	password, err := decryptField(account.Password)
+	if err != nil {
+		account.Password = ""
+		account.Notes = ""
+		return err
+	}
+	notes, err := decryptField(account.Notes)
+	if err != nil {
+		account.Password = ""
+		account.Notes = ""
+		return err
+	}
+
+	account.Password = password
+	account.Notes = notes
+	return nil
+}
+
+// searchableSecrets returns the decrypted sensitive text that search should match
+func searchableSecrets(account *models.Account) string {
+	return account.Notes
+}
+
+// searchableFieldSecrets decrypts the secret custom field values of one
+// account for the search index. Values that fail to decrypt are skipped.
+func searchableFieldSecrets(values []models.AccountFieldValue) string {
+	var texts []string
+	for _, v := range values {
+		if v.SecretValue == "" {
+			continue
+		}
+		plaintext, err := decryptField(v.SecretValue)
+		if err != nil {
+			logger.WithFields(map[string]interface{}{
+				"account_id": v.AccountID,
+				"field_id":   v.FieldID,
+				"error":      err.Error(),
+			}).Warn("Skipping custom field in search index")
+			continue
+		}
+		texts = append(texts, plaintext)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// secretSearchIndex keeps the decrypted searchable text of encrypted fields in
+// memory while the vault is unlocked, so search on notes and secret custom
+// fields keeps working without storing them in plaintext. It is shared by all AccountService instances.
+type secretSearchIndex struct {
+	mu      sync.RWMutex
+	entries map[uint]indexedSecrets // account id -> lowercased text
+}
+
+// indexedSecrets is the searchable text of one account. Notes are kept apart
+// so the notes: search term can match them alone.
+type indexedSecrets struct {
+	notes  string
+	fields string
+}
+
+var secretIndex = &secretSearchIndex{entries: map[uint]indexedSecrets{}}
+
+// Rebuild decrypts every account and replaces the index contents
+func (idx *secretSearchIndex) Rebuild(repo *repository.AccountRepository, fieldRepo *repository.CustomFieldRepository) error {
+	accounts, err := repo.FindAllSecrets()
+	if err != nil {
+		return err
+	}
+	values, err := fieldRepo.FindSecretValues()
+	if err != nil {
+		return err
+	}
+	byAccount := make(map[uint][]models.AccountFieldValue)
+	for _, v := range values {
+		byAccount[v.AccountID] = append(byAccount[v.AccountID], v)
+	}
+
+	entries := make(map[uint]indexedSecrets, len(accounts))
+	for i := range accounts {
+		if err := decryptAccountSecrets(&accounts[i]); err != nil {
+			logger.WithFields(map[string]interface{}{
+				"account_id": accounts[i].ID,
+				"error":      err.Error(),
+			}).Warn("Skipping account in search index")
+			continue
+		}
+		notes := searchableSecrets(&accounts[i])
+		fields := searchableFieldSecrets(byAccount[accounts[i].ID])
+		if notes != "" || fields != "" {
+			entries[accounts[i].ID] = indexedSecrets{notes: strings.ToLower(notes), fields: strings.ToLower(fields)}
+		}
+	}
+
+	idx.mu.Lock()
+	idx.entries = entries
+	idx.mu.Unlock()
+
+	logger.WithField("accounts", len(entries)).Debug("Secret search index rebuilt")
+	return nil
+}
+
+// Clear drops all decrypted text, used when the vault locks
+func (idx *secretSearchIndex) Clear() {
+	idx.mu.Lock()
+	idx.entries = map[uint]indexedSecrets{}
+	idx.mu.Unlock()
+}
+
+// Put indexes the decrypted notes and secret custom field values of one account
+func (idx *secretSearchIndex) Put(accountID uint, notes, fields string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if notes == "" && fields == "" {
+		delete(idx.entries, accountID)
+		return
+	}
+	idx.entries[accountID] = indexedSecrets{notes: strings.ToLower(notes), fields: strings.ToLower(fields)}
+}
+
+// Remove drops one account from the index
+func (idx *secretSearchIndex) Remove(accountID uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.entries, accountID)
+}
+
+// Match returns the ids of accounts whose notes or secret custom field values
+// contain query, for plain searches
+func (idx *secretSearchIndex) Match(query string) []uint {
+	return idx.match(query, true)
+}
+
+// MatchNotes returns the ids of accounts whose notes contain query, for the
+// notes: search term
+func (idx *secretSearchIndex) MatchNotes(query string) []uint {
+	return idx.match(query, false)
+}
+
+func (idx *secretSearchIndex) match(query string, withFields bool) []uint {
+	query = strings.ToLower(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var ids []uint
+	for id, text := range idx.entries {
+		if strings.Contains(text.notes, query) || withFields && strings.Contains(text.fields, query) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"account-manager/internal/models"
+)
+
+func sortedIDs(ids []uint) []uint {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return ids
+}
+
+func TestSecretSearchIndexMatch(t *testing.T) {
+	idx := &secretSearchIndex{entries: map[uint]indexedSecrets{}}
+	idx.Put(1, "Recovery code ZEBRA42", "")
+	idx.Put(2, "", "zebra pin")
+	idx.Put(3, "nothing here", "nothing")
+	idx.Put(4, "", "")
+
+	tests := []struct {
+		query       string
+		match, note []uint
+	}{
+		{"zebra", []uint{1, 2}, []uint{1}},
+		{"RECOVERY", []uint{1}, []uint{1}},
+		{"pin", []uint{2}, nil},
+		{"nothing", []uint{3}, []uint{3}},
+		{"absent", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := sortedIDs(idx.Match(tt.query)); !slices.Equal(got, tt.match) {
+			t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.match)
+		}
+		if got := sortedIDs(idx.MatchNotes(tt.query)); !slices.Equal(got, tt.note) {
+			t.Errorf("MatchNotes(%q) = %v, want %v", tt.query, got, tt.note)
+		}
+	}
+
+	idx.Put(1, "", "")
+	idx.Remove(2)
+	if got := idx.Match("zebra"); len(got) != 0 {
+		t.Errorf("Match() after removing both accounts = %v", got)
+	}
+	idx.Clear()
+	if got := idx.Match("nothing"); len(got) != 0 {
+		t.Errorf("Match() after Clear = %v", got)
+	}
+}
+
+func TestPrepareFilterMatchesNotes(t *testing.T) {
+	secretIndex.Put(1, "recovery code zebra42", "")
+	secretIndex.Put(2, "", "zebra pin")
+	t.Cleanup(secretIndex.Clear)
+
+	// A plain search covers notes and secret custom field values
+	filter := models.AccountFilter{Search: "zebra"}
+	if err := prepareFilter(&filter); err != nil {
+		t.Fatal(err)
+	}
+	if filter.Search != "zebra" || filter.Terms != nil {
+		t.Errorf("plain search was parsed as a query: %+v", filter)
+	}
+	if got := sortedIDs(filter.SecretMatchIDs); !slices.Equal(got, []uint{1, 2}) {
+		t.Errorf("plain search SecretMatchIDs = %v, want [1 2]", got)
+	}
+
+	// Bare words of a query do as well, notes: terms only match notes
+	filter = models.AccountFilter{Search: "type:PLUS zebra notes:zebra"}
+	if err := prepareFilter(&filter); err != nil {
+		t.Fatal(err)
+	}
+	if filter.Search != "" || len(filter.Terms) != 3 {
+		t.Fatalf("query was not parsed: %+v", filter)
+	}
+	if got := sortedIDs(filter.Terms[1].MatchIDs); !slices.Equal(got, []uint{1, 2}) {
+		t.Errorf("bare word MatchIDs = %v, want [1 2]", got)
+	}
+	if got := filter.Terms[2].MatchIDs; !slices.Equal(got, []uint{1}) {
+		t.Errorf("notes: MatchIDs = %v, want [1]", got)
+	}
+}
@@ -2,11 +2,14 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"account-manager/internal/cache"
 	"account-manager/internal/config"
+	apperrors "account-manager/internal/errors"
 	"account-manager/internal/logger"
 	"account-manager/internal/models"
 	"account-manager/internal/repository"
@@ -14,20 +17,65 @@ import (
 )
 
 type AccountService struct {
-	repo       *repository.AccountRepository
-	emailRepo  *repository.EmailRepository
-	auditLog   *AuditLogService
+	repo            *repository.AccountRepository
+	emailRepo       *repository.EmailRepository
+	breachRepo      *repository.BreachRepository
+	historyRepo     *repository.PasswordHistoryRepository
+	typeRepo        *repository.AccountTypeRepository
+	statusRepo      *repository.AccountStatusRepository
+	fieldRepo       *repository.CustomFieldRepository
+	changeRepo      *repository.AccountChangeRepository
+	renewalRepo     *repository.RenewalRepository
+	saleRepo        *repository.SaleRepository
+	customerRepo    *repository.CustomerRepository
+	reservationRepo *repository.ReservationRepository
+	auditLog        *AuditLogService
 }
 
 func NewAccountService() *AccountService {
 	return &AccountService{
-		repo:      repository.NewAccountRepository(),
-		emailRepo: repository.NewEmailRepository(),
-		auditLog:  NewAuditLogService(),
+		repo:            repository.NewAccountRepository(),
+		emailRepo:       repository.NewEmailRepository(),
+		breachRepo:      repository.NewBreachRepository(),
+		historyRepo:     repository.NewPasswordHistoryRepository(),
+		typeRepo:        repository.NewAccountTypeRepository(),
+		statusRepo:      repository.NewAccountStatusRepository(),
+		fieldRepo:       repository.NewCustomFieldRepository(),
+		changeRepo:      repository.NewAccountChangeRepository(),
+		renewalRepo:     repository.NewRenewalRepository(),
+		saleRepo:        repository.NewSaleRepository(),
+		customerRepo:    repository.NewCustomerRepository(),
+		reservationRepo: repository.NewReservationRepository(),
+		auditLog:        NewAuditLogService(),
 	}
 }
 
 func (s *AccountService) CreateAccount(account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool) error {
+	return s.CreateAccountWithFields(account, password, accountType, expireAt, notes, isSold, nil)
+}
+
+// CreateAccountWithFields creates an account along with the values of the
+// custom fields of its type, keyed by field name
+func (s *AccountService) CreateAccountWithFields(account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string) error {
+	if err := s.createAccount(account, password, accountType, expireAt, notes, isSold, fields); err != nil {
+		return err
+	}
+	s.refreshReusedFlags()
+	return nil
+}
+
+// createAccount creates an account without refreshing the reused password
+// flags, which scans every account. BatchImport refreshes them once.
+func (s *AccountService) createAccount(account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+
 	if account == "" {
 		return errors.New("账号不能为空")
 	}
@@ -37,68 +85,100 @@ func (s *AccountService) CreateAccount(account string, password string, accountT
 	if existing != nil {
 		return errors.New("账号已存在")
 	}
+	if trashed, _ := s.repo.FindDeletedByAccount(account); trashed != nil {
+		return apperrors.NewAccountInTrash()
+	}
 
-	// Encrypt password
-	encryptedPassword := ""
-	if password != "" {
-		var err error
-		encryptedPassword, err = utils.Encrypt(password)
-		if err != nil {
-			return err
-		}
+	typeConfig, err := findAccountType(s.typeRepo, accountType)
+	if err != nil {
+		return err
+	}
+	fieldValues, _, err := prepareFieldValues(s.fieldRepo, typeConfig.Name, fields, nil, true)
+	if err != nil {
+		return err
 	}
 
-	// Calculate expire date
-	var finalExpireAt *time.Time
-	accType := models.AccountType(accountType)
+	// Encrypt password and notes
+	encryptedPassword, err := encryptField(password)
+	if err != nil {
+		return err
+	}
+	encryptedNotes, err := encryptField(notes)
+	if err != nil {
+		return err
+	}
 
-	if accType != models.AccountTypeFREE {
-		if expireAt != nil {
-			finalExpireAt = expireAt
-		} else {
-			// Default: 30 days from now
-			sysConfig, _ := s.emailRepo.GetSystemConfig()
-			days := 30
-			if sysConfig != nil {
-				days = sysConfig.DefaultValidityDays
-			}
-			expire := time.Now().AddDate(0, 0, days)
-			finalExpireAt = &expire
-		}
+	// Calculate expire date from the type unless one was given
+	finalExpireAt := defaultExpiry(s.emailRepo, typeConfig)
+	if typeConfig.Expires && expireAt != nil {
+		finalExpireAt = expireAt
 	}
 
-	var soldAt *time.Time
-	if isSold {
-		now := time.Now()
-		soldAt = &now
+	// New accounts start in the initial status, or the first sold one
+	status, err := s.soldTarget(isSold)
+	if err != nil {
+		return err
 	}
 
 	newAccount := &models.Account{
 		Account:     account,
 		Password:    encryptedPassword,
-		AccountType: accType,
+		AccountType: typeConfig.Name,
 		ExpireAt:    finalExpireAt,
-		Notes:       notes,
-		IsSold:      isSold,
-		SoldAt:      soldAt,
+		Notes:       encryptedNotes,
+	}
+	now := time.Now()
+	applyStatus(newAccount, status, now)
+	if err := assessPassword(s.breachRepo, newAccount, password); err != nil {
+		return err
 	}
 
-	err := s.repo.Create(newAccount)
+	err = s.repo.Create(newAccount)
+	if err == nil && len(fieldValues) > 0 {
+		err = s.fieldRepo.SetValues(newAccount.ID, fieldValues, nil)
+	}
 	if err == nil {
 		// Invalidate stats cache after creating account
 		cache.InvalidateStats()
+		s.indexSecrets(newAccount.ID, notes)
+		recordStatusChange(s.statusRepo, newAccount.ID, "", status.Name, now, "")
 
 		// Audit log
-		s.auditLog.LogAccountCreate(newAccount.ID, "user", account)
+		s.auditLog.LogAccountCreate(newAccount.ID, currentActor(), account)
 	}
 	return err
 }
 
 func (s *AccountService) UpdateAccount(id uint, account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool) error {
+	return s.UpdateAccountWithFields(id, account, password, accountType, expireAt, notes, isSold, nil)
+}
+
+// UpdateAccountWithFields updates an account and the custom field values
+// given by field name. Fields left out keep their value; an empty value
+// clears the field.
+func (s *AccountService) UpdateAccountWithFields(id uint, account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string) error {
+	return s.updateAccount(id, account, password, accountType, expireAt, notes, isSold, fields, models.ChangeSourceUpdate, nil)
+}
+
+// updateAccount saves an account and records the fields that changed as a
+// change set of the given source
+func (s *AccountService) updateAccount(id uint, account string, password string, accountType string, expireAt *time.Time, notes string, isSold bool, fields map[string]string, source string, revertOf *uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+
 	existing, err := s.repo.FindByID(id)
 	if err != nil {
 		return errors.New("账号不存在")
 	}
+	if err := s.holdCheck(time.Now())(existing); err != nil {
+		return err
+	}
 
 	// Check if new account name conflicts with another account
 	if account != existing.Account {
@@ -106,103 +186,269 @@ func (s *AccountService) UpdateAccount(id uint, account string, password string,
 		if conflict != nil {
 			return errors.New("账号名已被使用")
 		}
+		if trashed, _ := s.repo.FindDeletedByAccount(account); trashed != nil {
+			return apperrors.NewAccountInTrash()
+		}
+	}
+
+	typeConfig, err := findAccountType(s.typeRepo, accountType)
+	if err != nil {
+		return err
+	}
+
+	currentValues, err := s.fieldRepo.FindValues([]uint{id})
+	if err != nil {
+		return err
+	}
+	fieldValues, clearFields, err := prepareFieldValues(s.fieldRepo, typeConfig.Name, fields, currentValues, typeConfig.Name != existing.AccountType)
+	if err != nil {
+		return err
+	}
+	fieldNames, err := s.customFieldNames()
+	if err != nil {
+		return err
+	}
+	before := snapshotAccount(existing, currentValues, fieldNames)
+
+	encryptedNotes, err := encryptField(notes)
+	if err != nil {
+		return err
 	}
 
 	existing.Account = account
-	existing.AccountType = models.AccountType(accountType)
-	existing.Notes = notes
+	existing.AccountType = typeConfig.Name
+	existing.Notes = encryptedNotes
 
-	// Update isSold status
+	// Changing isSold is a status transition and follows the workflow
+	fromStatus := existing.Status
+	var status *models.AccountStatusConfig
+	var sales []models.Sale
+	now := time.Now()
 	if isSold != existing.IsSold {
-		existing.IsSold = isSold
-		if isSold {
-			now := time.Now()
-			existing.SoldAt = &now
-		} else {
-			existing.SoldAt = nil
+		target, err := s.soldTarget(isSold)
+		if err != nil {
+			return err
+		}
+		status, err = checkTransition(s.statusRepo, fromStatus, target.Name)
+		if err != nil {
+			return err
+		}
+		applyStatus(existing, status, now)
+		if sales, err = s.saleEntries(existing, !isSold, nil, now); err != nil {
+			return err
 		}
 	}
 
-	// Update password if provided
+	// Update password if provided, keeping the old one in the history
+	passwordChanged := false
 	if password != "" {
+		current, err := decryptField(existing.Password)
+		if err != nil {
+			return apperrors.NewDecryptionFailed(err)
+		}
+		passwordChanged = current != password
+	}
+	if passwordChanged {
+		if err := recordPasswordHistory(s.historyRepo, existing, currentActor(), historyReasonUpdate); err != nil {
+			return err
+		}
 		encryptedPassword, err := utils.Encrypt(password)
 		if err != nil {
 			return err
 		}
 		existing.Password = encryptedPassword
+		if err := assessPassword(s.breachRepo, existing, password); err != nil {
+			return err
+		}
 	}
 
-	// Update expire date
-	if models.AccountType(accountType) == models.AccountTypeFREE {
+	// Update expire date, a moved date is reminded again
+	if !typeConfig.Expires {
 		existing.ExpireAt = nil
 	} else if expireAt != nil {
+		if existing.ExpireAt == nil || !existing.ExpireAt.Equal(*expireAt) {
+			existing.ReminderSent = false
+		}
 		existing.ExpireAt = expireAt
 	}
 
-	err = s.repo.Update(existing)
+	if len(sales) > 0 {
+		err = s.saleRepo.Record(existing, sales)
+	} else {
+		err = s.repo.Update(existing)
+	}
+	if err == nil && (len(fieldValues) > 0 || len(clearFields) > 0) {
+		err = s.fieldRepo.SetValues(id, fieldValues, clearFields)
+	}
 	if err == nil {
 		// Invalidate stats cache after updating account
 		cache.InvalidateStats()
+		s.indexSecrets(id, notes)
+		if passwordChanged {
+			s.refreshReusedFlags()
+		}
+		if status != nil {
+			recordStatusChange(s.statusRepo, id, fromStatus, status.Name, now, "")
+		}
+
+		// Field level history, secrets only marked as changed
+		var changedFields []string
+		if afterValues, err := s.fieldRepo.FindValues([]uint{id}); err == nil {
+			after := snapshotAccount(existing, afterValues, fieldNames)
+			changedFields = s.recordChangeSet(id, before, after, source, revertOf)
+		}
 
 		// Audit log
 		changes := map[string]interface{}{
 			"account": account,
 			"type":    accountType,
+			"fields":  changedFields,
+		}
+		if passwordChanged {
+			changes["password"] = "changed"
+		}
+		if status != nil {
+			changes["status"] = status.Name
+			changes["from_status"] = fromStatus
 		}
-		s.auditLog.LogAccountUpdate(id, "user", changes)
+		if revertOf != nil {
+			changes["reverted_change_set"] = *revertOf
+		}
+		s.auditLog.LogAccountUpdate(id, currentActor(), changes)
 	}
 	return err
 }
 
+// DeleteAccount moves an account to the trash, see RestoreAccount and PurgeAccount
 func (s *AccountService) DeleteAccount(id uint) error {
-	// Get account info before deletion for audit log
-	account, _ := s.repo.FindByID(id)
-	accountName := ""
-	if account != nil {
-		accountName = account.Account
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
 	}
 
-	err := s.repo.Delete(id)
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	if err := s.holdCheck(time.Now())(account); err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(id, currentActor())
 	if err == nil {
 		// Invalidate stats cache after deleting account
 		cache.InvalidateStats()
+		secretIndex.Remove(id)
+		s.refreshReusedFlags()
 
 		// Audit log
-		s.auditLog.LogAccountDelete(id, "user", accountName)
+		s.auditLog.LogAccountDelete(id, currentActor(), account.Account)
 	}
 	return err
 }
 
 func (s *AccountService) GetAccount(id uint) (*models.Account, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
 	account, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Decrypt password for display
-	if account.Password != "" {
-		decrypted, err := utils.Decrypt(account.Password)
-		if err == nil {
-			account.Password = decrypted
-		}
+	// Decrypt password and notes for display
+	if err := decryptAccountSecrets(account); err != nil {
+		return nil, apperrors.NewDecryptionFailed(err)
+	}
+	reveal := hasPermission(models.PermRevealPasswords)
+	if !reveal {
+		account.Password = ""
+	}
+	accounts := []models.Account{*account}
+	if err := attachCustomFields(s.fieldRepo, accounts, reveal); err != nil {
+		return nil, err
 	}
 
-	return account, nil
+	return &accounts[0], nil
 }
 
 func (s *AccountService) GetAccounts(filter models.AccountFilter) (*models.PaginatedAccounts, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
+	if err := prepareFilter(&filter); err != nil {
+		return nil, err
+	}
+
 	result, err := s.repo.FindAll(filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// Batch decrypt passwords using goroutine pool
+	// Batch decrypt passwords and notes using goroutine pool
 	s.batchDecrypt(result.Data)
+	if texts := searchTexts(filter); len(texts) > 0 {
+		for i := range result.Data {
+			highlightMatch(&result.Data[i], texts)
+		}
+	}
+	// Users who may not reveal passwords still see the rest of the account
+	reveal := hasPermission(models.PermRevealPasswords)
+	if !reveal {
+		for i := range result.Data {
+			result.Data[i].Password = ""
+		}
+	}
+	if err := attachCustomFields(s.fieldRepo, result.Data, reveal); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
-// batchDecrypt decrypts passwords in parallel using a goroutine pool
+// prepareFilter checks the field filters, parses a search written in the
+// query syntax and resolves the search over encrypted fields
+func prepareFilter(filter *models.AccountFilter) error {
+	if filter.Search != "" {
+		terms, structured, err := parseAccountQuery(filter.Search, time.Now())
+		if err != nil {
+			return err
+		}
+		if !structured {
+			filter.SecretMatchIDs = secretIndex.Match(filter.Search)
+		} else {
+			for i := range terms {
+				switch terms[i].Field {
+				case models.QueryFieldText:
+					terms[i].MatchIDs = secretIndex.Match(terms[i].Text)
+				case models.QueryFieldNotes:
+					terms[i].MatchIDs = secretIndex.MatchNotes(terms[i].Text)
+				}
+			}
+			filter.Terms = terms
+			filter.Search = ""
+		}
+	}
+	for _, f := range filter.Fields {
+		switch f.Op {
+		case "", models.FieldOpEquals, models.FieldOpContains, models.FieldOpGreater, models.FieldOpLess:
+		default:
+			return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("不支持的字段筛选条件 %s", f.Op))
+		}
+	}
+	return nil
+}
+
+// batchDecrypt decrypts passwords and notes in parallel using a goroutine pool.
+// Accounts that fail to decrypt keep blank secrets and get DecryptError set,
+// so ciphertext is never shown as plaintext and the rest of the page is kept.
 func (s *AccountService) batchDecrypt(accounts []models.Account) {
 	cfg := config.Get()
 	maxWorkers := cfg.Worker.DecryptionWorkers
@@ -210,7 +456,7 @@ func (s *AccountService) batchDecrypt(accounts []models.Account) {
 	var wg sync.WaitGroup
 
 	for i := range accounts {
-		if accounts[i].Password == "" {
+		if accounts[i].Password == "" && accounts[i].Notes == "" {
 			continue
 		}
 
@@ -220,14 +466,12 @@ func (s *AccountService) batchDecrypt(accounts []models.Account) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release semaphore
 
-			decrypted, err := utils.Decrypt(accounts[idx].Password)
-			if err == nil {
-				accounts[idx].Password = decrypted
-			} else {
+			if err := decryptAccountSecrets(&accounts[idx]); err != nil {
+				accounts[idx].DecryptError = true
 				logger.WithFields(map[string]interface{}{
 					"account_id": accounts[idx].ID,
 					"error":      err.Error(),
-				}).Error("Failed to decrypt password")
+				}).Error("Failed to decrypt account secrets")
 			}
 		}(i)
 	}
@@ -235,6 +479,10 @@ func (s *AccountService) batchDecrypt(accounts []models.Account) {
 }
 
 func (s *AccountService) GetStats() (*models.AccountStats, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+
 	// Try to get from cache first
 	c := cache.GetCache()
 	if cached, found := c.Get(cache.KeyStats); found {
@@ -255,42 +503,109 @@ func (s *AccountService) GetStats() (*models.AccountStats, error) {
 	return stats, nil
 }
 
-func (s *AccountService) MarkAsSold(id uint) error {
+// ChangeStatus moves an account to another status, as allowed by the
+// configured transitions, and records the change in its status history
+func (s *AccountService) ChangeStatus(id uint, status string, note string) error {
+	_, err := s.changeStatus(id, status, note, models.ChangeSourceStatus, nil, nil)
+	return err
+}
+
+// changeStatus moves an account to another status and returns the ledger
+// entries written when its sold state changed. sale describes the sale when
+// the account is sold and may be nil.
+func (s *AccountService) changeStatus(id uint, status string, note string, source string, revertOf *uint, sale *models.SaleDetails) ([]models.Sale, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return nil, err
+	}
+	// The whole row is saved back, secrets included
+	release := utils.HoldKey()
+	defer release()
+
 	account, err := s.repo.FindByID(id)
 	if err != nil {
-		return errors.New("账号不存在")
+		return nil, apperrors.NewAccountNotFound()
+	}
+	if err := s.holdCheck(time.Now())(account); err != nil {
+		return nil, err
+	}
+	if account.Status == status {
+		return nil, nil
 	}
 
+	from := account.Status
+	wasSold := account.IsSold
+	target, err := checkTransition(s.statusRepo, from, status)
+	if err != nil {
+		return nil, err
+	}
+	before := accountSnapshot{plain: map[string]string{models.ChangeFieldStatus: from}}
 	now := time.Now()
-	account.IsSold = true
-	account.SoldAt = &now
+	applyStatus(account, target, now)
+	sales, err := s.saleEntries(account, wasSold, sale, now)
+	if err != nil {
+		return nil, err
+	}
 
-	err = s.repo.Update(account)
-	if err == nil {
-		// Invalidate stats cache after marking as sold
-		cache.InvalidateStats()
+	if err := s.saleRepo.Record(account, sales); err != nil {
+		return nil, err
 	}
-	return err
+	cache.InvalidateStats()
+	recordStatusChange(s.statusRepo, id, from, target.Name, now, note)
+	after := accountSnapshot{plain: map[string]string{models.ChangeFieldStatus: target.Name}}
+	s.recordChangeSet(id, before, after, source, revertOf)
+
+	changes := map[string]interface{}{
+		"status":      target.Name,
+		"from_status": from,
+	}
+	for _, entry := range sales {
+		changes[entry.Kind] = entry.ID
+	}
+	s.auditLog.LogAccountUpdate(id, currentActor(), changes)
+	return sales, nil
 }
 
-func (s *AccountService) MarkAsUnsold(id uint) error {
-	account, err := s.repo.FindByID(id)
+// MarkAsSold moves an account to the first status that counts as sold
+func (s *AccountService) MarkAsSold(id uint) error {
+	target, err := s.soldTarget(true)
 	if err != nil {
-		return errors.New("账号不存在")
+		return err
 	}
+	return s.ChangeStatus(id, target.Name, "")
+}
 
-	account.IsSold = false
-	account.SoldAt = nil
+// MarkAsUnsold moves an account back to the initial status
+func (s *AccountService) MarkAsUnsold(id uint) error {
+	target, err := s.soldTarget(false)
+	if err != nil {
+		return err
+	}
+	return s.ChangeStatus(id, target.Name, "")
+}
 
-	err = s.repo.Update(account)
-	if err == nil {
-		// Invalidate stats cache after marking as unsold
-		cache.InvalidateStats()
+// soldTarget returns the status that stands for the legacy sold flag
+func (s *AccountService) soldTarget(sold bool) (*models.AccountStatusConfig, error) {
+	var status *models.AccountStatusConfig
+	var err error
+	if sold {
+		status, err = s.statusRepo.FindFirstSold()
+	} else {
+		status, err = s.statusRepo.FindInitial()
 	}
-	return err
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrCodeStatusNotFound, "未配置对应的账号状态")
+	}
+	return status, nil
 }
 
 func (s *AccountService) BatchImport(accounts []map[string]interface{}) (int, []string) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, []string{err.Error()}
+	}
+	if err := requireUnlocked(); err != nil {
+		return 0, []string{err.Error()}
+	}
+
 	successCount := 0
 	var errors []string
 
@@ -299,6 +614,14 @@ func (s *AccountService) BatchImport(accounts []map[string]interface{}) (int, []
 		password, _ := acc["password"].(string)
 		accountType, _ := acc["accountType"].(string)
 		isSold, _ := acc["isSold"].(bool)
+		totp, _ := acc["totp"].(string)
+		var fields map[string]string
+		if values, ok := acc["fields"].(map[string]interface{}); ok {
+			fields = make(map[string]string, len(values))
+			for name, value := range values {
+				fields[name] = fmt.Sprint(value)
+			}
+		}
 
 		var expireAt *time.Time
 		if expireStr, ok := acc["expireAt"].(string); ok && expireStr != "" {
@@ -308,7 +631,12 @@ func (s *AccountService) BatchImport(accounts []map[string]interface{}) (int, []
 			}
 		}
 
-		err := s.CreateAccount(account, password, accountType, expireAt, "", isSold)
+		err := s.createAccount(account, password, accountType, expireAt, "", isSold, fields)
+		if err == nil && totp != "" {
+			if created, findErr := s.repo.FindByAccount(account); findErr == nil {
+				err = s.SetTOTP(created.ID, totp)
+			}
+		}
 		if err != nil {
 			errors = append(errors, account+": "+err.Error())
 		} else {
@@ -319,13 +647,53 @@ func (s *AccountService) BatchImport(accounts []map[string]interface{}) (int, []
 	// Invalidate stats cache after batch import
 	if successCount > 0 {
 		cache.InvalidateStats()
+		s.refreshReusedFlags()
 	}
 
 	return successCount, errors
 }
 
+// refreshReusedFlags updates reuse detection after a password was added,
+// changed or removed. A failure only leaves the flags stale.
+func (s *AccountService) refreshReusedFlags() {
+	if err := s.repo.RefreshReusedFlags(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to refresh reused password flags")
+	}
+}
+
+// RebuildSearchIndex decrypts the searchable encrypted fields into memory.
+// It is called after the vault is unlocked.
+func (s *AccountService) RebuildSearchIndex() error {
+	if !utils.HasEncryptionKey() {
+		return apperrors.NewVaultLocked()
+	}
+	return secretIndex.Rebuild(s.repo, s.fieldRepo)
+}
+
+// indexSecrets puts the decrypted notes and secret custom field values of an
+// account in the search index
+func (s *AccountService) indexSecrets(id uint, notes string) {
+	values, err := s.fieldRepo.FindValues([]uint{id})
+	if err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to index custom fields")
+	}
+	secretIndex.Put(id, notes, searchableFieldSecrets(values))
+}
+
+// ClearSearchIndex drops the decrypted search index, called when the vault locks
+func (s *AccountService) ClearSearchIndex() {
+	secretIndex.Clear()
+}
+
 // DecryptPassword decrypts a single password on-demand
 func (s *AccountService) DecryptPassword(id uint) (string, error) {
+	if err := requirePermission(models.PermRevealPasswords); err != nil {
+		return "", err
+	}
+	if err := requireUnlocked(); err != nil {
+		return "", err
+	}
+
 	account, err := s.repo.FindByID(id)
 	if err != nil {
 		return "", errors.New("账号不存在")
@@ -337,11 +705,165 @@ func (s *AccountService) DecryptPassword(id uint) (string, error) {
 
 	decrypted, err := utils.Decrypt(account.Password)
 	if err != nil {
-		return "", errors.New("解密失败")
+		return "", apperrors.NewDecryptionFailed(err)
 	}
 
 	// Audit log - password access
-	s.auditLog.LogPasswordView(id, "user", "decrypt")
+	s.auditLog.LogPasswordView(id, currentActor(), "decrypt")
 
 	return decrypted, nil
 }
+
+// SetTOTP stores the TOTP secret of an account, given as an otpauth:// URI or
+// a bare base32 secret
+func (s *AccountService) SetTOTP(id uint, secret string) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
+	key, err := utils.ParseTOTP(secret)
+	if err != nil {
+		return apperrors.NewInvalidTOTP(err)
+	}
+	return s.writeTOTP(id, key, models.ChangeSourceTOTP, nil)
+}
+
+// RemoveTOTP deletes the TOTP secret of an account
+func (s *AccountService) RemoveTOTP(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	return s.writeTOTP(id, nil, models.ChangeSourceTOTP, nil)
+}
+
+// writeTOTP stores or, when key is nil, removes the TOTP secret of an
+// account together with a change set of the given source
+func (s *AccountService) writeTOTP(id uint, key *utils.TOTPKey, source string, revertOf *uint) error {
+	release := utils.HoldKey()
+	defer release()
+
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	before := snapshotAccount(account, nil, nil)
+
+	action := "removed"
+	account.TOTP = ""
+	account.HasTOTP = false
+	if key != nil {
+		if key.AccountName == "" {
+			key.AccountName = account.Account
+		}
+		if account.TOTP, err = utils.Encrypt(key.URI()); err != nil {
+			return apperrors.NewEncryptionFailed("TOTP", err)
+		}
+		account.HasTOTP = true
+		action = "set"
+	}
+
+	set := newChangeSet(id, before, snapshotAccount(account, nil, nil), source, revertOf)
+	if err := s.repo.UpdateWithChanges(account, nil, set); err != nil {
+		return err
+	}
+	s.auditLog.LogAccountUpdate(id, currentActor(), map[string]interface{}{"totp": action})
+	return nil
+}
+
+// GetTOTPCode returns the current one-time code of an account
+func (s *AccountService) GetTOTPCode(id uint) (*models.TOTPCode, error) {
+	if err := requirePermission(models.PermRevealPasswords); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewAccountNotFound()
+	}
+
+	code, err := s.totpCode(account)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogPasswordView(id, currentActor(), "totp")
+	return code, nil
+}
+
+func (s *AccountService) totpCode(account *models.Account) (*models.TOTPCode, error) {
+	if account.TOTP == "" {
+		return nil, apperrors.NewTOTPNotConfigured()
+	}
+
+	uri, err := utils.Decrypt(account.TOTP)
+	if err != nil {
+		return nil, apperrors.NewDecryptionFailed(err)
+	}
+	key, err := utils.ParseTOTP(uri)
+	if err != nil {
+		return nil, apperrors.NewInvalidTOTP(err)
+	}
+
+	code, remaining, err := key.Code(time.Now())
+	if err != nil {
+		return nil, apperrors.NewInvalidTOTP(err)
+	}
+	return &models.TOTPCode{Code: code, Remaining: remaining, Period: key.Period, Issuer: key.Issuer}, nil
+}
+
+// RenderCopyText fills SystemConfig.CopyFormat for an account. Supported
+// placeholders are {account}, {password}, {type}, {expireAt} and {totp}.
+func (s *AccountService) RenderCopyText(id uint) (string, error) {
+	if err := requirePermission(models.PermRevealPasswords); err != nil {
+		return "", err
+	}
+	if err := requireUnlocked(); err != nil {
+		return "", err
+	}
+
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return "", apperrors.NewAccountNotFound()
+	}
+
+	format := "账号：{account}\n密码：{password}"
+	if sysConfig, err := s.emailRepo.GetSystemConfig(); err == nil && sysConfig.CopyFormat != "" {
+		format = sysConfig.CopyFormat
+	}
+
+	password, err := decryptField(account.Password)
+	if err != nil {
+		return "", apperrors.NewDecryptionFailed(err)
+	}
+
+	totp := ""
+	if strings.Contains(format, "{totp}") && account.TOTP != "" {
+		code, err := s.totpCode(account)
+		if err != nil {
+			return "", err
+		}
+		totp = code.Code
+	}
+
+	expireAt := ""
+	if account.ExpireAt != nil {
+		expireAt = account.ExpireAt.Format("2006-01-02")
+	}
+
+	text := strings.NewReplacer(
+		"{account}", account.Account,
+		"{password}", password,
+		"{type}", string(account.AccountType),
+		"{expireAt}", expireAt,
+		"{totp}", totp,
+	).Replace(format)
+
+	s.auditLog.LogPasswordView(id, currentActor(), "copy")
+	return text, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+)
+
+// Longest status name, see models.Account.Status
+const maxAccountStatusName = 20
+
+type AccountStatusService struct {
+	repo        *repository.AccountStatusRepository
+	accountRepo *repository.AccountRepository
+	auditLog    *AuditLogService
+}
+
+func NewAccountStatusService() *AccountStatusService {
+	return &AccountStatusService{
+		repo:        repository.NewAccountStatusRepository(),
+		accountRepo: repository.NewAccountRepository(),
+		auditLog:    NewAuditLogService(),
+	}
+}
+
+// GetStatuses returns all account statuses in workflow order
+func (s *AccountStatusService) GetStatuses() ([]models.AccountStatusConfig, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+// SaveStatus creates a status, or updates it when ID is set. Renaming a
+// status moves its accounts and transitions along.
+func (s *AccountStatusService) SaveStatus(status *models.AccountStatusConfig) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	status.Name = strings.TrimSpace(status.Name)
+	if err := validateAccountStatus(status); err != nil {
+		return err
+	}
+	if conflict, _ := s.repo.FindByName(status.Name); conflict != nil && conflict.ID != status.ID {
+		return apperrors.NewStatusExists(status.Name)
+	}
+
+	oldName := status.Name
+	if status.ID == 0 {
+		if err := s.repo.Create(status); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(status.ID)
+		if err != nil {
+			return apperrors.NewStatusNotFound(fmt.Sprint(status.ID))
+		}
+		// Another status must be made initial instead
+		if existing.Initial && !status.Initial {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, "必须有一个状态作为新账号的初始状态")
+		}
+		oldName = existing.Name
+		status.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(status, oldName); err != nil {
+			return err
+		}
+	}
+
+	cache.InvalidateStats()
+
+	changes := map[string]interface{}{
+		"status":  status.Name,
+		"label":   status.Label,
+		"initial": status.Initial,
+		"sold":    status.Sold,
+	}
+	if oldName != status.Name {
+		changes["renamed_from"] = oldName
+	}
+	s.auditLog.LogConfigChange("account_status", currentActor(), changes)
+	return nil
+}
+
+// DeleteStatus removes a status that no account is in, along with its transitions
+func (s *AccountStatusService) DeleteStatus(id uint) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	status, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewStatusNotFound(fmt.Sprint(id))
+	}
+	if status.Initial {
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "不能删除新账号的初始状态")
+	}
+	count, err := s.accountRepo.CountByStatus(status.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.NewStatusInUse(status.Name, count)
+	}
+
+	if err := s.repo.Delete(status); err != nil {
+		return err
+	}
+	cache.InvalidateStats()
+
+	s.auditLog.LogConfigChange("account_status", currentActor(), map[string]interface{}{
+		"status":  status.Name,
+		"deleted": true,
+	})
+	return nil
+}
+
+// GetTransitions returns every allowed status transition
+func (s *AccountStatusService) GetTransitions() ([]models.AccountStatusTransition, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.repo.FindTransitions()
+}
+
+// SetTransitions replaces the statuses accounts may move to from one status
+func (s *AccountStatusService) SetTransitions(from string, to []string) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if _, err := findAccountStatus(s.repo, from); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	targets := make([]string, 0, len(to))
+	for _, name := range to {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if name == from {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, "状态不能转换为自身")
+		}
+		if _, err := findAccountStatus(s.repo, name); err != nil {
+			return err
+		}
+		seen[name] = true
+		targets = append(targets, name)
+	}
+
+	if err := s.repo.SetTransitions(from, targets); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("account_status", currentActor(), map[string]interface{}{
+		"status":      from,
+		"transitions": targets,
+	})
+	return nil
+}
+
+// GetStatusHistory returns the status transitions of an account, newest first
+func (s *AccountStatusService) GetStatusHistory(accountID uint) ([]models.AccountStatusChange, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.repo.FindChanges(accountID)
+}
+
+func validateAccountStatus(status *models.AccountStatusConfig) error {
+	switch {
+	case status.Name == "":
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "状态名称不能为空")
+	case len(status.Name) > maxAccountStatusName:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("状态名称不能超过 %d 个字符", maxAccountStatusName))
+	}
+	return nil
+}
+
+// findAccountStatus looks up the configuration of an account status by name
+func findAccountStatus(repo *repository.AccountStatusRepository, name string) (*models.AccountStatusConfig, error) {
+	status, err := repo.FindByName(name)
+	if err != nil {
+		return nil, apperrors.NewStatusNotFound(name)
+	}
+	return status, nil
+}
+
+// checkTransition returns the configuration of the status an account moves
+// to, or an error when the workflow does not allow the move
+func checkTransition(repo *repository.AccountStatusRepository, from, to string) (*models.AccountStatusConfig, error) {
+	status, err := findAccountStatus(repo, to)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := repo.TransitionAllowed(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, apperrors.NewInvalidTransition(from, to)
+	}
+	return status, nil
+}
+
+// applyStatus moves an account to a status, keeping IsSold and SoldAt in step
+func applyStatus(account *models.Account, status *models.AccountStatusConfig, now time.Time) {
+	account.Status = status.Name
+	account.StatusAt = &now
+	if status.Sold && !account.IsSold {
+		account.SoldAt = &now
+	} else if !status.Sold {
+		account.SoldAt = nil
+		account.CustomerID = nil
+	}
+	account.IsSold = status.Sold
+}
+
+// recordStatusChange stores a transition in the status history of an account.
+// A failure only leaves a gap in the history.
+func recordStatusChange(repo *repository.AccountStatusRepository, accountID uint, from, to string, at time.Time, note string) {
+	err := repo.CreateChange(&models.AccountStatusChange{
+		AccountID:  accountID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedBy:  currentActor(),
+		ChangedAt:  at,
+		Note:       note,
+	})
+	if err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to record account status change")
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"time"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+)
+
+// Longest trash retention that can be configured
+const maxTrashRetentionDays = 3650
+
+// GetTrash returns the accounts in the trash, most recently deleted first.
+// Encrypted fields are left out.
+func (s *AccountService) GetTrash(page, pageSize int) (*models.PaginatedAccounts, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.FindTrash(page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Data {
+		result.Data[i].Password = ""
+		result.Data[i].Notes = ""
+	}
+	return result, nil
+}
+
+// RestoreAccount takes an account out of the trash
+func (s *AccountService) RestoreAccount(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	account, err := s.repo.FindDeletedByID(id)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	if err := s.repo.Restore(id); err != nil {
+		return err
+	}
+
+	cache.InvalidateStats()
+	s.refreshReusedFlags()
+	// While locked the index is rebuilt on unlock
+	if notes, err := decryptField(account.Notes); err == nil {
+		s.indexSecrets(id, notes)
+	}
+
+	s.auditLog.LogAccountRestore(id, currentActor(), account.Account)
+	return nil
+}
+
+// PurgeAccount removes an account in the trash for good
+func (s *AccountService) PurgeAccount(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	account, err := s.repo.FindDeletedByID(id)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	return s.purge(account, currentActor())
+}
+
+// EmptyTrash removes every account in the trash for good and returns how
+// many were removed
+func (s *AccountService) EmptyTrash() (int, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, err
+	}
+	return s.purgeTrashedBefore(time.Now().Add(time.Second), currentActor())
+}
+
+// PurgeExpiredTrash removes accounts that have been in the trash longer than
+// the configured retention. It is run by the scheduler.
+func (s *AccountService) PurgeExpiredTrash() (int, error) {
+	days := s.GetTrashRetentionDays()
+	if days <= 0 {
+		return 0, nil
+	}
+	return s.purgeTrashedBefore(time.Now().AddDate(0, 0, -days), "system")
+}
+
+func (s *AccountService) purgeTrashedBefore(date time.Time, user string) (int, error) {
+	ids, err := s.repo.FindTrashedBefore(date)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		account, err := s.repo.FindDeletedByID(id)
+		if err != nil {
+			continue
+		}
+		if err := s.purge(account, user); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		logger.WithField("accounts", purged).Info("Trash emptied")
+	}
+	return purged, nil
+}
+
+// purge deletes an account in the trash together with its history
+func (s *AccountService) purge(account *models.Account, user string) error {
+	if err := s.repo.Purge(account.ID); err != nil {
+		return err
+	}
+	secretIndex.Remove(account.ID)
+
+	s.auditLog.LogAccountPurge(account.ID, user, account.Account)
+	return nil
+}
+
+// GetTrashRetentionDays returns the days accounts stay in the trash, 0 means forever
+func (s *AccountService) GetTrashRetentionDays() int {
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return 0
+	}
+	return sysConfig.TrashRetentionDays
+}
+
+// SetTrashRetentionDays updates the days accounts stay in the trash before
+// they are purged, 0 keeps them until the trash is emptied by hand
+func (s *AccountService) SetTrashRetentionDays(days int) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if days < 0 || days > maxTrashRetentionDays {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "回收站保留天数需在 0 到 3650 之间")
+	}
+
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return err
+	}
+	sysConfig.TrashRetentionDays = days
+	if err := s.emailRepo.UpdateSystemConfig(sysConfig); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("system_config", currentActor(), map[string]interface{}{
+		"trash_retention_days": days,
+	})
+	return nil
+}
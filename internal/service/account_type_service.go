@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+)
+
+// Longest account type name, see models.Account.AccountType
+const maxAccountTypeName = 20
+
+type AccountTypeService struct {
+	repo        *repository.AccountTypeRepository
+	accountRepo *repository.AccountRepository
+	generator   *PasswordGeneratorService
+	auditLog    *AuditLogService
+}
+
+func NewAccountTypeService() *AccountTypeService {
+	return &AccountTypeService{
+		repo:        repository.NewAccountTypeRepository(),
+		accountRepo: repository.NewAccountRepository(),
+		generator:   NewPasswordGeneratorService(),
+		auditLog:    NewAuditLogService(),
+	}
+}
+
+// GetTypes returns all account types in display order
+func (s *AccountTypeService) GetTypes() ([]models.AccountTypeConfig, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+// SaveType creates an account type, or updates it when ID is set. Renaming a
+// type moves its accounts and default password policy along.
+func (s *AccountTypeService) SaveType(accountType *models.AccountTypeConfig) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	accountType.Name = models.AccountType(strings.TrimSpace(string(accountType.Name)))
+	if err := validateAccountType(accountType); err != nil {
+		return err
+	}
+	if conflict, _ := s.repo.FindByName(accountType.Name); conflict != nil && conflict.ID != accountType.ID {
+		return apperrors.NewAccountTypeExists(string(accountType.Name))
+	}
+
+	oldName := accountType.Name
+	if accountType.ID == 0 {
+		if err := s.repo.Create(accountType); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(accountType.ID)
+		if err != nil {
+			return apperrors.NewAccountTypeNotFound(fmt.Sprint(accountType.ID))
+		}
+		oldName = existing.Name
+		accountType.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(accountType, oldName); err != nil {
+			return err
+		}
+		if oldName != accountType.Name {
+			s.renameTypePolicy(oldName, accountType.Name)
+		}
+	}
+
+	cache.InvalidateStats()
+
+	changes := map[string]interface{}{
+		"name":          accountType.Name,
+		"validity_days": accountType.ValidityDays,
+		"expires":       accountType.Expires,
+	}
+	if oldName != accountType.Name {
+		changes["renamed_from"] = oldName
+	}
+	if accountType.RenewalPrice != nil {
+		changes["renewal_price"] = *accountType.RenewalPrice
+	}
+	s.auditLog.LogConfigChange("account_type", currentActor(), changes)
+	return nil
+}
+
+// DeleteType removes an account type that no account uses
+func (s *AccountTypeService) DeleteType(id uint) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	accountType, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewAccountTypeNotFound(fmt.Sprint(id))
+	}
+	count, err := s.accountRepo.CountByType(accountType.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.NewAccountTypeInUse(string(accountType.Name), count)
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.renameTypePolicy(accountType.Name, "")
+	cache.InvalidateStats()
+
+	s.auditLog.LogConfigChange("account_type", currentActor(), map[string]interface{}{
+		"name":    accountType.Name,
+		"deleted": true,
+	})
+	return nil
+}
+
+// renameTypePolicy moves the default password policy of a type, or drops it
+// when newName is empty
+func (s *AccountTypeService) renameTypePolicy(oldName, newName models.AccountType) {
+	policies, err := s.generator.GetTypePolicies()
+	if err != nil {
+		return
+	}
+	policy, ok := policies[string(oldName)]
+	if !ok {
+		return
+	}
+	delete(policies, string(oldName))
+	if newName != "" {
+		policies[string(newName)] = policy
+	}
+	s.generator.saveTypePolicies(policies)
+}
+
+func validateAccountType(accountType *models.AccountTypeConfig) error {
+	switch {
+	case accountType.Name == "":
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "类型名称不能为空")
+	case len(accountType.Name) > maxAccountTypeName:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("类型名称不能超过 %d 个字符", maxAccountTypeName))
+	case accountType.ValidityDays < 0:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "有效天数不能为负数")
+	case accountType.RenewalPrice != nil && *accountType.RenewalPrice < 0:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "续费价格不能为负数")
+	}
+	return nil
+}
+
+// findAccountType looks up the configuration of an account type by name
+func findAccountType(repo *repository.AccountTypeRepository, name string) (*models.AccountTypeConfig, error) {
+	accountType, err := repo.FindByName(models.AccountType(name))
+	if err != nil {
+		return nil, apperrors.NewAccountTypeNotFound(name)
+	}
+	return accountType, nil
+}
+
+// defaultExpiry returns the expiry date of a new account of a type, nil for
+// types that never expire
+func defaultExpiry(emailRepo *repository.EmailRepository, accountType *models.AccountTypeConfig) *time.Time {
+	if !accountType.Expires {
+		return nil
+	}
+	expire := time.Now().AddDate(0, 0, validityDays(emailRepo, accountType))
+	return &expire
+}
+
+// validityDays returns the days one validity period of a type lasts
+func validityDays(emailRepo *repository.EmailRepository, accountType *models.AccountTypeConfig) int {
+	if accountType.ValidityDays > 0 {
+		return accountType.ValidityDays
+	}
+	days := 30
+	if sysConfig, _ := emailRepo.GetSystemConfig(); sysConfig != nil {
+		days = sysConfig.DefaultValidityDays
+	}
+	return days
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/utils"
+)
+
+// Number of entries between two periodic checkpoints
+const auditCheckpointInterval = 100
+
+// Number of entries read at a time while verifying the chain
+const auditVerifyBatch = 500
+
+// auditChain serializes appends, since every service has its own
+// AuditLogService and each entry must link to the one written before it
+var auditChain struct {
+	mu     sync.Mutex
+	sealed bool // Entries from before chaining have been linked
+}
+
+// auditEntryHash links an entry to the previous one. Every stored field
+// except the id and CreatedAt is covered.
+func auditEntryHash(log *models.AuditLog) string {
+	fields, _ := json.Marshal([]interface{}{
+		log.PrevHash,
+		log.Timestamp.UnixNano(),
+		log.User,
+		log.Action,
+		log.ResourceType,
+		log.ResourceID,
+		log.IPAddress,
+		log.Details,
+		log.Success,
+		log.ErrorMessage,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkpointMessage is the data signed for a checkpoint or anchor
+func checkpointMessage(c *models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("account-manager audit %s\n%d\n%d\n%d\n%s\n%s",
+		c.Kind, c.FirstLogID, c.LastLogID, c.Entries, c.PrevHash, c.Hash))
+}
+
+// appendLog writes an entry at the end of the chain. The caller holds auditChain.mu.
+func (s *AuditLogService) appendLog(log *models.AuditLog) error {
+	err := s.sealUnchained()
+	if err != nil {
+		return err
+	}
+
+	log.PrevHash, err = s.headHash()
+	if err != nil {
+		return err
+	}
+	log.Hash = auditEntryHash(log)
+	if err := s.repo.Create(log); err != nil {
+		return err
+	}
+
+	if err := s.checkpointIfDue(log); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to write audit checkpoint")
+	}
+	return nil
+}
+
+// headHash returns the hash the next entry links to. When every entry has
+// been pruned that is the hash recorded by the newest anchor.
+func (s *AuditLogService) headHash() (string, error) {
+	last, err := s.repo.Last()
+	if err != nil {
+		return "", err
+	}
+	if last != nil {
+		return last.Hash, nil
+	}
+	anchor, err := s.repo.LastCheckpoint(models.CheckpointAnchor)
+	if err != nil || anchor == nil {
+		return "", err
+	}
+	return anchor.Hash, nil
+}
+
+// sealUnchained links the entries written before chaining was introduced.
+// It only does work once per process. The caller holds auditChain.mu.
+func (s *AuditLogService) sealUnchained() error {
+	if auditChain.sealed {
+		return nil
+	}
+	logs, err := s.repo.FindUnchained()
+	if err != nil {
+		return err
+	}
+	auditChain.sealed = true
+	if len(logs) == 0 {
+		return nil
+	}
+
+	prev := ""
+	for i := range logs {
+		logs[i].PrevHash = prev
+		logs[i].Hash = auditEntryHash(&logs[i])
+		if err := s.repo.UpdateChain(logs[i].ID, logs[i].PrevHash, logs[i].Hash); err != nil {
+			return err
+		}
+		prev = logs[i].Hash
+	}
+
+	logger.WithField("entries", len(logs)).Info("Existing audit log entries chained")
+	return nil
+}
+
+// checkpointIfDue signs the chain head every auditCheckpointInterval entries.
+// Checkpoints need the vault key and are skipped while it is locked.
+func (s *AuditLogService) checkpointIfDue(head *models.AuditLog) error {
+	if !utils.HasEncryptionKey() {
+		return nil
+	}
+	last, err := s.repo.LastCheckpoint(models.CheckpointPeriodic)
+	if err != nil {
+		return err
+	}
+	if last != nil && head.ID-last.LastLogID < auditCheckpointInterval {
+		return nil
+	}
+	return s.writeCheckpoint(head, last)
+}
+
+// Checkpoint signs the current chain head if it has entries not yet covered
+// by a checkpoint. It is called before the vault locks.
+func (s *AuditLogService) Checkpoint() error {
+	if !utils.HasEncryptionKey() {
+		return nil
+	}
+
+	auditChain.mu.Lock()
+	defer auditChain.mu.Unlock()
+
+	head, err := s.repo.Last()
+	if err != nil || head == nil {
+		return err
+	}
+	last, err := s.repo.LastCheckpoint(models.CheckpointPeriodic)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.LastLogID >= head.ID {
+		return nil
+	}
+	return s.writeCheckpoint(head, last)
+}
+
+func (s *AuditLogService) writeCheckpoint(head *models.AuditLog, previous *models.AuditCheckpoint) error {
+	checkpoint := &models.AuditCheckpoint{
+		Kind:       models.CheckpointPeriodic,
+		FirstLogID: 1,
+		LastLogID:  head.ID,
+		Hash:       head.Hash,
+	}
+	if previous != nil {
+		checkpoint.FirstLogID = previous.LastLogID + 1
+		checkpoint.PrevHash = previous.Hash
+	}
+	checkpoint.Entries = int64(checkpoint.LastLogID - checkpoint.FirstLogID + 1)
+
+	if err := s.sign(checkpoint); err != nil {
+		return err
+	}
+	return s.repo.CreateCheckpoint(checkpoint)
+}
+
+// sign sets the signature of a checkpoint, creating the signing key on first use
+func (s *AuditLogService) sign(checkpoint *models.AuditCheckpoint) error {
+	key, err := s.signingKey(true)
+	if err != nil {
+		return err
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpointMessage(checkpoint)))
+	return nil
+}
+
+// signingKey decrypts the checkpoint signing key. It returns nil when no key
+// exists and create is false.
+func (s *AuditLogService) signingKey(create bool) (ed25519.PrivateKey, error) {
+	if create {
+		release := utils.HoldKey()
+		defer release()
+	}
+	stored, err := s.repo.GetSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if stored == nil {
+		if !create {
+			return nil, nil
+		}
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := utils.Encrypt(base64.StdEncoding.EncodeToString(private.Seed()))
+		if err != nil {
+			return nil, err
+		}
+		err = s.repo.CreateSigningKey(&models.AuditSigningKey{
+			PrivateKey: encrypted,
+			PublicKey:  base64.StdEncoding.EncodeToString(public),
+		})
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Audit checkpoint signing key created")
+		return private, nil
+	}
+
+	encoded, err := utils.Decrypt(stored.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid audit signing key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// VerifyAuditChain walks the audit log and reports the first entry that was
+// modified, removed or reordered. Signatures of checkpoints and anchors are
+// only checked while the vault is unlocked; the hash links always are.
+// Since the hashes are not keyed, an unlocked check also requires the signing
+// key and a checkpoint at most auditCheckpointInterval entries behind the
+// head, so the chain cannot be rewritten after deleting the checkpoints.
+func (s *AuditLogService) VerifyAuditChain() (*models.AuditChainReport, error) {
+	if err := requirePermission(models.PermViewAudit); err != nil {
+		return nil, err
+	}
+	return s.verifyChain()
+}
+
+func (s *AuditLogService) verifyChain() (*models.AuditChainReport, error) {
+	auditChain.mu.Lock()
+	err := s.sealUnchained()
+	auditChain.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AuditChainReport{Valid: true}
+	broken := func(id uint, reason string) (*models.AuditChainReport, error) {
+		report.Valid = false
+		report.BrokenAt = id
+		report.Reason = reason
+		return report, nil
+	}
+
+	var public ed25519.PublicKey
+	if utils.HasEncryptionKey() {
+		private, err := s.signingKey(false)
+		if err != nil {
+			return nil, err
+		}
+		if private != nil {
+			public = private.Public().(ed25519.PublicKey)
+		}
+		report.SignaturesVerified = true
+	}
+	if report.SignaturesVerified && public == nil {
+		// The key is created by the first checkpoint, which is written at
+		// the latest when the vault unlocks
+		head, err := s.repo.Last()
+		if err != nil {
+			return nil, err
+		}
+		if head != nil {
+			return broken(head.ID, "检查点签名密钥缺失")
+		}
+	}
+	signatureValid := func(c *models.AuditCheckpoint) bool {
+		if !report.SignaturesVerified {
+			return true
+		}
+		signature, err := base64.StdEncoding.DecodeString(c.Signature)
+		return err == nil && public != nil && ed25519.Verify(public, checkpointMessage(c), signature)
+	}
+
+	// Pruned ranges come first and must follow each other
+	anchors, err := s.repo.FindCheckpoints(models.CheckpointAnchor)
+	if err != nil {
+		return nil, err
+	}
+	prev := ""
+	var after uint
+	for i := range anchors {
+		anchor := &anchors[i]
+		if anchor.PrevHash != prev {
+			return broken(anchor.FirstLogID, "已清理的日志区间不连续")
+		}
+		if !signatureValid(anchor) {
+			return broken(anchor.FirstLogID, "清理锚点签名无效")
+		}
+		prev = anchor.Hash
+		after = anchor.LastLogID
+		report.Anchors++
+	}
+
+	checkpoints, err := s.repo.FindCheckpoints(models.CheckpointPeriodic)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[uint]*models.AuditCheckpoint)
+	for i := range checkpoints {
+		checkpoint := &checkpoints[i]
+		if checkpoint.LastLogID <= after {
+			continue
+		}
+		if !signatureValid(checkpoint) {
+			return broken(checkpoint.LastLogID, "检查点签名无效")
+		}
+		pending[checkpoint.LastLogID] = checkpoint
+	}
+
+	// Entries after the last anchor or checkpoint
+	var unsigned int
+	var firstUnsigned uint
+	for {
+		logs, err := s.repo.FindAfter(after, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		if len(logs) == 0 {
+			break
+		}
+		for i := range logs {
+			entry := &logs[i]
+			if entry.PrevHash != prev {
+				return broken(entry.ID, "前一条记录被删除或修改")
+			}
+			if auditEntryHash(entry) != entry.Hash {
+				return broken(entry.ID, "记录内容被修改")
+			}
+			if checkpoint, ok := pending[entry.ID]; ok {
+				if checkpoint.Hash != entry.Hash {
+					return broken(entry.ID, "记录与已签名的检查点不符")
+				}
+				delete(pending, entry.ID)
+				report.Checkpoints++
+				unsigned, firstUnsigned = 0, 0
+			} else {
+				if unsigned == 0 {
+					firstUnsigned = entry.ID
+				}
+				unsigned++
+			}
+			prev = entry.Hash
+			after = entry.ID
+			report.Entries++
+		}
+	}
+
+	// A signed checkpoint past the end means entries were cut off
+	var missing uint
+	for id := range pending {
+		if missing == 0 || id < missing {
+			missing = id
+		}
+	}
+	if missing != 0 {
+		return broken(missing, "已签名检查点之前的记录被删除")
+	}
+	if report.SignaturesVerified && unsigned > auditCheckpointInterval {
+		return broken(firstUnsigned, "最近的记录缺少签名检查点")
+	}
+
+	return report, nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
 	"account-manager/internal/models"
 	"account-manager/internal/repository"
 )
@@ -38,7 +40,9 @@ func (s *AuditLogService) Log(action, resourceType string, resourceID uint, user
 		ErrorMessage: errorMsg,
 	}
 
-	return s.repo.Create(log)
+	auditChain.mu.Lock()
+	defer auditChain.mu.Unlock()
+	return s.appendLog(log)
 }
 
 // LogAccountView logs account viewing
@@ -67,6 +71,28 @@ func (s *AuditLogService) LogAccountDelete(accountID uint, user string, account
 	return s.Log("delete", "account", accountID, user, details, true, "")
 }
 
+// LogAccountRestore logs an account taken out of the trash
+func (s *AuditLogService) LogAccountRestore(accountID uint, user string, account string) error {
+	details := map[string]interface{}{
+		"account": account,
+	}
+	return s.Log("restore", "account", accountID, user, details, true, "")
+}
+
+// LogAccountPurge logs an account removed from the trash for good
+func (s *AuditLogService) LogAccountPurge(accountID uint, user string, account string) error {
+	details := map[string]interface{}{
+		"account": account,
+	}
+	return s.Log("purge", "account", accountID, user, details, true, "")
+}
+
+// LogAccountBulk logs a bulk operation on accounts as one entry. details
+// lists the accounts changed and the parameters of the operation.
+func (s *AuditLogService) LogAccountBulk(user string, action string, details map[string]interface{}) error {
+	return s.Log("bulk_"+action, "account", 0, user, details, true, "")
+}
+
 // LogPasswordView logs password viewing/copying
 func (s *AuditLogService) LogPasswordView(accountID uint, user string, action string) error {
 	details := map[string]interface{}{
@@ -92,25 +118,100 @@ func (s *AuditLogService) LogConfigChange(configType string, user string, change
 
 // GetLogs returns paginated audit logs
 func (s *AuditLogService) GetLogs(filter models.AuditLogFilter) (*models.PaginatedAuditLogs, error) {
+	if err := requirePermission(models.PermViewAudit); err != nil {
+		return nil, err
+	}
+
 	return s.repo.FindAll(filter)
 }
 
-// CleanupOldLogs deletes logs older than the retention period (default 90 days)
+// CleanupOldLogs deletes logs older than the retention period (default 90 days).
+// The pruned range is replaced by a signed anchor so the remaining chain can
+// still be verified, which needs the vault to be unlocked.
 func (s *AuditLogService) CleanupOldLogs(retentionDays int) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	if retentionDays <= 0 {
 		retentionDays = 90
 	}
 	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
-	return s.repo.DeleteOlderThan(cutoffDate)
+
+	// Pruning tampered entries would hide the tampering behind a valid anchor
+	report, err := s.verifyChain()
+	if err != nil {
+		return err
+	}
+	if !report.Valid {
+		return apperrors.NewAuditChainBroken(report.BrokenAt, report.Reason)
+	}
+
+	auditChain.mu.Lock()
+	count, err := s.prune(cutoffDate)
+	auditChain.mu.Unlock()
+	if err != nil || count == 0 {
+		return err
+	}
+
+	logger.WithField("entries", count).Info("Old audit logs removed")
+	return s.LogConfigChange("audit_log", currentActor(), map[string]interface{}{
+		"action":         "cleanup",
+		"retention_days": retentionDays,
+		"entries":        count,
+	})
+}
+
+// prune replaces the entries before date with an anchor. The caller holds auditChain.mu.
+func (s *AuditLogService) prune(date time.Time) (int64, error) {
+	firstID, lastID, count, err := s.repo.PruneRange(date)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	first, err := s.repo.FindByID(firstID)
+	if err != nil {
+		return 0, err
+	}
+	last, err := s.repo.FindByID(lastID)
+	if err != nil {
+		return 0, err
+	}
+
+	anchor := &models.AuditCheckpoint{
+		Kind:       models.CheckpointAnchor,
+		FirstLogID: firstID,
+		LastLogID:  lastID,
+		Entries:    count,
+		PrevHash:   first.PrevHash,
+		Hash:       last.Hash,
+	}
+	if err := s.sign(anchor); err != nil {
+		return 0, err
+	}
+	if err := s.repo.Prune(lastID, anchor); err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 // GetStats returns audit log statistics
 func (s *AuditLogService) GetStats() (map[string]int64, error) {
+	if err := requirePermission(models.PermViewAudit); err != nil {
+		return nil, err
+	}
+
 	return s.repo.GetStats()
 }
 
 // ExportToCSV exports audit logs to CSV format
 func (s *AuditLogService) ExportToCSV(filter models.AuditLogFilter) (string, error) {
+	if err := requirePermission(models.PermViewAudit); err != nil {
+		return "", err
+	}
+
 	// Set a large page size to get all logs
 	filter.PageSize = 10000
 	filter.Page = 1
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+)
+
+// Longest text value of a custom field, in characters
+const maxFieldValue = 1000
+
+// Custom field names are used as keys, e.g. in copy formats
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,49}$`)
+
+type CustomFieldService struct {
+	repo     *repository.CustomFieldRepository
+	typeRepo *repository.AccountTypeRepository
+	auditLog *AuditLogService
+}
+
+func NewCustomFieldService() *CustomFieldService {
+	return &CustomFieldService{
+		repo:     repository.NewCustomFieldRepository(),
+		typeRepo: repository.NewAccountTypeRepository(),
+		auditLog: NewAuditLogService(),
+	}
+}
+
+// GetFields returns the custom fields of an account type, or of every type
+// when accountType is empty
+func (s *CustomFieldService) GetFields(accountType string) ([]models.CustomField, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if accountType == "" {
+		return s.repo.FindAll()
+	}
+	return s.repo.FindByType(models.AccountType(accountType))
+}
+
+// SaveField creates a custom field, or updates it when ID is set. Existing
+// values are kept; a field cannot become secret or stop being secret while
+// accounts have a value for it, since that would need re-encryption.
+func (s *CustomFieldService) SaveField(field *models.CustomField) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if err := normalizeCustomField(field); err != nil {
+		return err
+	}
+	if _, err := findAccountType(s.typeRepo, string(field.AccountType)); err != nil {
+		return err
+	}
+	if conflict, _ := s.repo.FindByName(field.AccountType, field.Name); conflict != nil && conflict.ID != field.ID {
+		return apperrors.NewFieldExists(field.Name)
+	}
+
+	if field.ID == 0 {
+		if err := s.repo.Create(field); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(field.ID)
+		if err != nil {
+			return apperrors.NewFieldNotFound(fmt.Sprint(field.ID))
+		}
+		if (existing.Type == models.FieldTypeSecret) != (field.Type == models.FieldTypeSecret) {
+			count, err := s.repo.CountValues(field.ID)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("已有 %d 个账号填写了字段 %s，不能更改其是否加密", count, existing.Name))
+			}
+		}
+		field.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(field); err != nil {
+			return err
+		}
+	}
+
+	s.auditLog.LogConfigChange("custom_field", currentActor(), map[string]interface{}{
+		"account_type": field.AccountType,
+		"field":        field.Name,
+		"type":         field.Type,
+		"required":     field.Required,
+	})
+	return nil
+}
+
+// DeleteField removes a custom field and its value on every account
+func (s *CustomFieldService) DeleteField(id uint) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	field, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewFieldNotFound(fmt.Sprint(id))
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("custom_field", currentActor(), map[string]interface{}{
+		"account_type": field.AccountType,
+		"field":        field.Name,
+		"deleted":      true,
+	})
+	return nil
+}
+
+func normalizeCustomField(field *models.CustomField) error {
+	field.Name = strings.TrimSpace(field.Name)
+	field.Label = strings.TrimSpace(field.Label)
+	if !fieldNamePattern.MatchString(field.Name) {
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "字段名称须以字母开头，只能包含字母、数字和下划线，且不超过 50 个字符")
+	}
+
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeSecret, models.FieldTypeDate,
+		models.FieldTypeNumber, models.FieldTypeURL:
+		field.Options = nil
+	case models.FieldTypeEnum:
+		seen := make(map[string]bool)
+		options := make([]string, 0, len(field.Options))
+		for _, option := range field.Options {
+			option = strings.TrimSpace(option)
+			if option != "" && !seen[option] {
+				seen[option] = true
+				options = append(options, option)
+			}
+		}
+		if len(options) == 0 {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, "枚举字段至少需要一个选项")
+		}
+		field.Options = options
+	default:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("不支持的字段类型 %s", field.Type))
+	}
+	return nil
+}
+
+// fieldDisplayName is how a field is named in error messages
+func fieldDisplayName(field *models.CustomField) string {
+	if field.Label != "" {
+		return field.Label
+	}
+	return field.Name
+}
+
+// validateFieldValue checks a non-empty value against its field and returns
+// it in stored form
+func validateFieldValue(field *models.CustomField, value string) (string, error) {
+	name := fieldDisplayName(field)
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeSecret:
+		if utf8.RuneCountInString(value) > maxFieldValue {
+			return "", apperrors.NewInvalidFieldValue(name, fmt.Sprintf("不能超过 %d 个字符", maxFieldValue))
+		}
+	case models.FieldTypeDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return "", apperrors.NewInvalidFieldValue(name, "日期格式应为 YYYY-MM-DD")
+		}
+	case models.FieldTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", apperrors.NewInvalidFieldValue(name, "不是有效的数字")
+		}
+		value = strconv.FormatFloat(n, 'f', -1, 64)
+	case models.FieldTypeEnum:
+		for _, option := range field.Options {
+			if value == option {
+				return value, nil
+			}
+		}
+		return "", apperrors.NewInvalidFieldValue(name, "不是可选的值")
+	case models.FieldTypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "", apperrors.NewInvalidFieldValue(name, "不是有效的网址")
+		}
+	}
+	return value, nil
+}
+
+// prepareFieldValues validates the custom field values given for an account
+// of a type. Fields missing from values keep their current value; an empty
+// value clears the field. Required fields missing from both are only
+// rejected when checkMissing is set, so accounts created before a field
+// became required can still be edited. Current values of fields the type
+// does not have are cleared, which happens when the account changes type.
+// It returns the values to write, with secrets encrypted, and the field ids
+// to clear.
+func prepareFieldValues(repo *repository.CustomFieldRepository, accountType models.AccountType, values map[string]string, current []models.AccountFieldValue, checkMissing bool) ([]models.AccountFieldValue, []uint, error) {
+	fields, err := repo.FindByType(accountType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byName := make(map[string]*models.CustomField, len(fields))
+	for i := range fields {
+		byName[fields[i].Name] = &fields[i]
+	}
+	for name := range values {
+		if byName[name] == nil {
+			return nil, nil, apperrors.NewInvalidFieldValue(name, fmt.Sprintf("不是 %s 类型的字段", accountType))
+		}
+	}
+	has := make(map[uint]bool, len(current))
+	for _, v := range current {
+		has[v.FieldID] = true
+	}
+
+	var set []models.AccountFieldValue
+	var clear []uint
+	for i := range fields {
+		field := &fields[i]
+		value, given := values[field.Name]
+		value = strings.TrimSpace(value)
+		if !given && (has[field.ID] || !checkMissing) {
+			continue
+		}
+		if value == "" {
+			if field.Required {
+				return nil, nil, apperrors.NewInvalidFieldValue(fieldDisplayName(field), "为必填项")
+			}
+			if has[field.ID] {
+				clear = append(clear, field.ID)
+			}
+			continue
+		}
+
+		value, err := validateFieldValue(field, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		stored := models.AccountFieldValue{FieldID: field.ID}
+		if field.Type == models.FieldTypeSecret {
+			if stored.SecretValue, err = encryptField(value); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			stored.Value = value
+		}
+		set = append(set, stored)
+	}
+
+	inType := make(map[uint]bool, len(fields))
+	for _, field := range fields {
+		inType[field.ID] = true
+	}
+	for _, v := range current {
+		if !inType[v.FieldID] {
+			clear = append(clear, v.FieldID)
+		}
+	}
+	return set, clear, nil
+}
+
+// attachCustomFields fills CustomFields of the given accounts. Secret values
+// are decrypted when reveal is set and left empty otherwise.
+func attachCustomFields(repo *repository.CustomFieldRepository, accounts []models.Account, reveal bool) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(accounts))
+	index := make(map[uint]*models.Account, len(accounts))
+	for i := range accounts {
+		ids[i] = accounts[i].ID
+		index[accounts[i].ID] = &accounts[i]
+		accounts[i].CustomFields = map[string]string{}
+	}
+
+	values, err := repo.FindValues(ids)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	fields, err := repo.FindAll()
+	if err != nil {
+		return err
+	}
+	names := make(map[uint]string, len(fields))
+	for _, field := range fields {
+		names[field.ID] = field.Name
+	}
+
+	for _, v := range values {
+		account, name := index[v.AccountID], names[v.FieldID]
+		if account == nil || name == "" {
+			continue
+		}
+		value := v.Value
+		if v.SecretValue != "" {
+			value = ""
+			if reveal {
+				if value, err = decryptField(v.SecretValue); err != nil {
+					logger.WithFields(map[string]interface{}{
+						"account_id": v.AccountID,
+						"field_id":   v.FieldID,
+						"error":      err.Error(),
+					}).Error("Failed to decrypt custom field")
+					account.DecryptError = true
+					continue
+				}
+			}
+		}
+		account.CustomFields[name] = value
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// Longest values of a customer, see models.Customer
+const (
+	maxCustomerName   = 100
+	maxCustomerEmail  = 255
+	maxCustomerHandle = 100
+)
+
+type CustomerService struct {
+	repo     *repository.CustomerRepository
+	auditLog *AuditLogService
+}
+
+func NewCustomerService() *CustomerService {
+	return &CustomerService{
+		repo:     repository.NewCustomerRepository(),
+		auditLog: NewAuditLogService(),
+	}
+}
+
+// GetCustomers returns the customers whose name, email or handle contains
+// search. Notes are left out.
+func (s *CustomerService) GetCustomers(search string, page, pageSize int) (*models.PaginatedCustomers, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	result, err := s.repo.FindAll(strings.TrimSpace(search), page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Data {
+		result.Data[i].Notes = ""
+	}
+	return result, nil
+}
+
+// GetCustomer returns a customer with its notes decrypted
+func (s *CustomerService) GetCustomer(id uint) (*models.Customer, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.NewCustomerNotFound(id)
+	}
+	if customer.Notes, err = decryptField(customer.Notes); err != nil {
+		return nil, apperrors.NewDecryptionFailed(err)
+	}
+	return customer, nil
+}
+
+// SaveCustomer creates a customer, or updates it when ID is set
+func (s *CustomerService) SaveCustomer(customer *models.Customer) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+	if err := normalizeCustomer(customer); err != nil {
+		return err
+	}
+
+	notes, err := encryptField(customer.Notes)
+	if err != nil {
+		return err
+	}
+	saved := *customer
+	saved.Notes = notes
+
+	action := "create"
+	if saved.ID == 0 {
+		if err := s.repo.Create(&saved); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(saved.ID)
+		if err != nil {
+			return apperrors.NewCustomerNotFound(saved.ID)
+		}
+		saved.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(&saved); err != nil {
+			return err
+		}
+		action = "update"
+	}
+	customer.ID = saved.ID
+	customer.CreatedAt = saved.CreatedAt
+	customer.UpdatedAt = saved.UpdatedAt
+
+	s.auditLog.Log(action, "customer", customer.ID, currentActor(), map[string]interface{}{
+		"name":   customer.Name,
+		"email":  customer.Email,
+		"handle": customer.Handle,
+	}, true, "")
+	return nil
+}
+
+// DeleteCustomer deletes a customer and unlinks its accounts and sales
+func (s *CustomerService) DeleteCustomer(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	customer, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewCustomerNotFound(id)
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	cache.InvalidateStats()
+	s.auditLog.Log("delete", "customer", id, currentActor(), map[string]interface{}{
+		"name":     customer.Name,
+		"accounts": customer.AccountCount,
+	}, true, "")
+	return nil
+}
+
+// AssignAccounts links accounts to a customer and returns how many were
+// linked. Accounts that are not sold are skipped.
+func (s *CustomerService) AssignAccounts(customerID uint, accountIDs []uint) (int64, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, err
+	}
+	customer, err := s.repo.FindByID(customerID)
+	if err != nil {
+		return 0, apperrors.NewCustomerNotFound(customerID)
+	}
+
+	linked, err := s.repo.AssignAccounts(&customer.ID, accountIDs)
+	if err != nil {
+		return 0, err
+	}
+	if linked > 0 {
+		cache.InvalidateStats()
+		s.auditLog.LogAccountUpdate(0, currentActor(), map[string]interface{}{
+			"accounts": accountIDs,
+			"customer": customer.Name,
+		})
+	}
+	return linked, nil
+}
+
+// UnassignAccounts unlinks accounts from their customer and returns how many
+// were unlinked
+func (s *CustomerService) UnassignAccounts(accountIDs []uint) (int64, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, err
+	}
+
+	unlinked, err := s.repo.AssignAccounts(nil, accountIDs)
+	if err != nil {
+		return 0, err
+	}
+	if unlinked > 0 {
+		cache.InvalidateStats()
+		s.auditLog.LogAccountUpdate(0, currentActor(), map[string]interface{}{
+			"accounts": accountIDs,
+			"customer": nil,
+		})
+	}
+	return unlinked, nil
+}
+
+// GetExpiryOverview lists, per customer, the accounts expiring in [from, to).
+// customerID 0 covers every customer; customers with nothing expiring are
+// left out. Accounts that already expired are flagged.
+func (s *CustomerService) GetExpiryOverview(customerID uint, from, to time.Time) ([]models.CustomerExpiry, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, apperrors.New(apperrors.ErrCodeInvalidInput, "开始日期必须早于结束日期")
+	}
+	if customerID != 0 {
+		if _, err := s.repo.FindByID(customerID); err != nil {
+			return nil, apperrors.NewCustomerNotFound(customerID)
+		}
+	}
+
+	accounts, err := s.repo.FindExpiring(customerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, account := range accounts {
+		if len(ids) == 0 || ids[len(ids)-1] != *account.CustomerID {
+			ids = append(ids, *account.CustomerID)
+		}
+	}
+	customers, err := s.repo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Customer, len(customers))
+	for i := range customers {
+		byID[customers[i].ID] = &customers[i]
+	}
+
+	now := time.Now()
+	overview := []models.CustomerExpiry{}
+	for _, account := range accounts {
+		if n := len(overview); n == 0 || overview[n-1].CustomerID != *account.CustomerID {
+			entry := models.CustomerExpiry{CustomerID: *account.CustomerID}
+			if customer := byID[entry.CustomerID]; customer != nil {
+				entry.Name = customer.Name
+				entry.Email = customer.Email
+				entry.Handle = customer.Handle
+			}
+			overview = append(overview, entry)
+		}
+		entry := &overview[len(overview)-1]
+		entry.Accounts = append(entry.Accounts, models.ExpiringAccount{
+			ID:          account.ID,
+			Account:     account.Account,
+			AccountType: account.AccountType,
+			Status:      account.Status,
+			ExpireAt:    *account.ExpireAt,
+			Expired:     account.ExpireAt.Before(now),
+		})
+	}
+	return overview, nil
+}
+
+// normalizeCustomer trims and checks the fields of a customer
+func normalizeCustomer(customer *models.Customer) error {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.Email = strings.TrimSpace(customer.Email)
+	customer.Handle = strings.TrimSpace(customer.Handle)
+	customer.Notes = strings.TrimSpace(customer.Notes)
+
+	switch {
+	case customer.Name == "":
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "客户名称不能为空")
+	case utf8.RuneCountInString(customer.Name) > maxCustomerName:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("客户名称不能超过 %d 个字符", maxCustomerName))
+	case utf8.RuneCountInString(customer.Email) > maxCustomerEmail:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("邮箱不能超过 %d 个字符", maxCustomerEmail))
+	case utf8.RuneCountInString(customer.Handle) > maxCustomerHandle:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("联系账号不能超过 %d 个字符", maxCustomerHandle))
+	}
+	if customer.Email != "" {
+		if addr, err := mail.ParseAddress(customer.Email); err != nil || addr.Address != customer.Email {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, "邮箱格式不正确")
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"account-manager/internal/config"
+	apperrors "account-manager/internal/errors"
 	"account-manager/internal/logger"
 	"account-manager/internal/models"
 	"account-manager/internal/queue"
@@ -34,6 +35,13 @@ func NewEmailService() *EmailService {
 }
 
 func (s *EmailService) GetConfig() (*models.EmailConfig, error) {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
 	config, err := s.repo.GetConfig()
 	if err != nil {
 		return nil, err
@@ -42,15 +50,25 @@ func (s *EmailService) GetConfig() (*models.EmailConfig, error) {
 	// Decrypt password for display
 	if config.SenderPassword != "" {
 		decrypted, err := utils.Decrypt(config.SenderPassword)
-		if err == nil {
-			config.SenderPassword = decrypted
+		if err != nil {
+			return nil, apperrors.NewDecryptionFailed(err)
 		}
+		config.SenderPassword = decrypted
 	}
 
 	return config, nil
 }
 
 func (s *EmailService) UpdateConfig(smtpHost string, smtpPort int, senderEmail, senderPassword, recipientEmail string, isActive bool) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+
 	config, err := s.repo.GetConfig()
 	if err != nil {
 		config = &models.EmailConfig{}
@@ -104,12 +122,17 @@ func (s *EmailService) sendEmailSync(subject, content string) error {
 		return fmt.Errorf("邮件服务未启用")
 	}
 
+	// Queued emails may be sent by the scheduler, so this does not count as user activity
+	if !utils.HasEncryptionKey() {
+		return apperrors.NewVaultLocked()
+	}
+
 	// Decrypt password
 	password := ""
 	if config.SenderPassword != "" {
 		decrypted, err := utils.Decrypt(config.SenderPassword)
 		if err != nil {
-			return fmt.Errorf("解密密码失败: %v", err)
+			return apperrors.NewDecryptionFailed(err)
 		}
 		password = decrypted
 	}
@@ -200,6 +223,13 @@ func (s *EmailService) GetQueueSize() int {
 }
 
 func (s *EmailService) TestSend() error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	subject := "账号管理系统 - 测试邮件"
 	content := `
 	<html>
@@ -215,6 +245,10 @@ func (s *EmailService) TestSend() error {
 }
 
 func (s *EmailService) GetLogs(page, pageSize int) ([]models.EmailLog, int64, error) {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return nil, 0, err
+	}
+
 	return s.repo.GetLogs(page, pageSize)
 }
 
@@ -223,6 +257,10 @@ func (s *EmailService) GetSystemConfig() (*models.SystemConfig, error) {
 }
 
 func (s *EmailService) UpdateSystemConfig(defaultValidityDays, reminderDaysBefore int, copyFormat, emailFormat, accountTypes, accountStatuses string) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
 	config, err := s.repo.GetSystemConfig()
 	if err != nil {
 		config = &models.SystemConfig{}
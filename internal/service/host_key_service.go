@@ -73,6 +73,10 @@ func (s *HostKeyService) VerifyHostKey(host string, port int) ssh.HostKeyCallbac
 
 // TrustHostKey marks a host key as trusted
 func (s *HostKeyService) TrustHostKey(id uint) error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+
 	hostKey, err := s.repo.FindByFingerprint("")
 	if err != nil {
 		// Find by ID instead
@@ -99,11 +103,19 @@ func (s *HostKeyService) TrustHostKey(id uint) error {
 
 // GetAllHostKeys returns all stored host keys
 func (s *HostKeyService) GetAllHostKeys() ([]models.HostKey, error) {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return nil, err
+	}
+
 	return s.repo.FindAll()
 }
 
 // DeleteHostKey deletes a host key
 func (s *HostKeyService) DeleteHostKey(id uint) error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+
 	return s.repo.Delete(id)
 }
 
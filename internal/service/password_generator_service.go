@@ -0,0 +1,273 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// Limits accepted for password policies
+const (
+	minPolicyLength = 4
+	maxPolicyLength = 128
+	minPolicyWords  = 3
+	maxPolicyWords  = 12
+)
+
+type PasswordGeneratorService struct {
+	repo      *repository.PasswordPolicyRepository
+	emailRepo *repository.EmailRepository
+	auditLog  *AuditLogService
+}
+
+func NewPasswordGeneratorService() *PasswordGeneratorService {
+	return &PasswordGeneratorService{
+		repo:      repository.NewPasswordPolicyRepository(),
+		emailRepo: repository.NewEmailRepository(),
+		auditLog:  NewAuditLogService(),
+	}
+}
+
+// Generate creates a password with the named policy
+func (s *PasswordGeneratorService) Generate(policyName string) (*models.GeneratedPassword, error) {
+	if policyName == "" {
+		policyName = models.DefaultPasswordPolicy
+	}
+
+	policy, err := s.repo.FindByName(policyName)
+	if err != nil {
+		return nil, apperrors.NewPolicyNotFound(policyName)
+	}
+
+	return generateWithPolicy(policy)
+}
+
+// GenerateForType creates a password with the default policy of an account type
+func (s *PasswordGeneratorService) GenerateForType(accountType string) (*models.GeneratedPassword, error) {
+	policies, err := s.GetTypePolicies()
+	if err != nil {
+		return nil, err
+	}
+	return s.Generate(policies[accountType])
+}
+
+func (s *PasswordGeneratorService) GetPolicies() ([]models.PasswordPolicy, error) {
+	return s.repo.FindAll()
+}
+
+// SavePolicy creates a policy, or updates it when ID is set
+func (s *PasswordGeneratorService) SavePolicy(policy *models.PasswordPolicy) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	policy.Name = strings.TrimSpace(policy.Name)
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	if conflict, _ := s.repo.FindByName(policy.Name); conflict != nil && conflict.ID != policy.ID {
+		return apperrors.NewPolicyExists(policy.Name)
+	}
+
+	if policy.ID == 0 {
+		policy.BuiltIn = false
+		if err := s.repo.Create(policy); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(policy.ID)
+		if err != nil {
+			return apperrors.NewPolicyNotFound(policy.Name)
+		}
+		if existing.BuiltIn && existing.Name != policy.Name {
+			return apperrors.NewPolicyBuiltIn()
+		}
+		policy.BuiltIn = existing.BuiltIn
+		policy.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(policy); err != nil {
+			return err
+		}
+	}
+
+	s.auditLog.LogConfigChange("password_policy", currentActor(), map[string]interface{}{
+		"name": policy.Name,
+		"mode": policy.Mode,
+	})
+	return nil
+}
+
+// DeletePolicy removes a custom policy and any account type defaults using it
+func (s *PasswordGeneratorService) DeletePolicy(id uint) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	policy, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewPolicyNotFound(fmt.Sprint(id))
+	}
+	if policy.BuiltIn {
+		return apperrors.NewPolicyBuiltIn()
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	typePolicies, err := s.GetTypePolicies()
+	if err == nil {
+		changed := false
+		for accountType, name := range typePolicies {
+			if name == policy.Name {
+				delete(typePolicies, accountType)
+				changed = true
+			}
+		}
+		if changed {
+			s.saveTypePolicies(typePolicies)
+		}
+	}
+
+	s.auditLog.LogConfigChange("password_policy", currentActor(), map[string]interface{}{
+		"name":    policy.Name,
+		"deleted": true,
+	})
+	return nil
+}
+
+// GetTypePolicies returns the default policy name of each account type
+func (s *PasswordGeneratorService) GetTypePolicies() (map[string]string, error) {
+	policies := map[string]string{}
+
+	config, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.PasswordPolicies != "" {
+		if err := json.Unmarshal([]byte(config.PasswordPolicies), &policies); err != nil {
+			return nil, err
+		}
+	}
+
+	return policies, nil
+}
+
+// SetTypePolicy sets the default policy of an account type.
+// An empty policy name falls back to the default policy.
+func (s *PasswordGeneratorService) SetTypePolicy(accountType, policyName string) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if accountType == "" {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "账号类型不能为空")
+	}
+	if policyName != "" {
+		if _, err := s.repo.FindByName(policyName); err != nil {
+			return apperrors.NewPolicyNotFound(policyName)
+		}
+	}
+
+	policies, err := s.GetTypePolicies()
+	if err != nil {
+		return err
+	}
+	if policyName == "" {
+		delete(policies, accountType)
+	} else {
+		policies[accountType] = policyName
+	}
+
+	if err := s.saveTypePolicies(policies); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("password_policy", currentActor(), map[string]interface{}{
+		"accountType": accountType,
+		"policy":      policyName,
+	})
+	return nil
+}
+
+func (s *PasswordGeneratorService) saveTypePolicies(policies map[string]string) error {
+	config, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(policies)
+	if err != nil {
+		return err
+	}
+	config.PasswordPolicies = string(data)
+	return s.emailRepo.UpdateSystemConfig(config)
+}
+
+// generateWithPolicy runs the generator selected by the policy mode
+func generateWithPolicy(policy *models.PasswordPolicy) (*models.GeneratedPassword, error) {
+	result := &models.GeneratedPassword{Policy: policy.Name}
+	var err error
+
+	switch policy.Mode {
+	case models.PasswordModePassphrase:
+		result.Password, err = utils.GeneratePassphrase(policy.WordCount, policy.Separator, policy.Uppercase, policy.Digits)
+		result.EntropyBits = utils.PassphraseEntropy(policy.WordCount, policy.Digits)
+	case models.PasswordModePronounceable:
+		result.Password, err = utils.GeneratePronounceable(policy.Length, policy.Uppercase, policy.Digits)
+		result.EntropyBits = utils.PronounceableEntropy(policy.Length, policy.Digits)
+	default:
+		opts := passwordOptions(policy)
+		result.Password, err = utils.GeneratePassword(opts)
+		result.EntropyBits = utils.PasswordEntropy(opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func passwordOptions(policy *models.PasswordPolicy) utils.PasswordOptions {
+	return utils.PasswordOptions{
+		Length:           policy.Length,
+		Lowercase:        policy.Lowercase,
+		Uppercase:        policy.Uppercase,
+		Digits:           policy.Digits,
+		Symbols:          policy.Symbols,
+		ExcludeAmbiguous: policy.ExcludeAmbiguous,
+	}
+}
+
+func validatePolicy(policy *models.PasswordPolicy) error {
+	if policy.Name == "" {
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "策略名称不能为空")
+	}
+
+	switch policy.Mode {
+	case models.PasswordModeRandom:
+		if !policy.Lowercase && !policy.Uppercase && !policy.Digits && !policy.Symbols {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, "至少选择一种字符类型")
+		}
+		if policy.Length < minPolicyLength || policy.Length > maxPolicyLength {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("密码长度必须在 %d 到 %d 之间", minPolicyLength, maxPolicyLength))
+		}
+	case models.PasswordModePronounceable:
+		if policy.Length < minPolicyLength || policy.Length > maxPolicyLength {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("密码长度必须在 %d 到 %d 之间", minPolicyLength, maxPolicyLength))
+		}
+	case models.PasswordModePassphrase:
+		if policy.WordCount < minPolicyWords || policy.WordCount > maxPolicyWords {
+			return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("单词数必须在 %d 到 %d 之间", minPolicyWords, maxPolicyWords))
+		}
+	default:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "未知的生成模式")
+	}
+
+	return nil
+}
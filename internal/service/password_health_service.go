@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// Number of breach entries written per batch during import
+const breachImportBatch = 5000
+
+type PasswordHealthService struct {
+	repo       *repository.AccountRepository
+	breachRepo *repository.BreachRepository
+	auditLog   *AuditLogService
+}
+
+func NewPasswordHealthService() *PasswordHealthService {
+	return &PasswordHealthService{
+		repo:       repository.NewAccountRepository(),
+		breachRepo: repository.NewBreachRepository(),
+		auditLog:   NewAuditLogService(),
+	}
+}
+
+// CheckPassword scores a candidate password and looks it up in the breach list.
+// Nothing is stored.
+func (s *PasswordHealthService) CheckPassword(password string) *models.PasswordCheck {
+	strength := utils.EstimateStrength(password)
+	check := &models.PasswordCheck{
+		Score:       strength.Score,
+		EntropyBits: strength.EntropyBits,
+		Feedback:    strength.Feedback,
+	}
+	if password != "" {
+		if entry, err := s.breachRepo.FindByHash(breachHash(password)); err == nil {
+			check.Breached = true
+			check.BreachCount = entry.Count
+		}
+	}
+	return check
+}
+
+// RecheckAll recomputes the password health of every account, e.g. after a
+// breach list import or a key rotation, and returns the number checked
+func (s *PasswordHealthService) RecheckAll() (int, error) {
+	if err := requireUnlocked(); err != nil {
+		return 0, err
+	}
+
+	accounts, err := s.repo.FindAllSecrets()
+	if err != nil {
+		return 0, err
+	}
+	return s.recheck(accounts)
+}
+
+// RecheckPending computes the password health of accounts never checked,
+// such as accounts created before this feature existed
+func (s *PasswordHealthService) RecheckPending() (int, error) {
+	if err := requireUnlocked(); err != nil {
+		return 0, err
+	}
+
+	accounts, err := s.repo.FindUncheckedSecrets()
+	if err != nil {
+		return 0, err
+	}
+	if len(accounts) == 0 {
+		return 0, nil
+	}
+	return s.recheck(accounts)
+}
+
+func (s *PasswordHealthService) recheck(accounts []models.Account) (int, error) {
+	checked := 0
+	for i := range accounts {
+		account := &accounts[i]
+		if account.Password == "" {
+			continue
+		}
+
+		password, err := utils.Decrypt(account.Password)
+		if err != nil {
+			logger.WithFields(map[string]interface{}{
+				"account_id": account.ID,
+				"error":      err.Error(),
+			}).Warn("Skipping password health check")
+			continue
+		}
+		if err := assessPassword(s.breachRepo, account, password); err != nil {
+			return checked, err
+		}
+		if err := s.repo.UpdatePasswordHealth(account); err != nil {
+			return checked, err
+		}
+		checked++
+	}
+
+	if err := s.repo.RefreshReusedFlags(); err != nil {
+		return checked, err
+	}
+
+	logger.WithField("accounts", checked).Info("Password health checked")
+	return checked, nil
+}
+
+// GetStats counts weak, reused and breached passwords
+func (s *PasswordHealthService) GetStats() (*models.PasswordHealthStats, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetPasswordHealthStats()
+	if err != nil {
+		return nil, err
+	}
+	stats.BreachEntries, err = s.breachRepo.Count()
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ImportBreachFile imports SHA-1 hashes of breached passwords from a file or a
+// directory of files. Lines are "HASH[:COUNT]" with the full 40 character
+// hash, or "SUFFIX:COUNT" with the 35 character suffix in files named after
+// the 5 character hash prefix, as served by the Pwned Passwords range API.
+// Accounts are rechecked afterwards when the vault is unlocked.
+func (s *PasswordHealthService) ImportBreachFile(path string) (int, error) {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, apperrors.Wrap(err, apperrors.ErrCodeInvalidInput, "无法读取泄露密码文件")
+	}
+
+	imported := 0
+	if info.IsDir() {
+		err = filepath.Walk(path, func(file string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			n, err := s.importFile(file)
+			imported += n
+			return err
+		})
+	} else {
+		imported, err = s.importFile(path)
+	}
+	if err != nil {
+		return imported, err
+	}
+
+	s.auditLog.LogConfigChange("breach_database", currentActor(), map[string]interface{}{
+		"action":  "import",
+		"path":    path,
+		"entries": imported,
+	})
+	logger.WithField("entries", imported).Info("Breached password list imported")
+
+	if utils.HasEncryptionKey() {
+		if _, err := s.RecheckAll(); err != nil {
+			logger.WithField("error", err.Error()).Warn("Failed to recheck passwords after breach import")
+		}
+	}
+
+	return imported, nil
+}
+
+// ClearBreachData removes the imported breach list and the breached flags
+func (s *PasswordHealthService) ClearBreachData() error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if err := s.breachRepo.DeleteAll(); err != nil {
+		return err
+	}
+	s.auditLog.LogConfigChange("breach_database", currentActor(), map[string]interface{}{
+		"action": "clear",
+	})
+
+	if utils.HasEncryptionKey() {
+		_, err := s.RecheckAll()
+		return err
+	}
+	return nil
+}
+
+func (s *PasswordHealthService) importFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// Range files are named after the hash prefix, e.g. "21BD1.txt"
+	prefix := strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if len(prefix) != 5 || !isHex(prefix) {
+		prefix = ""
+	}
+
+	imported := 0
+	batch := make([]models.BreachedPassword, 0, breachImportBatch)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, countText, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) == 35 && prefix != "" {
+			hash = prefix + hash
+		}
+		if len(hash) != 40 || !isHex(hash) {
+			continue
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countText))
+		if err != nil || count < 1 {
+			count = 1
+		}
+
+		batch = append(batch, models.BreachedPassword{Hash: hash, Count: count})
+		if len(batch) == breachImportBatch {
+			if err := s.breachRepo.Upsert(batch); err != nil {
+				return imported, err
+			}
+			imported += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, err
+	}
+
+	if err := s.breachRepo.Upsert(batch); err != nil {
+		return imported, err
+	}
+	return imported + len(batch), nil
+}
+
+// assessPassword fills the password health fields of an account from its
+// plaintext password. Reuse flags are refreshed separately.
+func assessPassword(breachRepo *repository.BreachRepository, account *models.Account, password string) error {
+	if password == "" {
+		account.PasswordScore = 0
+		account.PasswordBreached = false
+		account.PasswordFingerprint = ""
+		account.PasswordCheckedAt = nil
+		return nil
+	}
+
+	fingerprint, err := utils.PasswordFingerprint(password)
+	if err != nil {
+		return err
+	}
+	_, err = breachRepo.FindByHash(breachHash(password))
+
+	now := time.Now()
+	account.PasswordScore = utils.EstimateStrength(password).Score
+	account.PasswordBreached = err == nil
+	account.PasswordFingerprint = fingerprint
+	account.PasswordCheckedAt = &now
+	return nil
+}
+
+// breachHash returns the upper-case hex SHA-1 used by breach lists
+func breachHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isHex(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789ABCDEFabcdef") == ""
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// Reasons recorded in PasswordHistory.Reason
+const (
+	historyReasonUpdate  = "update"
+	historyReasonRestore = "restore"
+)
+
+type PasswordHistoryService struct {
+	repo        *repository.PasswordHistoryRepository
+	accountRepo *repository.AccountRepository
+	breachRepo  *repository.BreachRepository
+	auditLog    *AuditLogService
+}
+
+func NewPasswordHistoryService() *PasswordHistoryService {
+	return &PasswordHistoryService{
+		repo:        repository.NewPasswordHistoryRepository(),
+		accountRepo: repository.NewAccountRepository(),
+		breachRepo:  repository.NewBreachRepository(),
+		auditLog:    NewAuditLogService(),
+	}
+}
+
+// GetHistory lists the previous passwords of an account, newest first.
+// Passwords are not included, see Reveal.
+func (s *PasswordHistoryService) GetHistory(accountID uint) ([]models.PasswordHistory, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindByAccount(accountID)
+}
+
+// Reveal decrypts one previous password
+func (s *PasswordHistoryService) Reveal(historyID uint) (string, error) {
+	if err := requirePermission(models.PermRevealPasswords); err != nil {
+		return "", err
+	}
+	if err := requireUnlocked(); err != nil {
+		return "", err
+	}
+
+	entry, err := s.repo.FindByID(historyID)
+	if err != nil {
+		return "", errors.New("历史记录不存在")
+	}
+
+	password, err := utils.Decrypt(entry.Password)
+	if err != nil {
+		return "", apperrors.NewDecryptionFailed(err)
+	}
+
+	s.auditLog.LogPasswordView(entry.AccountID, currentActor(), "reveal_history")
+	return password, nil
+}
+
+// Restore makes a previous password current again. The replaced password is
+// kept in the history, so a restore can itself be undone.
+func (s *PasswordHistoryService) Restore(historyID uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+
+	entry, err := s.repo.FindByID(historyID)
+	if err != nil {
+		return errors.New("历史记录不存在")
+	}
+	account, err := s.accountRepo.FindByID(entry.AccountID)
+	if err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+
+	password, err := utils.Decrypt(entry.Password)
+	if err != nil {
+		return apperrors.NewDecryptionFailed(err)
+	}
+	encrypted, err := utils.Encrypt(password)
+	if err != nil {
+		return apperrors.NewEncryptionFailed("密码", err)
+	}
+
+	before := snapshotAccount(account, nil, nil)
+	replaced := newPasswordHistory(account, currentActor(), historyReasonRestore)
+	account.Password = encrypted
+	if err := assessPassword(s.breachRepo, account, password); err != nil {
+		return err
+	}
+	set := newChangeSet(account.ID, before, snapshotAccount(account, nil, nil), models.ChangeSourceRestore, nil)
+	if err := s.accountRepo.UpdateWithChanges(account, replaced, set); err != nil {
+		return err
+	}
+
+	if err := s.accountRepo.RefreshReusedFlags(); err != nil {
+		return err
+	}
+
+	s.auditLog.LogAccountUpdate(account.ID, currentActor(), map[string]interface{}{
+		"password":   "restored",
+		"history_id": historyID,
+	})
+	return nil
+}
+
+// recordPasswordHistory saves the current password of an account before it is
+// replaced. The ciphertext is copied as is, so no decryption is needed.
+func recordPasswordHistory(repo *repository.PasswordHistoryRepository, account *models.Account, changedBy, reason string) error {
+	entry := newPasswordHistory(account, changedBy, reason)
+	if entry == nil {
+		return nil
+	}
+	return repo.Create(entry)
+}
+
+// newPasswordHistory returns the history entry keeping the current password
+// of an account, nil when it has none
+func newPasswordHistory(account *models.Account, changedBy, reason string) *models.PasswordHistory {
+	if account.Password == "" {
+		return nil
+	}
+	return &models.PasswordHistory{
+		AccountID: account.ID,
+		Password:  account.Password,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+		Reason:    reason,
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+)
+
+// Longest range a report may cover
+const maxReportDays = 3660
+
+// validateReport checks the range and granularity of a report
+func validateReport(from, to time.Time, granularity string) error {
+	switch granularity {
+	case models.ReportByDay, models.ReportByWeek, models.ReportByMonth:
+	default:
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("不支持的统计周期 %s", granularity))
+	}
+	if !to.After(from) {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "结束时间必须晚于开始时间")
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, fmt.Sprintf("统计范围不能超过 %d 天", maxReportDays))
+	}
+	return nil
+}
+
+// periodKey names the report period t falls in, in local time
+func periodKey(t time.Time, granularity string) string {
+	t = t.Local()
+	switch granularity {
+	case models.ReportByWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case models.ReportByMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
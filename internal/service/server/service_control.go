@@ -44,7 +44,7 @@ func (sc *ServiceControl) GetStatus(client *ssh.Client, config *models.ServerCon
 	} else {
 		config.ServiceStatus = "stopped"
 	}
-	sc.repo.UpdateConfig(config)
+	sc.repo.UpdateServiceStatus(config.ID, config.ServiceStatus, nil)
 
 	return config.ServiceStatus, nil
 }
@@ -56,7 +56,7 @@ func (sc *ServiceControl) Stop(client *ssh.Client, config *models.ServerConfig)
 	}
 
 	config.ServiceStatus = "stopped"
-	sc.repo.UpdateConfig(config)
+	sc.repo.UpdateServiceStatus(config.ID, config.ServiceStatus, nil)
 
 	return nil
 }
@@ -68,7 +68,7 @@ func (sc *ServiceControl) Start(client *ssh.Client, config *models.ServerConfig)
 	}
 
 	config.ServiceStatus = "running"
-	sc.repo.UpdateConfig(config)
+	sc.repo.UpdateServiceStatus(config.ID, config.ServiceStatus, nil)
 
 	return nil
 }
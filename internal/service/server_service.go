@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	apperrors "account-manager/internal/errors"
 	"account-manager/internal/models"
 	"account-manager/internal/repository"
 	"account-manager/internal/service/server"
@@ -39,6 +40,13 @@ func NewServerService() *ServerService {
 
 // GetConfig retrieves server configuration
 func (s *ServerService) GetConfig() (*models.ServerConfig, error) {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
 	config, err := s.repo.GetConfig()
 	if err != nil {
 		return nil, err
@@ -47,15 +55,17 @@ func (s *ServerService) GetConfig() (*models.ServerConfig, error) {
 	// Decrypt sensitive data
 	if config.Password != "" {
 		decrypted, err := utils.Decrypt(config.Password)
-		if err == nil {
-			config.Password = decrypted
+		if err != nil {
+			return nil, apperrors.NewDecryptionFailed(err)
 		}
+		config.Password = decrypted
 	}
 	if config.PrivateKey != "" {
 		decrypted, err := utils.Decrypt(config.PrivateKey)
-		if err == nil {
-			config.PrivateKey = decrypted
+		if err != nil {
+			return nil, apperrors.NewDecryptionFailed(err)
 		}
+		config.PrivateKey = decrypted
 	}
 
 	return config, nil
@@ -63,6 +73,15 @@ func (s *ServerService) GetConfig() (*models.ServerConfig, error) {
 
 // UpdateConfig updates server configuration
 func (s *ServerService) UpdateConfig(host string, port int, username, password, privateKey, deployPath string, isActive bool) error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+	release := utils.HoldKey()
+	defer release()
+
 	config, err := s.repo.GetConfig()
 	if err != nil {
 		config = &models.ServerConfig{}
@@ -97,6 +116,13 @@ func (s *ServerService) UpdateConfig(host string, port int, username, password,
 
 // TestConnection tests SSH connection to the server
 func (s *ServerService) TestConnection() error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	config, err := s.GetConfig()
 	if err != nil {
 		return fmt.Errorf("获取服务器配置失败: %v", err)
@@ -129,6 +155,13 @@ func (s *ServerService) TestConnection() error {
 
 // DetectServerInfo detects server OS type, version, and systemd availability
 func (s *ServerService) DetectServerInfo() (*models.ServerInfo, error) {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return nil, err
+	}
+	if err := requireUnlocked(); err != nil {
+		return nil, err
+	}
+
 	config, err := s.GetConfig()
 	if err != nil {
 		return nil, fmt.Errorf("获取服务器配置失败: %v", err)
@@ -213,6 +246,13 @@ func (s *ServerService) detectPackageManager(client *ssh.Client) string {
 
 // DeployEmailService deploys the email service to remote server
 func (s *ServerService) DeployEmailService(emailConfig *models.EmailConfig) error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	serverConfig, err := s.GetConfig()
 	if err != nil {
 		return fmt.Errorf("获取服务器配置失败: %v", err)
@@ -277,13 +317,20 @@ func (s *ServerService) DeployEmailService(emailConfig *models.EmailConfig) erro
 	now := time.Now()
 	serverConfig.LastDeployedAt = &now
 	serverConfig.ServiceStatus = "running"
-	s.repo.UpdateConfig(serverConfig)
+	s.repo.UpdateServiceStatus(serverConfig.ID, serverConfig.ServiceStatus, serverConfig.LastDeployedAt)
 
 	return nil
 }
 
 // GetServiceStatus checks the status of the email service on remote server
 func (s *ServerService) GetServiceStatus() (string, error) {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return "", err
+	}
+	if err := requireUnlocked(); err != nil {
+		return "unknown", err
+	}
+
 	config, err := s.GetConfig()
 	if err != nil {
 		return "unknown", err
@@ -304,6 +351,13 @@ func (s *ServerService) GetServiceStatus() (string, error) {
 
 // StopService stops the email service on remote server
 func (s *ServerService) StopService() error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	config, err := s.GetConfig()
 	if err != nil {
 		return err
@@ -320,6 +374,13 @@ func (s *ServerService) StopService() error {
 
 // StartService starts the email service on remote server
 func (s *ServerService) StartService() error {
+	if err := requirePermission(models.PermDeployServers); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
 	config, err := s.GetConfig()
 	if err != nil {
 		return err
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"account-manager/internal/cache"
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+)
+
+// Longest tag name in characters, see models.Tag.Name
+const maxTagName = 50
+
+type TagService struct {
+	repo        *repository.TagRepository
+	accountRepo *repository.AccountRepository
+	auditLog    *AuditLogService
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		repo:        repository.NewTagRepository(),
+		accountRepo: repository.NewAccountRepository(),
+		auditLog:    NewAuditLogService(),
+	}
+}
+
+// GetTags returns all tags by name
+func (s *TagService) GetTags() ([]models.Tag, error) {
+	if err := requirePermission(models.PermViewAccounts); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+// SaveTag creates a tag, or renames or recolours it when ID is set
+func (s *TagService) SaveTag(tag *models.Tag) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	tag.Name = strings.TrimSpace(tag.Name)
+	switch {
+	case tag.Name == "":
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "标签名称不能为空")
+	case utf8.RuneCountInString(tag.Name) > maxTagName:
+		return apperrors.New(apperrors.ErrCodeValidationFailed, fmt.Sprintf("标签名称不能超过 %d 个字符", maxTagName))
+	}
+	if conflict, _ := s.repo.FindByName(tag.Name); conflict != nil && conflict.ID != tag.ID {
+		return apperrors.NewTagExists(tag.Name)
+	}
+
+	changes := map[string]interface{}{
+		"tag":   tag.Name,
+		"color": tag.Color,
+	}
+	if tag.ID == 0 {
+		if err := s.repo.Create(tag); err != nil {
+			return err
+		}
+	} else {
+		existing, err := s.repo.FindByID(tag.ID)
+		if err != nil {
+			return apperrors.NewTagNotFound(fmt.Sprint(tag.ID))
+		}
+		tag.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(tag); err != nil {
+			return err
+		}
+		if existing.Name != tag.Name {
+			changes["renamed_from"] = existing.Name
+		}
+	}
+
+	cache.InvalidateStats()
+	s.auditLog.LogConfigChange("tag", currentActor(), changes)
+	return nil
+}
+
+// DeleteTag removes a tag from every account and deletes it
+func (s *TagService) DeleteTag(id uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+
+	tag, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewTagNotFound(fmt.Sprint(id))
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	cache.InvalidateStats()
+	s.auditLog.LogConfigChange("tag", currentActor(), map[string]interface{}{
+		"tag":     tag.Name,
+		"deleted": true,
+	})
+	return nil
+}
+
+// TagAccounts adds every tag to every account and returns the number of tags
+// added. Tags an account already has are skipped.
+func (s *TagService) TagAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, err
+	}
+	tags, err := s.findTags(tagIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	added, err := s.repo.AddToAccounts(accountIDs, tagIDs)
+	if err != nil {
+		return 0, err
+	}
+	if added > 0 {
+		cache.InvalidateStats()
+		s.auditLog.LogAccountUpdate(0, currentActor(), map[string]interface{}{
+			"accounts":   accountIDs,
+			"tags_added": tagNames(tags),
+		})
+	}
+	return added, nil
+}
+
+// UntagAccounts removes the tags from the accounts and returns the number of
+// tags removed
+func (s *TagService) UntagAccounts(accountIDs, tagIDs []uint) (int64, error) {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return 0, err
+	}
+	tags, err := s.findTags(tagIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := s.repo.RemoveFromAccounts(accountIDs, tagIDs)
+	if err != nil {
+		return 0, err
+	}
+	if removed > 0 {
+		cache.InvalidateStats()
+		s.auditLog.LogAccountUpdate(0, currentActor(), map[string]interface{}{
+			"accounts":     accountIDs,
+			"tags_removed": tagNames(tags),
+		})
+	}
+	return removed, nil
+}
+
+// SetAccountTags replaces the tags of one account
+func (s *TagService) SetAccountTags(accountID uint, tagIDs []uint) error {
+	if err := requirePermission(models.PermEditAccounts); err != nil {
+		return err
+	}
+	if _, err := s.accountRepo.FindByID(accountID); err != nil {
+		return apperrors.NewAccountNotFound()
+	}
+	tags, err := s.findTags(tagIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetAccountTags(accountID, tagIDs); err != nil {
+		return err
+	}
+	cache.InvalidateStats()
+	s.auditLog.LogAccountUpdate(accountID, currentActor(), map[string]interface{}{
+		"tags": tagNames(tags),
+	})
+	return nil
+}
+
+// findTags loads the given tags, failing on the first unknown id
+func (s *TagService) findTags(ids []uint) ([]models.Tag, error) {
+	tags, err := s.repo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		found[tag.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, apperrors.NewTagNotFound(fmt.Sprint(id))
+		}
+	}
+	return tags, nil
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
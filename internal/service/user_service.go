@@ -0,0 +1,407 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+)
+
+// Minimum length of a local user password
+const minUserPasswordLength = 8
+
+// Actor recorded in audit logs while no local users exist
+const localActor = "local"
+
+// session holds the signed-in user. Like the vault key it is shared by all
+// services, since the desktop app has exactly one person at the keyboard.
+var session struct {
+	mu           sync.RWMutex
+	user         *models.User
+	loginEnabled bool // Set once the first user exists
+}
+
+// currentActor returns the name recorded in audit logs
+func currentActor() string {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	switch {
+	case session.user != nil:
+		return session.user.Username
+	case !session.loginEnabled:
+		return localActor
+	default:
+		return "anonymous"
+	}
+}
+
+// hasPermission reports whether the signed-in user may perform an action.
+// Everything is allowed in single-user mode, before any user is created.
+func hasPermission(perm models.Permission) bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if !session.loginEnabled {
+		return true
+	}
+	return session.user != nil && session.user.Role.Has(perm)
+}
+
+// requirePermission guards service methods called on behalf of the user
+func requirePermission(perm models.Permission) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if !session.loginEnabled {
+		return nil
+	}
+	if session.user == nil {
+		return apperrors.NewNotLoggedIn()
+	}
+	if !session.user.Role.Has(perm) {
+		return apperrors.NewPermissionDenied(string(perm))
+	}
+	return nil
+}
+
+type UserService struct {
+	repo     *repository.UserRepository
+	auditLog *AuditLogService
+}
+
+func NewUserService() *UserService {
+	return &UserService{
+		repo:     repository.NewUserRepository(),
+		auditLog: NewAuditLogService(),
+	}
+}
+
+// Initialize turns login on when local users exist. It is called at startup.
+func (s *UserService) Initialize() error {
+	count, err := s.repo.Count()
+	if err != nil {
+		return err
+	}
+
+	session.mu.Lock()
+	session.loginEnabled = count > 0
+	session.mu.Unlock()
+	return nil
+}
+
+// GetSession returns the signed-in user and their permissions
+func (s *UserService) GetSession() *models.Session {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	result := &models.Session{LoginEnabled: session.loginEnabled}
+	switch {
+	case session.user != nil:
+		u := *session.user
+		result.User = &u
+		result.Permissions = models.RolePermissions[u.Role]
+	case !session.loginEnabled:
+		result.Permissions = models.RolePermissions[models.RoleAdmin]
+	default:
+		result.Permissions = []models.Permission{}
+	}
+	return result
+}
+
+// Login signs a local user in
+func (s *UserService) Login(username, password string) (*models.Session, error) {
+	username = strings.TrimSpace(username)
+
+	user, err := s.repo.FindByUsername(username)
+	if err != nil {
+		// Spend the same time as a real check so usernames cannot be probed
+		if hash, err := dummyPasswordHash(); err != nil {
+			logger.WithField("error", err.Error()).Error("Failed to compute dummy password hash")
+		} else {
+			utils.VerifyPassword(hash, password)
+		}
+		s.auditLog.LogLogin(username, false, "unknown user")
+		return nil, apperrors.NewInvalidPassword()
+	}
+	if !utils.VerifyPassword(user.PasswordHash, password) {
+		s.auditLog.LogLogin(username, false, "invalid password")
+		return nil, apperrors.NewInvalidPassword()
+	}
+	if user.Disabled {
+		s.auditLog.LogLogin(username, false, "user disabled")
+		return nil, apperrors.NewUserDisabled()
+	}
+
+	s.setSessionUser(user)
+	if err := s.repo.UpdateLastLogin(user.ID); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to record last login")
+	}
+	s.auditLog.LogLogin(username, true, "")
+	logger.WithField("user", username).Info("User logged in")
+
+	return s.GetSession(), nil
+}
+
+// Logout signs the current user out, e.g. when the vault locks
+func (s *UserService) Logout() {
+	session.mu.Lock()
+	user := session.user
+	session.user = nil
+	session.mu.Unlock()
+
+	if user != nil {
+		s.auditLog.LogLogout(user.Username)
+		logger.WithField("user", user.Username).Info("User logged out")
+	}
+}
+
+func (s *UserService) GetUsers() ([]models.User, error) {
+	if err := requirePermission(models.PermManageUsers); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+// CreateUser adds a local user. The first user must be an administrator and
+// is signed in right away, which turns login on for the workstation.
+func (s *UserService) CreateUser(username, displayName, password string, role models.Role) (*models.User, error) {
+	if err := requirePermission(models.PermManageUsers); err != nil {
+		return nil, err
+	}
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, apperrors.New(apperrors.ErrCodeValidationFailed, "用户名不能为空")
+	}
+	if !role.Valid() {
+		return nil, apperrors.New(apperrors.ErrCodeValidationFailed, "未知的角色")
+	}
+	if len(password) < minUserPasswordLength {
+		return nil, apperrors.NewPasswordTooShort(minUserPasswordLength)
+	}
+	if existing, _ := s.repo.FindByUsername(username); existing != nil {
+		return nil, apperrors.NewUserExists()
+	}
+
+	count, err := s.repo.Count()
+	if err != nil {
+		return nil, err
+	}
+	first := count == 0
+	if first && role != models.RoleAdmin {
+		return nil, apperrors.New(apperrors.ErrCodeValidationFailed, "第一个用户必须是管理员")
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username:     username,
+		DisplayName:  displayName,
+		PasswordHash: hash,
+		Role:         role,
+	}
+	if err := s.repo.Create(user); err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogConfigChange("user", currentActor(), map[string]interface{}{
+		"action":   "create",
+		"username": username,
+		"role":     role,
+	})
+
+	if first {
+		session.mu.Lock()
+		session.loginEnabled = true
+		session.mu.Unlock()
+		s.setSessionUser(user)
+		s.auditLog.LogLogin(username, true, "")
+		logger.WithField("user", username).Info("First user created, login enabled")
+	}
+
+	return user, nil
+}
+
+// UpdateUser changes the profile, role or disabled state of a user
+func (s *UserService) UpdateUser(id uint, displayName string, role models.Role, disabled bool) error {
+	if err := requirePermission(models.PermManageUsers); err != nil {
+		return err
+	}
+	if !role.Valid() {
+		return apperrors.New(apperrors.ErrCodeValidationFailed, "未知的角色")
+	}
+
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewUserNotFound()
+	}
+
+	losesAdmin := user.Role == models.RoleAdmin && !user.Disabled && (role != models.RoleAdmin || disabled)
+	if losesAdmin {
+		if err := s.requireAnotherAdmin(); err != nil {
+			return err
+		}
+	}
+
+	changes := map[string]interface{}{"action": "update", "username": user.Username}
+	if user.Role != role {
+		changes["role"] = role
+	}
+	if user.Disabled != disabled {
+		changes["disabled"] = disabled
+	}
+
+	user.DisplayName = displayName
+	user.Role = role
+	user.Disabled = disabled
+	if err := s.repo.Update(user); err != nil {
+		return err
+	}
+
+	// Apply the change to the current session right away
+	session.mu.Lock()
+	if session.user != nil && session.user.ID == id {
+		if disabled {
+			session.user = nil
+		} else {
+			u := *user
+			session.user = &u
+		}
+	}
+	session.mu.Unlock()
+
+	s.auditLog.LogConfigChange("user", currentActor(), changes)
+	return nil
+}
+
+// ResetPassword sets a new password for another user
+func (s *UserService) ResetPassword(id uint, newPassword string) error {
+	if err := requirePermission(models.PermManageUsers); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewUserNotFound()
+	}
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("user", currentActor(), map[string]interface{}{
+		"action":   "reset_password",
+		"username": user.Username,
+	})
+	return nil
+}
+
+// ChangePassword changes the password of the signed-in user
+func (s *UserService) ChangePassword(oldPassword, newPassword string) error {
+	session.mu.RLock()
+	current := session.user
+	session.mu.RUnlock()
+	if current == nil {
+		return apperrors.NewNotLoggedIn()
+	}
+
+	user, err := s.repo.FindByID(current.ID)
+	if err != nil {
+		return apperrors.NewUserNotFound()
+	}
+	if !utils.VerifyPassword(user.PasswordHash, oldPassword) {
+		return apperrors.NewInvalidPassword()
+	}
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("user", user.Username, map[string]interface{}{
+		"action":   "change_password",
+		"username": user.Username,
+	})
+	return nil
+}
+
+// DeleteUser removes a user other than the signed-in one
+func (s *UserService) DeleteUser(id uint) error {
+	if err := requirePermission(models.PermManageUsers); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return apperrors.NewUserNotFound()
+	}
+
+	session.mu.RLock()
+	self := session.user != nil && session.user.ID == id
+	session.mu.RUnlock()
+	if self {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "不能删除当前登录的用户")
+	}
+	if user.Role == models.RoleAdmin && !user.Disabled {
+		if err := s.requireAnotherAdmin(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditLog.LogConfigChange("user", currentActor(), map[string]interface{}{
+		"action":   "delete",
+		"username": user.Username,
+	})
+	return nil
+}
+
+func (s *UserService) setPassword(user *models.User, password string) error {
+	if len(password) < minUserPasswordLength {
+		return apperrors.NewPasswordTooShort(minUserPasswordLength)
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return s.repo.Update(user)
+}
+
+// requireAnotherAdmin refuses changes that would leave no enabled administrator
+func (s *UserService) requireAnotherAdmin() error {
+	admins, err := s.repo.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return apperrors.NewLastAdmin()
+	}
+	return nil
+}
+
+func (s *UserService) setSessionUser(user *models.User) {
+	u := *user
+	session.mu.Lock()
+	session.user = &u
+	session.mu.Unlock()
+}
+
+// dummyHash is checked for unknown usernames, see Login. It is computed on
+// first use rather than at startup, since hashing takes 64 MiB.
+var dummyHash struct {
+	once sync.Once
+	hash string
+	err  error
+}
+
+func dummyPasswordHash() (string, error) {
+	dummyHash.once.Do(func() {
+		dummyHash.hash, dummyHash.err = utils.HashPassword("account-manager-dummy-password")
+	})
+	return dummyHash.hash, dummyHash.err
+}
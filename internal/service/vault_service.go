@@ -0,0 +1,574 @@
+package service
+
+import (
+	"encoding/base64"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apperrors "account-manager/internal/errors"
+	"account-manager/internal/logger"
+	"account-manager/internal/migration"
+	"account-manager/internal/models"
+	"account-manager/internal/repository"
+	"account-manager/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Minimum length of the master password
+const minMasterPasswordLength = 8
+
+// verifierPlaintext is encrypted with the derived key to detect a wrong master password
+const verifierPlaintext = "account-manager-vault-verifier"
+
+// How often the auto-lock watcher checks for idleness
+const autoLockCheckInterval = 30 * time.Second
+
+// lastVaultActivity holds the UnixNano time of the last secret access made on
+// behalf of the user. It is shared by all services so any of them keeps the
+// vault unlocked.
+var lastVaultActivity atomic.Int64
+
+// touchVault resets the idle timer
+func touchVault() {
+	lastVaultActivity.Store(time.Now().UnixNano())
+}
+
+// requireUnlocked guards secret-touching calls made on behalf of the user.
+// It refuses while the vault is locked and otherwise resets the idle timer.
+func requireUnlocked() error {
+	if !utils.HasEncryptionKey() {
+		return apperrors.NewVaultLocked()
+	}
+	touchVault()
+	return nil
+}
+
+type VaultService struct {
+	repo      *repository.VaultRepository
+	emailRepo *repository.EmailRepository
+	auditLog  *AuditLogService
+
+	mu       sync.Mutex
+	rotating bool
+	stopChan chan struct{}
+	onLock   []func()
+	onUnlock []func()
+	onRotate []func()
+}
+
+func NewVaultService() *VaultService {
+	return &VaultService{
+		repo:      repository.NewVaultRepository(),
+		emailRepo: repository.NewEmailRepository(),
+		auditLog:  NewAuditLogService(),
+	}
+}
+
+// IsInitialized reports whether a master password has been set up
+func (s *VaultService) IsInitialized() bool {
+	exists, err := s.repo.Exists()
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to check vault state")
+		return false
+	}
+	return exists
+}
+
+// IsInteractive reports whether unlocking needs a master password typed by the
+// user, as opposed to a key file, environment variable or key agent
+func (s *VaultService) IsInteractive() bool {
+	return utils.GetKeyProvider().Interactive()
+}
+
+// IsUnlocked reports whether the data key is loaded in memory
+func (s *VaultService) IsUnlocked() bool {
+	return utils.HasEncryptionKey()
+}
+
+// Setup creates the vault on first run and loads the derived key
+func (s *VaultService) Setup(masterPassword string) error {
+	if s.IsInitialized() {
+		return apperrors.NewVaultInitialized()
+	}
+
+	material, err := newKeyMaterial(masterPassword)
+	if err != nil {
+		return err
+	}
+
+	config := &models.VaultConfig{
+		KeyID:      material.keyID,
+		Provider:   utils.GetKeyProvider().Name(),
+		KDF:        utils.KDFName,
+		Salt:       material.salt,
+		KDFTime:    utils.KDFTime,
+		KDFMemory:  utils.KDFMemory,
+		KDFThreads: utils.KDFThreads,
+		Verifier:   material.verifier,
+	}
+
+	if err := s.repo.SaveConfig(config); err != nil {
+		return err
+	}
+
+	if err := utils.SetEncryptionKey(material.keyID, material.key); err != nil {
+		return err
+	}
+
+	logger.WithField("provider", config.Provider).Info("Vault initialized")
+	s.unlocked()
+	return nil
+}
+
+// Unlock derives the data key from the master password and loads it
+func (s *VaultService) Unlock(masterPassword string) error {
+	config, err := s.repo.GetConfig()
+	if err != nil {
+		return apperrors.NewVaultNotInitialized()
+	}
+
+	key, err := s.checkMasterPassword(config, masterPassword)
+	if err != nil {
+		return err
+	}
+
+	// Vaults created before versioned ciphertexts have no key id yet
+	if config.KeyID == "" {
+		if err := s.assignKeyID(config, key); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.SetEncryptionKey(config.KeyID, key); err != nil {
+		return err
+	}
+
+	logger.Info("Vault unlocked")
+	s.unlocked()
+	return nil
+}
+
+// Lock wipes the data key from memory. Secret-touching service methods
+// return ErrCodeVaultLocked until the vault is unlocked again.
+func (s *VaultService) Lock() {
+	if !utils.HasEncryptionKey() {
+		return
+	}
+	// Sign the audit chain head while the key is still available
+	if err := s.auditLog.Checkpoint(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to write audit checkpoint")
+	}
+	utils.ClearEncryptionKey()
+	logger.Info("Vault locked")
+
+	s.mu.Lock()
+	hooks := append([]func(){}, s.onLock...)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// OnLock registers a callback run after the vault is locked
+func (s *VaultService) OnLock(hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onLock = append(s.onLock, hook)
+}
+
+// OnUnlock registers a callback run after the vault is unlocked
+func (s *VaultService) OnUnlock(hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onUnlock = append(s.onUnlock, hook)
+}
+
+// OnKeyRotated registers a callback run after a key rotation completes
+func (s *VaultService) OnKeyRotated(hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotate = append(s.onRotate, hook)
+}
+
+func (s *VaultService) unlocked() {
+	touchVault()
+	// Sign the entries written while the vault was locked
+	if err := s.auditLog.Checkpoint(); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to write audit checkpoint")
+	}
+
+	s.mu.Lock()
+	hooks := append([]func(){}, s.onUnlock...)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// GetAutoLockMinutes returns the idle timeout in minutes, 0 means disabled
+func (s *VaultService) GetAutoLockMinutes() int {
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return 0
+	}
+	return sysConfig.AutoLockMinutes
+}
+
+// SetAutoLockMinutes updates the idle timeout, 0 disables auto-lock
+func (s *VaultService) SetAutoLockMinutes(minutes int) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+
+	if minutes < 0 {
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "自动锁定时间不能为负数")
+	}
+
+	sysConfig, err := s.emailRepo.GetSystemConfig()
+	if err != nil {
+		return err
+	}
+	sysConfig.AutoLockMinutes = minutes
+	return s.emailRepo.UpdateSystemConfig(sysConfig)
+}
+
+// StartAutoLock starts watching for idleness and locks the vault once the
+// configured timeout has passed without secret access
+func (s *VaultService) StartAutoLock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopChan != nil {
+		return
+	}
+	s.stopChan = make(chan struct{})
+	go s.watchIdle(s.stopChan)
+}
+
+// StopAutoLock stops the idle watcher
+func (s *VaultService) StopAutoLock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopChan != nil {
+		close(s.stopChan)
+		s.stopChan = nil
+	}
+}
+
+func (s *VaultService) watchIdle(stop chan struct{}) {
+	ticker := time.NewTicker(autoLockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !utils.HasEncryptionKey() {
+				continue
+			}
+			minutes := s.GetAutoLockMinutes()
+			if minutes <= 0 {
+				continue
+			}
+			idle := time.Since(time.Unix(0, lastVaultActivity.Load()))
+			if idle >= time.Duration(minutes)*time.Minute {
+				logger.WithField("idle", idle.Round(time.Second).String()).Info("Vault idle timeout reached")
+				s.Lock()
+			}
+		}
+	}
+}
+
+// assignKeyID gives an existing vault a key id and upgrades its verifier
+func (s *VaultService) assignKeyID(config *models.VaultConfig, key []byte) error {
+	keyID, err := utils.GenerateKeyID()
+	if err != nil {
+		return err
+	}
+	verifier, err := utils.EncryptWithKey(keyID, key, verifierPlaintext)
+	if err != nil {
+		return apperrors.NewEncryptionFailed("校验值", err)
+	}
+
+	config.KeyID = keyID
+	config.Verifier = verifier
+	return s.repo.SaveConfig(config)
+}
+
+// checkMasterPassword obtains the key for config from the key provider and
+// verifies it. masterPassword is ignored by non-interactive providers.
+func (s *VaultService) checkMasterPassword(config *models.VaultConfig, masterPassword string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(config.Salt)
+	if err != nil {
+		return nil, apperrors.NewDecryptionFailed(err)
+	}
+
+	provider := utils.GetKeyProvider()
+	params := utils.KeyParams{
+		Salt:    salt,
+		Time:    config.KDFTime,
+		Memory:  config.KDFMemory,
+		Threads: config.KDFThreads,
+	}
+	key, err := utils.ProvideKey(params, masterPassword)
+	if err != nil {
+		if provider.Interactive() {
+			return nil, apperrors.NewInvalidPassword()
+		}
+		return nil, apperrors.NewKeyProviderFailed(err)
+	}
+
+	check, err := utils.DecryptWithKey(key, config.Verifier)
+	if err != nil || check != verifierPlaintext {
+		if provider.Interactive() {
+			return nil, apperrors.NewInvalidPassword()
+		}
+		return nil, apperrors.NewKeyProviderFailed(utils.ErrDecryptionFailed)
+	}
+	return key, nil
+}
+
+// keyMaterial is a freshly derived data key with its stored parameters
+type keyMaterial struct {
+	keyID    string
+	salt     string // Base64 encoded
+	key      []byte
+	verifier string
+}
+
+// newKeyMaterial obtains a new data key from the configured key provider
+func newKeyMaterial(masterPassword string) (*keyMaterial, error) {
+	provider := utils.GetKeyProvider()
+	if provider.Interactive() && len(masterPassword) < minMasterPasswordLength {
+		return nil, apperrors.NewPasswordTooShort(minMasterPasswordLength)
+	}
+
+	salt, err := utils.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := utils.GenerateKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := utils.ProvideKey(utils.DefaultKeyParams(salt), masterPassword)
+	if err != nil {
+		return nil, apperrors.NewKeyProviderFailed(err)
+	}
+	verifier, err := utils.EncryptWithKey(keyID, key, verifierPlaintext)
+	if err != nil {
+		return nil, apperrors.NewEncryptionFailed("校验值", err)
+	}
+
+	return &keyMaterial{
+		keyID:    keyID,
+		salt:     base64.StdEncoding.EncodeToString(salt),
+		key:      key,
+		verifier: verifier,
+	}, nil
+}
+
+// HasPendingRotation reports whether a key rotation was interrupted
+func (s *VaultService) HasPendingRotation() bool {
+	config, err := s.repo.GetConfig()
+	return err == nil && config.PendingKeyID != ""
+}
+
+// RotateKey replaces the data key with one derived from newMasterPassword and
+// a new salt, re-encrypting every encrypted column in a single transaction.
+// Writers wait until it is done, see utils.HoldKeyExclusive. Values that no
+// loaded key decrypts are left as they are and reported.
+// Passing the current master password again rotates the key only.
+// With a non-interactive key provider both passwords are ignored: the new key
+// is read from the provider, so replace the key file, variable or agent entry
+// while the vault is unlocked and then rotate.
+func (s *VaultService) RotateKey(currentMasterPassword, newMasterPassword string, progress func(models.KeyRotationProgress)) error {
+	if err := requirePermission(models.PermManageSettings); err != nil {
+		return err
+	}
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
+	config, err := s.repo.GetConfig()
+	if err != nil {
+		return apperrors.NewVaultNotInitialized()
+	}
+	if config.PendingKeyID != "" {
+		return s.ResumeRotation(progress)
+	}
+	if s.IsInteractive() {
+		if _, err := s.checkMasterPassword(config, currentMasterPassword); err != nil {
+			return err
+		}
+	}
+
+	material, err := newKeyMaterial(newMasterPassword)
+	if err != nil {
+		return err
+	}
+	if !s.IsInteractive() {
+		if check, err := utils.DecryptWithKey(material.key, config.Verifier); err == nil && check == verifierPlaintext {
+			return apperrors.New(apperrors.ErrCodeInvalidInput, "密钥提供方返回的仍是当前密钥")
+		}
+	}
+
+	// Record the rotation before touching any data so it can be resumed
+	wrapped, err := utils.Encrypt(base64.StdEncoding.EncodeToString(material.key))
+	if err != nil {
+		return apperrors.NewEncryptionFailed("新密钥", err)
+	}
+	config.PendingKeyID = material.keyID
+	config.PendingSalt = material.salt
+	config.PendingVerifier = material.verifier
+	config.PendingKey = wrapped
+	if err := s.repo.SaveConfig(config); err != nil {
+		return err
+	}
+
+	return s.runRotation(config, material.key, progress)
+}
+
+// ResumeRotation finishes a key rotation that was interrupted, for example
+// because the app was closed while it was running
+func (s *VaultService) ResumeRotation(progress func(models.KeyRotationProgress)) error {
+	if err := requireUnlocked(); err != nil {
+		return err
+	}
+
+	config, err := s.repo.GetConfig()
+	if err != nil {
+		return apperrors.NewVaultNotInitialized()
+	}
+	if config.PendingKeyID == "" {
+		return nil
+	}
+
+	key, err := loadPendingKey(config)
+	if err != nil {
+		return apperrors.NewDecryptionFailed(err)
+	}
+
+	logger.WithField("key_id", config.PendingKeyID).Info("Resuming interrupted key rotation")
+	return s.runRotation(config, key, progress)
+}
+
+// loadPendingKey unwraps the key of an unfinished rotation
+func loadPendingKey(config *models.VaultConfig) ([]byte, error) {
+	encoded, err := utils.Decrypt(config.PendingKey)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (s *VaultService) runRotation(config *models.VaultConfig, newKey []byte, progress func(models.KeyRotationProgress)) error {
+	s.mu.Lock()
+	if s.rotating {
+		s.mu.Unlock()
+		return apperrors.New(apperrors.ErrCodeInvalidInput, "密钥轮换正在进行中")
+	}
+	s.rotating = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.rotating = false
+		s.mu.Unlock()
+	}()
+
+	oldKeyID := config.KeyID
+	newKeyID := config.PendingKeyID
+
+	transform := func(value string) (string, bool, error) {
+		if utils.CiphertextKeyID(value) == newKeyID {
+			return value, false, nil
+		}
+		plaintext, err := utils.Decrypt(value)
+		if err != nil {
+			return "", false, err
+		}
+		encrypted, err := utils.EncryptWithKey(newKeyID, newKey, plaintext)
+		return encrypted, true, err
+	}
+	report := func(done, total int) {
+		// A long rotation is user activity and must not trip the idle lock
+		touchVault()
+		if progress != nil && (done == total || done%100 == 0) {
+			progress(models.KeyRotationProgress{Done: done, Total: total})
+		}
+	}
+
+	// No writer may store a value encrypted with the old key, or read before
+	// its row was re-encrypted, until the old key is dropped
+	release := utils.HoldKeyExclusive()
+	count := 0
+	var skipped []string
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		n, failed, err := migration.ReencryptAll(tx, transform, report)
+		if err != nil {
+			return err
+		}
+		count, skipped = n, failed
+
+		now := time.Now()
+		config.KeyID = newKeyID
+		config.Provider = utils.GetKeyProvider().Name()
+		config.Salt = config.PendingSalt
+		config.Verifier = config.PendingVerifier
+		config.KDF = utils.KDFName
+		config.KDFTime = utils.KDFTime
+		config.KDFMemory = utils.KDFMemory
+		config.KDFThreads = utils.KDFThreads
+		config.RotatedAt = &now
+		config.PendingKeyID = ""
+		config.PendingSalt = ""
+		config.PendingVerifier = ""
+		config.PendingKey = ""
+		return tx.Save(config).Error
+	})
+	if err == nil {
+		// Only the new key is needed from now on
+		err = utils.SetEncryptionKey(newKeyID, newKey)
+		utils.RemoveEncryptionKey(oldKeyID)
+	}
+	release()
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Key rotation failed, it will resume after the next unlock")
+		return apperrors.NewEncryptionFailed("数据", err)
+	}
+	touchVault()
+
+	logger.WithFields(map[string]interface{}{
+		"old_key_id": oldKeyID,
+		"new_key_id": newKeyID,
+		"values":     count,
+	}).Info("Encryption key rotated")
+	if len(skipped) > 0 {
+		logger.WithFields(map[string]interface{}{
+			"key_id": oldKeyID,
+			"values": skipped,
+		}).Warn("Values that could not be decrypted were left with the old key")
+	}
+
+	details := map[string]interface{}{
+		"action":     "key_rotation",
+		"old_key_id": oldKeyID,
+		"new_key_id": newKeyID,
+		"values":     count,
+	}
+	if len(skipped) > 0 {
+		details["skipped"] = skipped
+	}
+	s.auditLog.LogConfigChange("vault", currentActor(), details)
+
+	s.mu.Lock()
+	hooks := append([]func(){}, s.onRotate...)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
@@ -3,48 +3,379 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
 )
 
-// Fixed encryption key for AES-256
-var encryptionKey = []byte("account-manager-secret-key-32by!") // 32 bytes for AES-256
+// legacyEncryptionKey is the fixed key compiled into releases before the
+// master password was introduced. It is only used to migrate old data.
+var legacyEncryptionKey = []byte("account-manager-secret-key-32by!") // 32 bytes for AES-256
 
-func Encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(encryptionKey)
+// Argon2id parameters used to derive the data key from the master password
+const (
+	KDFName    = "argon2id"
+	KDFTime    = 3
+	KDFMemory  = 64 * 1024 // KiB
+	KDFThreads = 4
+	KeyLength  = 32
+	SaltLength = 16
+)
+
+// Ciphertext format: "v2:<key id>:<base64(nonce || AES-256-GCM sealed data)>".
+// The prefix is authenticated as additional data, so a value cannot be moved
+// to another key id. Values without the prefix are legacy AES-CFB blobs.
+const (
+	ciphertextVersion = "v2"
+	keyIDLength       = 4 // bytes, hex encoded in the prefix
+)
+
+var (
+	// ErrNoEncryptionKey is returned when no data key has been loaded yet
+	ErrNoEncryptionKey = errors.New("encryption key not loaded")
+	// ErrDecryptionFailed is returned for tampered data or a wrong key
+	ErrDecryptionFailed = errors.New("ciphertext authentication failed")
+	// ErrUnknownKeyID is returned when a value was encrypted with a key that is not loaded
+	ErrUnknownKeyID = errors.New("unknown encryption key id")
+)
+
+var (
+	keyMu        sync.RWMutex
+	keyring      = map[string][]byte{}
+	currentKeyID string
+	keyProvider  KeyProvider = &PasswordKeyProvider{}
+)
+
+// SetKeyProvider selects where the data key comes from
+func SetKeyProvider(provider KeyProvider) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	keyProvider = provider
+}
+
+// GetKeyProvider returns the configured key provider
+func GetKeyProvider() KeyProvider {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return keyProvider
+}
+
+// ProvideKey obtains a data key from the configured provider
+func ProvideKey(params KeyParams, secret string) ([]byte, error) {
+	key, err := GetKeyProvider().Key(params, secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != KeyLength {
+		return nil, errors.New("invalid encryption key length")
+	}
+	return key, nil
+}
+
+// DeriveKey derives a 32-byte data key from the master password using Argon2id
+func DeriveKey(password string, salt []byte, time, memory uint32, threads uint8) []byte {
+	return argon2.IDKey([]byte(password), salt, time, memory, threads, KeyLength)
+}
+
+// HashPassword hashes a login password with Argon2id. The result encodes the
+// parameters and salt: "argon2id$<time>$<memory>$<threads>$<salt>$<hash>".
+func HashPassword(password string) (string, error) {
+	salt, err := GenerateSalt()
 	if err != nil {
 		return "", err
 	}
+	hash := DeriveKey(password, salt, KDFTime, KDFMemory, KDFThreads)
+	return fmt.Sprintf("%s$%d$%d$%d$%s$%s", KDFName, KDFTime, KDFMemory, KDFThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
 
-	plaintextBytes := []byte(plaintext)
-	ciphertext := make([]byte, aes.BlockSize+len(plaintextBytes))
-	iv := ciphertext[:aes.BlockSize]
+// VerifyPassword checks a login password against a HashPassword result
+func VerifyPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != KDFName {
+		return false
+	}
+
+	var time, memory uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[1]+" "+parts[2]+" "+parts[3], "%d %d %d", &time, &memory, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(hash, expected) == 1
+}
 
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+// GenerateSalt returns a new random salt for key derivation
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, SaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// GenerateKeyID returns a new random identifier for a data key
+func GenerateKeyID() (string, error) {
+	id := make([]byte, keyIDLength)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(id), nil
+}
+
+// SetEncryptionKey loads the data key used by Encrypt and Decrypt
+func SetEncryptionKey(keyID string, key []byte) error {
+	if len(key) != KeyLength {
+		return errors.New("invalid encryption key length")
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	keyring[keyID] = append([]byte(nil), key...)
+	currentKeyID = keyID
+	return nil
+}
+
+// RemoveEncryptionKey wipes one data key from memory. The current key cannot
+// be removed, use ClearEncryptionKey.
+func RemoveEncryptionKey(keyID string) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if keyID == currentKeyID {
+		return
+	}
+	if key, ok := keyring[keyID]; ok {
+		for i := range key {
+			key[i] = 0
+		}
+		delete(keyring, keyID)
+	}
+}
+
+// ClearEncryptionKey wipes every data key from memory
+func ClearEncryptionKey() {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	for id, key := range keyring {
+		for i := range key {
+			key[i] = 0
+		}
+		delete(keyring, id)
+	}
+	currentKeyID = ""
+}
+
+// HasEncryptionKey reports whether a data key is loaded
+func HasEncryptionKey() bool {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return currentKeyID != ""
+}
+
+// CurrentKeyID returns the id of the key used for new ciphertexts
+func CurrentKeyID() string {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return currentKeyID
+}
+
+func currentKey() (string, []byte, error) {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	if currentKeyID == "" {
+		return "", nil, ErrNoEncryptionKey
+	}
+	return currentKeyID, keyring[currentKeyID], nil
+}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintextBytes)
+func lookupKey(keyID string) ([]byte, error) {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	if currentKeyID == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	key, ok := keyring[keyID]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	return key, nil
+}
 
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+// Encrypt encrypts plaintext with the current data key
+func Encrypt(plaintext string) (string, error) {
+	keyID, key, err := currentKey()
+	if err != nil {
+		return "", err
+	}
+	return EncryptWithKey(keyID, key, plaintext)
 }
 
+// Decrypt decrypts a value in either the current or the legacy format
 func Decrypt(encrypted string) (string, error) {
-	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if IsLegacyCiphertext(encrypted) {
+		_, key, err := currentKey()
+		if err != nil {
+			return "", err
+		}
+		return decryptCFB(key, encrypted)
+	}
+
+	keyID, _, err := parseCiphertext(encrypted)
+	if err != nil {
+		return "", err
+	}
+	key, err := lookupKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	return DecryptWithKey(key, encrypted)
+}
+
+// DecryptLegacy decrypts a value written with the old built-in key
+func DecryptLegacy(encrypted string) (string, error) {
+	return decryptCFB(legacyEncryptionKey, encrypted)
+}
+
+// IsLegacyCiphertext reports whether a value predates the authenticated format
+func IsLegacyCiphertext(encrypted string) bool {
+	return !strings.HasPrefix(encrypted, ciphertextVersion+":")
+}
+
+// CiphertextKeyID returns the key id a value was encrypted with, or "" for legacy values
+func CiphertextKeyID(encrypted string) string {
+	if IsLegacyCiphertext(encrypted) {
+		return ""
+	}
+	keyID, _, err := parseCiphertext(encrypted)
+	if err != nil {
+		return ""
+	}
+	return keyID
+}
+
+// EncryptWithKey encrypts plaintext with an explicit key using AES-256-GCM
+func EncryptWithKey(keyID string, key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	prefix := ciphertextVersion + ":" + keyID + ":"
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(prefix))
+
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptWithKey decrypts a value with an explicit key.
+// Legacy values are accepted for reading only.
+func DecryptWithKey(key []byte, encrypted string) (string, error) {
+	if IsLegacyCiphertext(encrypted) {
+		return decryptCFB(key, encrypted)
+	}
+
+	keyID, payload, err := parseCiphertext(encrypted)
 	if err != nil {
 		return "", err
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) < gcm.NonceSize() {
+		return "", ErrDecryptionFailed
+	}
+
+	prefix := ciphertextVersion + ":" + keyID + ":"
+	nonce, sealed := payload[:gcm.NonceSize()], payload[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, []byte(prefix))
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	return string(plaintext), nil
+}
+
+// PasswordFingerprint returns a keyed hash of a password so reuse can be
+// detected without comparing plaintext. The HMAC key is derived from the
+// current data key, so fingerprints must be recomputed after a key rotation.
+func PasswordFingerprint(password string) (string, error) {
+	_, key, err := currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	sub := hmac.New(sha256.New, key)
+	sub.Write([]byte("account-manager password fingerprint"))
+	mac := hmac.New(sha256.New, sub.Sum(nil))
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// parseCiphertext splits a versioned value into its key id and raw payload
+func parseCiphertext(encrypted string) (string, []byte, error) {
+	parts := strings.SplitN(encrypted, ":", 3)
+	if len(parts) != 3 || parts[0] != ciphertextVersion || parts[1] == "" {
+		return "", nil, ErrDecryptionFailed
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, ErrDecryptionFailed
+	}
+
+	return parts[1], payload, nil
+}
+
+// decryptCFB reads the unauthenticated AES-CFB format used by older releases.
+// It cannot detect a wrong key reliably, so output that is not valid UTF-8 is
+// treated as a failure rather than returned as garbage.
+func decryptCFB(key []byte, encrypted string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
 
 	if len(ciphertext) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+		return "", ErrDecryptionFailed
 	}
 
 	iv := ciphertext[:aes.BlockSize]
@@ -53,6 +384,9 @@ func Decrypt(encrypted string) (string, error) {
 	stream := cipher.NewCFBDecrypter(block, iv)
 	stream.XORKeyStream(ciphertext, ciphertext)
 
+	if !utf8.Valid(ciphertext) {
+		return "", ErrDecryptionFailed
+	}
+
 	return string(ciphertext), nil
 }
-
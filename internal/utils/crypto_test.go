@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useKeys loads data keys for one test, current being the one Encrypt uses
+func useKeys(t *testing.T, keys map[string][]byte, current string) {
+	t.Helper()
+	t.Cleanup(ClearEncryptionKey)
+	for id, key := range keys {
+		if id == current {
+			continue
+		}
+		if err := SetEncryptionKey(id, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetEncryptionKey(current, keys[current]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeyLength)
+}
+
+// encryptCFB writes a value in the format of releases before versioned
+// ciphertexts
+func encryptCFB(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
+	iv := ciphertext[:aes.BlockSize]
+	for i := range iv {
+		iv[i] = byte(i)
+	}
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], []byte(plaintext))
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	useKeys(t, map[string][]byte{"0a0a0a0a": testKey(1)}, "0a0a0a0a")
+
+	for _, plaintext := range []string{"", "hunter2", "密码 with spaces", strings.Repeat("x", 4096)} {
+		encrypted, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if !strings.HasPrefix(encrypted, "v2:0a0a0a0a:") {
+			t.Errorf("Encrypt(%q) = %q, want the v2 prefix with the key id", plaintext, encrypted)
+		}
+		if IsLegacyCiphertext(encrypted) || CiphertextKeyID(encrypted) != "0a0a0a0a" {
+			t.Errorf("CiphertextKeyID(%q) = %q", encrypted, CiphertextKeyID(encrypted))
+		}
+		decrypted, err := Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, decrypted, err)
+		}
+	}
+}
+
+func TestEncryptUsesFreshNonces(t *testing.T) {
+	useKeys(t, map[string][]byte{"0a0a0a0a": testKey(1)}, "0a0a0a0a")
+
+	a, _ := Encrypt("same")
+	b, _ := Encrypt("same")
+	if a == b {
+		t.Error("two encryptions of the same plaintext are identical")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	useKeys(t, map[string][]byte{"0a0a0a0a": testKey(1), "0b0b0b0b": testKey(2)}, "0a0a0a0a")
+
+	encrypted, err := Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "v2:0a0a0a0a:"
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload[len(payload)-1] ^= 1
+	flipped := prefix + base64.StdEncoding.EncodeToString(payload)
+
+	tests := []struct {
+		name      string
+		encrypted string
+		want      error
+	}{
+		{"flipped payload", flipped, ErrDecryptionFailed},
+		{"other key id", strings.Replace(encrypted, "0a0a0a0a", "0b0b0b0b", 1), ErrDecryptionFailed},
+		{"unknown key id", strings.Replace(encrypted, "0a0a0a0a", "0c0c0c0c", 1), ErrUnknownKeyID},
+		{"missing payload", "v2:0a0a0a0a:", ErrDecryptionFailed},
+		{"bad base64", "v2:0a0a0a0a:!!!", ErrDecryptionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.encrypted); !errors.Is(err, tt.want) {
+				t.Errorf("Decrypt() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptWithEveryLoadedKey(t *testing.T) {
+	useKeys(t, map[string][]byte{"0a0a0a0a": testKey(1)}, "0a0a0a0a")
+	old, err := Encrypt("before rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetEncryptionKey("0b0b0b0b", testKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	current, err := Encrypt("after rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CiphertextKeyID(current) != "0b0b0b0b" {
+		t.Fatalf("new values use key %q, want the current key", CiphertextKeyID(current))
+	}
+	if got, err := Decrypt(old); err != nil || got != "before rotation" {
+		t.Errorf("Decrypt(old) = %q, %v", got, err)
+	}
+
+	RemoveEncryptionKey("0a0a0a0a")
+	if _, err := Decrypt(old); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Decrypt(old) after removing its key: error = %v, want ErrUnknownKeyID", err)
+	}
+	RemoveEncryptionKey("0b0b0b0b")
+	if got, err := Decrypt(current); err != nil || got != "after rotation" {
+		t.Errorf("the current key was removed: Decrypt() = %q, %v", got, err)
+	}
+}
+
+func TestDecryptWithKey(t *testing.T) {
+	encrypted, err := EncryptWithKey("0a0a0a0a", testKey(1), "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecryptWithKey(testKey(1), encrypted); err != nil || got != "hunter2" {
+		t.Errorf("DecryptWithKey() = %q, %v", got, err)
+	}
+	if _, err := DecryptWithKey(testKey(2), encrypted); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("DecryptWithKey() with the wrong key: error = %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestDecryptLegacyCFB(t *testing.T) {
+	useKeys(t, map[string][]byte{"0a0a0a0a": testKey(1)}, "0a0a0a0a")
+
+	for _, plaintext := range []string{"hunter2", "密码", ""} {
+		builtIn := encryptCFB(t, legacyEncryptionKey, plaintext)
+		if !IsLegacyCiphertext(builtIn) || CiphertextKeyID(builtIn) != "" {
+			t.Errorf("%q is not recognized as a legacy value", builtIn)
+		}
+		if got, err := DecryptLegacy(builtIn); err != nil || got != plaintext {
+			t.Errorf("DecryptLegacy() = %q, %v, want %q", got, err, plaintext)
+		}
+
+		// Values encrypted with a derived key before the versioned format
+		derived := encryptCFB(t, testKey(1), plaintext)
+		if got, err := Decrypt(derived); err != nil || got != plaintext {
+			t.Errorf("Decrypt(legacy) = %q, %v, want %q", got, err, plaintext)
+		}
+		if got, err := DecryptWithKey(testKey(1), derived); err != nil || got != plaintext {
+			t.Errorf("DecryptWithKey(legacy) = %q, %v, want %q", got, err, plaintext)
+		}
+	}
+}
+
+func TestDecryptLegacyRejectsWrongKey(t *testing.T) {
+	encrypted := encryptCFB(t, testKey(1), strings.Repeat("plaintext ", 8))
+	if got, err := DecryptWithKey(testKey(2), encrypted); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("DecryptWithKey() with the wrong key = %q, %v, want ErrDecryptionFailed", got, err)
+	}
+}
+
+func TestEncryptWithoutKey(t *testing.T) {
+	ClearEncryptionKey()
+	if _, err := Encrypt("hunter2"); !errors.Is(err, ErrNoEncryptionKey) {
+		t.Errorf("Encrypt() error = %v, want ErrNoEncryptionKey", err)
+	}
+}
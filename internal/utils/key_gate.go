@@ -0,0 +1,60 @@
+package utils
+
+import "sync"
+
+// keyGate keeps a key rotation from running while values encrypted with the
+// old data key, or read before their row is re-encrypted, are being stored.
+// Unlike sync.RWMutex a waiting rotation does not block new holders, so
+// HoldKey may be nested.
+var keyGate = struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	holders  int
+	rotating bool
+}{}
+
+func init() {
+	keyGate.cond = sync.NewCond(&keyGate.mu)
+}
+
+// HoldKey marks the start of a read or encrypt then store sequence on
+// encrypted data. Call the returned function once the values are stored.
+func HoldKey() (release func()) {
+	keyGate.mu.Lock()
+	for keyGate.rotating {
+		keyGate.cond.Wait()
+	}
+	keyGate.holders++
+	keyGate.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			keyGate.mu.Lock()
+			keyGate.holders--
+			if keyGate.holders == 0 {
+				keyGate.cond.Broadcast()
+			}
+			keyGate.mu.Unlock()
+		})
+	}
+}
+
+// HoldKeyExclusive waits until no HoldKey sequence is running and blocks new
+// ones until the returned function is called. Key rotation holds it while it
+// re-encrypts every value and switches keys.
+func HoldKeyExclusive() (release func()) {
+	keyGate.mu.Lock()
+	for keyGate.rotating || keyGate.holders > 0 {
+		keyGate.cond.Wait()
+	}
+	keyGate.rotating = true
+	keyGate.mu.Unlock()
+
+	return func() {
+		keyGate.mu.Lock()
+		keyGate.rotating = false
+		keyGate.cond.Broadcast()
+		keyGate.mu.Unlock()
+	}
+}
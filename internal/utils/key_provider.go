@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Key provider names used in config.yaml
+const (
+	KeyProviderPassword = "password"
+	KeyProviderFile     = "keyfile"
+	KeyProviderEnv      = "env"
+	KeyProviderAgent    = "agent"
+)
+
+// Default environment variable read by the env provider
+const DefaultKeyEnv = "ACCOUNT_MANAGER_KEY"
+
+// How long to wait for the key agent to answer
+const agentTimeout = 5 * time.Second
+
+// KeyParams carries the per-install key derivation settings stored in the database
+type KeyParams struct {
+	Salt    []byte
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// DefaultKeyParams returns derivation settings for a new key with the given salt
+func DefaultKeyParams(salt []byte) KeyParams {
+	return KeyParams{Salt: salt, Time: KDFTime, Memory: KDFMemory, Threads: KDFThreads}
+}
+
+// KeyProvider supplies the 32-byte data key
+type KeyProvider interface {
+	// Name returns the provider name used in config.yaml
+	Name() string
+	// Interactive reports whether the user has to type a master password
+	Interactive() bool
+	// Key returns the data key. secret is the master password for interactive
+	// providers and is ignored by the others.
+	Key(params KeyParams, secret string) ([]byte, error)
+}
+
+// NewKeyProvider creates the provider selected in the security configuration
+func NewKeyProvider(name, keyFile, keyEnv, agentSocket string) (KeyProvider, error) {
+	switch name {
+	case "", KeyProviderPassword:
+		return &PasswordKeyProvider{}, nil
+	case KeyProviderFile:
+		if keyFile == "" {
+			return nil, errors.New("security.key_file is required for the keyfile provider")
+		}
+		return &FileKeyProvider{Path: keyFile}, nil
+	case KeyProviderEnv:
+		if keyEnv == "" {
+			keyEnv = DefaultKeyEnv
+		}
+		return &EnvKeyProvider{Variable: keyEnv}, nil
+	case KeyProviderAgent:
+		if agentSocket == "" {
+			return nil, errors.New("security.agent_socket is required for the agent provider")
+		}
+		return &AgentKeyProvider{Socket: agentSocket}, nil
+	default:
+		return nil, fmt.Errorf("unknown key provider %q", name)
+	}
+}
+
+// PasswordKeyProvider derives the key from the master password with Argon2id
+type PasswordKeyProvider struct{}
+
+func (p *PasswordKeyProvider) Name() string      { return KeyProviderPassword }
+func (p *PasswordKeyProvider) Interactive() bool { return true }
+
+func (p *PasswordKeyProvider) Key(params KeyParams, secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("master password is required")
+	}
+	return DeriveKey(secret, params.Salt, params.Time, params.Memory, params.Threads), nil
+}
+
+// FileKeyProvider reads the key from a file, e.g. on a USB stick
+type FileKeyProvider struct {
+	Path string
+}
+
+func (p *FileKeyProvider) Name() string      { return KeyProviderFile }
+func (p *FileKeyProvider) Interactive() bool { return false }
+
+func (p *FileKeyProvider) Key(params KeyParams, secret string) ([]byte, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	defer wipe(data)
+	return decodeKeyMaterial(data)
+}
+
+// EnvKeyProvider reads the key from an environment variable for headless use
+type EnvKeyProvider struct {
+	Variable string
+}
+
+func (p *EnvKeyProvider) Name() string      { return KeyProviderEnv }
+func (p *EnvKeyProvider) Interactive() bool { return false }
+
+func (p *EnvKeyProvider) Key(params KeyParams, secret string) ([]byte, error) {
+	value, ok := os.LookupEnv(p.Variable)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", p.Variable)
+	}
+	return decodeKeyMaterial([]byte(value))
+}
+
+// AgentKeyProvider asks a local key agent over a Unix socket.
+// The agent receives "GET account-manager\n" and answers with
+// "OK <base64 key>\n" or "ERR <message>\n".
+type AgentKeyProvider struct {
+	Socket string
+}
+
+func (p *AgentKeyProvider) Name() string      { return KeyProviderAgent }
+func (p *AgentKeyProvider) Interactive() bool { return false }
+
+func (p *AgentKeyProvider) Key(params KeyParams, secret string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", p.Socket, agentTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to key agent: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+
+	if _, err := conn.Write([]byte("GET account-manager\n")); err != nil {
+		return nil, fmt.Errorf("request key from agent: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read key agent reply: %w", err)
+	}
+	reply = strings.TrimSpace(reply)
+
+	switch {
+	case strings.HasPrefix(reply, "OK "):
+		return decodeKeyMaterial([]byte(strings.TrimPrefix(reply, "OK ")))
+	case strings.HasPrefix(reply, "ERR "):
+		return nil, fmt.Errorf("key agent: %s", strings.TrimPrefix(reply, "ERR "))
+	default:
+		return nil, errors.New("invalid key agent reply")
+	}
+}
+
+// decodeKeyMaterial accepts a raw 32-byte key or its hex or base64 encoding
+func decodeKeyMaterial(data []byte) ([]byte, error) {
+	if len(data) == KeyLength {
+		return append([]byte(nil), data...), nil
+	}
+
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == KeyLength {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == KeyLength {
+		return key, nil
+	}
+
+	return nil, errors.New("key material must be 32 bytes, raw or hex/base64 encoded")
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
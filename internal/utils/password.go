@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Character sets used by the password generator
+const (
+	lowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars     = "0123456789"
+	symbolChars    = "!@#$%^&*()-_=+[]{};:,.<>?/~"
+
+	// ambiguousChars look alike in common fonts or are awkward to type
+	ambiguousChars = "Il1O0o|`'\""
+
+	consonants = "bcdfghjklmnprstvz"
+	vowels     = "aeiou"
+)
+
+//go:embed wordlist.txt
+var wordlistData string
+
+var wordlist = strings.Fields(wordlistData)
+
+// PasswordOptions controls GeneratePassword
+type PasswordOptions struct {
+	Length           int
+	Lowercase        bool
+	Uppercase        bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+}
+
+// GeneratePassword returns a random password containing at least one
+// character from every selected class
+func GeneratePassword(opts PasswordOptions) (string, error) {
+	var sets []string
+	if opts.Lowercase {
+		sets = append(sets, lowercaseChars)
+	}
+	if opts.Uppercase {
+		sets = append(sets, uppercaseChars)
+	}
+	if opts.Digits {
+		sets = append(sets, digitChars)
+	}
+	if opts.Symbols {
+		sets = append(sets, symbolChars)
+	}
+	if len(sets) == 0 {
+		return "", errors.New("at least one character class is required")
+	}
+	if opts.Length < len(sets) {
+		return "", errors.New("password length is shorter than the number of character classes")
+	}
+
+	all := ""
+	for i, set := range sets {
+		if opts.ExcludeAmbiguous {
+			set = removeChars(set, ambiguousChars)
+			sets[i] = set
+		}
+		all += set
+	}
+
+	password := make([]byte, 0, opts.Length)
+	for _, set := range sets {
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < opts.Length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	if err := shuffle(password); err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// GeneratePassphrase returns words from the embedded wordlist joined by
+// separator. capitalize upper-cases each word and withNumber appends a digit
+// to one random word.
+func GeneratePassphrase(words int, separator string, capitalize, withNumber bool) (string, error) {
+	if words < 1 {
+		return "", errors.New("at least one word is required")
+	}
+
+	parts := make([]string, words)
+	for i := range parts {
+		n, err := randomInt(len(wordlist))
+		if err != nil {
+			return "", err
+		}
+		word := wordlist[n]
+		if capitalize {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		parts[i] = word
+	}
+
+	if withNumber {
+		i, err := randomInt(words)
+		if err != nil {
+			return "", err
+		}
+		d, err := randomChar(digitChars)
+		if err != nil {
+			return "", err
+		}
+		parts[i] += string(d)
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+// GeneratePronounceable returns a password of alternating consonants and
+// vowels that is easier to read out. capitalize upper-cases the first letter
+// and withDigits replaces the last two letters with digits.
+func GeneratePronounceable(length int, capitalize, withDigits bool) (string, error) {
+	if length < 4 {
+		return "", errors.New("pronounceable passwords need at least 4 characters")
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		set := consonants
+		if i%2 == 1 {
+			set = vowels
+		}
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	if capitalize {
+		password[0] = strings.ToUpper(string(password[0]))[0]
+	}
+	if withDigits {
+		for i := length - 2; i < length; i++ {
+			c, err := randomChar(digitChars)
+			if err != nil {
+				return "", err
+			}
+			password[i] = c
+		}
+	}
+
+	return string(password), nil
+}
+
+// PasswordEntropy estimates the entropy in bits of a random password
+// generated with opts
+func PasswordEntropy(opts PasswordOptions) float64 {
+	size := 0
+	for _, set := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{opts.Lowercase, lowercaseChars},
+		{opts.Uppercase, uppercaseChars},
+		{opts.Digits, digitChars},
+		{opts.Symbols, symbolChars},
+	} {
+		if !set.enabled {
+			continue
+		}
+		if opts.ExcludeAmbiguous {
+			size += len(removeChars(set.chars, ambiguousChars))
+		} else {
+			size += len(set.chars)
+		}
+	}
+	if size == 0 {
+		return 0
+	}
+	return float64(opts.Length) * math.Log2(float64(size))
+}
+
+// PronounceableEntropy estimates the entropy in bits of a generated
+// pronounceable password
+func PronounceableEntropy(length int, withDigits bool) float64 {
+	bits := 0.0
+	for i := 0; i < length; i++ {
+		switch {
+		case withDigits && i >= length-2:
+			bits += math.Log2(float64(len(digitChars)))
+		case i%2 == 1:
+			bits += math.Log2(float64(len(vowels)))
+		default:
+			bits += math.Log2(float64(len(consonants)))
+		}
+	}
+	return bits
+}
+
+// PassphraseEntropy estimates the entropy in bits of a generated passphrase
+func PassphraseEntropy(words int, withNumber bool) float64 {
+	bits := float64(words) * math.Log2(float64(len(wordlist)))
+	if withNumber {
+		bits += math.Log2(float64(words * len(digitChars)))
+	}
+	return bits
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := randomInt(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[n], nil
+}
+
+// shuffle performs an unbiased Fisher-Yates shuffle
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func removeChars(set, exclude string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(exclude, r) {
+			return -1
+		}
+		return r
+	}, set)
+}
@@ -0,0 +1,327 @@
+package utils
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Password strength scores, following the zxcvbn scale
+const (
+	StrengthTooGuessable = iota
+	StrengthVeryGuessable
+	StrengthSomewhatGuessable
+	StrengthSafelyUnguessable
+	StrengthVeryUnguessable
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsData string
+
+// commonPasswords maps frequently used passwords to their popularity rank
+var commonPasswords = rankWords(commonPasswordsData)
+
+// dictionaryWords holds the generator wordlist, which doubles as the
+// dictionary of common English words
+var dictionaryWords = rankWords(wordlistData)
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+var leetSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '|': 'i',
+	'0': 'o', '$': 's', '5': 's', '7': 't', '+': 't',
+}
+
+// StrengthResult is the estimated strength of a password
+type StrengthResult struct {
+	Score       int      `json:"score"` // 0 (too guessable) to 4 (very unguessable)
+	EntropyBits float64  `json:"entropyBits"`
+	Feedback    []string `json:"feedback"`
+}
+
+// patternMatch is a substring explained by a guessable pattern
+type patternMatch struct {
+	start, end int // end is exclusive
+	bits       float64
+	feedback   string
+}
+
+// EstimateStrength scores a password in the style of zxcvbn: it looks for
+// common passwords, dictionary words, l33t substitutions, repeats, sequences,
+// keyboard walks and years, and takes the cheapest way to cover the password
+// with those patterns and brute-forced characters.
+func EstimateStrength(password string) StrengthResult {
+	chars := []rune(password)
+	n := len(chars)
+	if n == 0 {
+		return StrengthResult{Score: StrengthTooGuessable, Feedback: []string{"密码不能为空"}}
+	}
+
+	matches := findPatterns(chars)
+	bruteBits := math.Log2(float64(poolSize(chars)))
+
+	// best[i] is the minimum number of bits needed to guess chars[:i]
+	best := make([]float64, n+1)
+	choice := make([]*patternMatch, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = best[i-1] + bruteBits
+		choice[i] = nil
+		for k := range matches {
+			m := &matches[k]
+			if m.end != i {
+				continue
+			}
+			// One extra bit per pattern for not knowing where patterns start
+			if bits := best[m.start] + m.bits + 1; bits < best[i] {
+				best[i] = bits
+				choice[i] = m
+			}
+		}
+	}
+
+	var feedback []string
+	seen := map[string]bool{}
+	for i := n; i > 0; {
+		m := choice[i]
+		if m == nil {
+			i--
+			continue
+		}
+		if !seen[m.feedback] {
+			seen[m.feedback] = true
+			feedback = append(feedback, m.feedback)
+		}
+		i = m.start
+	}
+	if n < 8 {
+		feedback = append(feedback, "密码过短，建议至少 12 位")
+	}
+
+	bits := best[n]
+	return StrengthResult{
+		Score:       strengthScore(bits),
+		EntropyBits: math.Round(bits*10) / 10,
+		Feedback:    feedback,
+	}
+}
+
+// strengthScore maps bits to the zxcvbn thresholds of 10^3, 10^6, 10^8 and 10^10 guesses
+func strengthScore(bits float64) int {
+	guesses := bits * math.Log10(2)
+	switch {
+	case guesses < 3:
+		return StrengthTooGuessable
+	case guesses < 6:
+		return StrengthVeryGuessable
+	case guesses < 8:
+		return StrengthSomewhatGuessable
+	case guesses < 10:
+		return StrengthSafelyUnguessable
+	default:
+		return StrengthVeryUnguessable
+	}
+}
+
+func findPatterns(chars []rune) []patternMatch {
+	var matches []patternMatch
+	matches = append(matches, dictionaryMatches(chars)...)
+	matches = append(matches, repeatMatches(chars)...)
+	matches = append(matches, sequenceMatches(chars)...)
+	matches = append(matches, keyboardMatches(chars)...)
+	matches = append(matches, yearMatches(chars)...)
+	return matches
+}
+
+func dictionaryMatches(chars []rune) []patternMatch {
+	lower := []rune(strings.ToLower(string(chars)))
+	unleet := make([]rune, len(lower))
+	for i, c := range lower {
+		if sub, ok := leetSubstitutions[c]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = c
+		}
+	}
+
+	var matches []patternMatch
+	for i := 0; i < len(chars); i++ {
+		for j := i + 3; j <= len(chars); j++ {
+			word := string(lower[i:j])
+			extra := caseBits(chars[i:j])
+			if string(unleet[i:j]) != word {
+				word = string(unleet[i:j])
+				extra++
+			}
+
+			if rank, ok := commonPasswords[word]; ok {
+				matches = append(matches, patternMatch{i, j, math.Log2(float64(rank+1)) + extra, "包含常见密码"})
+			} else if _, ok := dictionaryWords[word]; ok && j-i >= 4 {
+				matches = append(matches, patternMatch{i, j, math.Log2(float64(len(dictionaryWords))) + extra, "包含常见单词"})
+			}
+		}
+	}
+	return matches
+}
+
+func repeatMatches(chars []rune) []patternMatch {
+	var matches []patternMatch
+	for i := 0; i < len(chars); {
+		j := i + 1
+		for j < len(chars) && chars[j] == chars[i] {
+			j++
+		}
+		if j-i >= 3 {
+			bits := math.Log2(float64(poolSize(chars[i:i+1]))) + math.Log2(float64(j-i))
+			matches = append(matches, patternMatch{i, j, bits, "包含重复字符"})
+		}
+		i = j
+	}
+	return matches
+}
+
+func sequenceMatches(chars []rune) []patternMatch {
+	var matches []patternMatch
+	for i := 0; i < len(chars)-2; {
+		delta := chars[i+1] - chars[i]
+		if (delta != 1 && delta != -1) || charClass(chars[i]) != charClass(chars[i+1]) {
+			i++
+			continue
+		}
+		j := i + 2
+		for j < len(chars) && chars[j]-chars[j-1] == delta && charClass(chars[j]) == charClass(chars[i]) {
+			j++
+		}
+		if j-i >= 3 {
+			bits := math.Log2(float64(poolSize(chars[i:i+1]))) + math.Log2(float64(j-i))
+			if delta < 0 {
+				bits++
+			}
+			matches = append(matches, patternMatch{i, j, bits, "包含连续字符序列"})
+		}
+		i = j - 1
+	}
+	return matches
+}
+
+func keyboardMatches(chars []rune) []patternMatch {
+	lower := strings.ToLower(string(chars))
+	runes := []rune(lower)
+
+	var matches []patternMatch
+	for i := 0; i < len(runes); i++ {
+		for j := i + 4; j <= len(runes); j++ {
+			walk := string(runes[i:j])
+			if !onKeyboard(walk) {
+				break
+			}
+			bits := math.Log2(47) + math.Log2(float64(j-i)) + caseBits(chars[i:j])
+			matches = append(matches, patternMatch{i, j, bits, "包含键盘排列"})
+		}
+	}
+	return matches
+}
+
+func onKeyboard(walk string) bool {
+	reversed := []rune(walk)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	for _, row := range keyboardRows {
+		if strings.Contains(row, walk) || strings.Contains(row, string(reversed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func yearMatches(chars []rune) []patternMatch {
+	var matches []patternMatch
+	for i := 0; i+4 <= len(chars); i++ {
+		year := 0
+		for _, c := range chars[i : i+4] {
+			if c < '0' || c > '9' {
+				year = -1
+				break
+			}
+			year = year*10 + int(c-'0')
+		}
+		if year >= 1900 && year <= 2039 {
+			matches = append(matches, patternMatch{i, i + 4, math.Log2(140), "包含年份"})
+		}
+	}
+	return matches
+}
+
+// caseBits estimates the extra guesses needed for capitalization
+func caseBits(chars []rune) float64 {
+	upper := 0
+	for _, c := range chars {
+		if unicode.IsUpper(c) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(chars) || (upper == 1 && unicode.IsUpper(chars[0])):
+		return 1
+	default:
+		return float64(upper)
+	}
+}
+
+const (
+	classLower = iota
+	classUpper
+	classDigit
+	classSymbol
+	classOther
+)
+
+func charClass(c rune) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return classLower
+	case c >= 'A' && c <= 'Z':
+		return classUpper
+	case c >= '0' && c <= '9':
+		return classDigit
+	case c < 128:
+		return classSymbol
+	default:
+		return classOther
+	}
+}
+
+// poolSize returns the brute-force alphabet size for the classes used in chars
+func poolSize(chars []rune) int {
+	sizes := map[int]int{classLower: 26, classUpper: 26, classDigit: 10, classSymbol: 33, classOther: 100}
+	used := map[int]bool{}
+	for _, c := range chars {
+		used[charClass(c)] = true
+	}
+
+	size := 0
+	for class := range used {
+		size += sizes[class]
+	}
+	return size
+}
+
+func rankWords(data string) map[string]int {
+	words := strings.Fields(data)
+	ranks := make(map[string]int, len(words))
+	for i, w := range words {
+		if _, ok := ranks[w]; !ok {
+			ranks[w] = i + 1
+		}
+	}
+	return ranks
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B
+func TestTOTPCodeRFC6238(t *testing.T) {
+	seeds := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, tt := range tests {
+		key := &TOTPKey{
+			Secret:    base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(seeds[tt.algorithm])),
+			Algorithm: tt.algorithm,
+			Digits:    8,
+			Period:    30,
+		}
+		got, remaining, err := key.Code(time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d, %s): %v", tt.unix, tt.algorithm, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d, %s) = %s, want %s", tt.unix, tt.algorithm, got, tt.want)
+		}
+		if want := 30 - int(tt.unix%30); remaining != want {
+			t.Errorf("Code(%d, %s) remaining = %d, want %d", tt.unix, tt.algorithm, remaining, want)
+		}
+	}
+}
+
+func TestTOTPCodeSixDigits(t *testing.T) {
+	key, err := ParseTOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last six digits of the RFC 6238 SHA1 vector at 59 seconds
+	if got, _, _ := key.Code(time.Unix(59, 0)); got != "287082" {
+		t.Errorf("Code() = %s, want 287082", got)
+	}
+}
+
+func TestParseTOTP(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TOTPKey
+		wantErr bool
+	}{
+		{
+			input: "gezd gnbv-gy3t qojq",
+			want:  TOTPKey{Secret: "GEZDGNBVGY3TQOJQ", Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			input: "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+			want:  TOTPKey{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", AccountName: "alice@example.com", Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			input: "otpauth://totp/bob?secret=JBSWY3DPEHPK3PXP&algorithm=sha256&digits=8&period=60",
+			want:  TOTPKey{Secret: "JBSWY3DPEHPK3PXP", AccountName: "bob", Algorithm: "SHA256", Digits: 8, Period: 60},
+		},
+		{input: "", wantErr: true},
+		{input: "not base32!", wantErr: true},
+		{input: "otpauth://hotp/bob?secret=JBSWY3DPEHPK3PXP", wantErr: true},
+		{input: "otpauth://totp/bob?secret=JBSWY3DPEHPK3PXP&algorithm=MD5", wantErr: true},
+		{input: "otpauth://totp/bob?secret=JBSWY3DPEHPK3PXP&digits=10", wantErr: true},
+		{input: "otpauth://totp/bob?secret=JBSWY3DPEHPK3PXP&period=0", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, err := ParseTOTP(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTOTP(%q) = %+v, want an error", tt.input, key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTOTP(%q): %v", tt.input, err)
+			continue
+		}
+		if *key != tt.want {
+			t.Errorf("ParseTOTP(%q) = %+v, want %+v", tt.input, *key, tt.want)
+		}
+	}
+}
+
+func TestTOTPURIRoundTrip(t *testing.T) {
+	key := TOTPKey{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", AccountName: "alice@example.com", Algorithm: "SHA512", Digits: 7, Period: 45}
+	uri := key.URI()
+	if !strings.HasPrefix(uri, "otpauth://totp/") {
+		t.Fatalf("URI() = %q", uri)
+	}
+	parsed, err := ParseTOTP(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != key {
+		t.Errorf("ParseTOTP(URI()) = %+v, want %+v", *parsed, key)
+	}
+}